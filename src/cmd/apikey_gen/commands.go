@@ -0,0 +1,258 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"dZev1/character-gallery/models/auth"
+)
+
+const day = 24 * time.Hour
+
+// cliActor records the actions of the tool in the audit log.
+var cliActor = auth.Actor{Source: auth.AuditSourceCLI}
+
+type createdKey struct {
+	*auth.APIKey
+	Key string `json:"key"`
+}
+
+func runCreate(args []string) error {
+	flags := flag.NewFlagSet("create", flag.ExitOnError)
+	name := flags.String("name", "CLI Generated Key", "Name/Description for the API key")
+	scopesStr := flags.String("scopes", auth.DefaultScopes.String(), "Comma separated scopes (read, write, admin)")
+	expiresDays := flags.Int("expires-days", 0, "Days until the key expires (0 means it never expires)")
+	asJSON := flags.Bool("json", false, "Print the result as JSON")
+	flags.Parse(args)
+
+	scopes, err := auth.ParseScopes(*scopesStr)
+	if err != nil {
+		return err
+	}
+
+	if *expiresDays < 0 {
+		return errors.New("-expires-days cannot be negative")
+	}
+
+	store, closeStore := openAuthStore()
+	defer closeStore()
+
+	key, rawKey, err := store.CreateAPIKey(cliActor, *name, scopes, time.Duration(*expiresDays)*day)
+	if err != nil {
+		return fmt.Errorf("could not create API key: %w", err)
+	}
+
+	if *asJSON {
+		return printJSON(createdKey{APIKey: key, Key: rawKey})
+	}
+
+	fmt.Println("API Key Generated Successfully!")
+	printNewKey(key, rawKey)
+	return nil
+}
+
+func runList(args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "Print the result as JSON")
+	flags.Parse(args)
+
+	store, closeStore := openAuthStore()
+	defer closeStore()
+
+	keys, err := store.ListAPIKeys()
+	if err != nil {
+		return fmt.Errorf("could not list API keys: %w", err)
+	}
+
+	if *asJSON {
+		if keys == nil {
+			keys = []auth.APIKey{}
+		}
+		return printJSON(keys)
+	}
+
+	table := newTable()
+	fmt.Fprintln(table, "ID\tNAME\tSCOPES\tCREATED\tLAST USED\tEXPIRES\tACTIVE")
+	for _, key := range keys {
+		fmt.Fprintf(table, "%d\t%s\t%s\t%s\t%s\t%s\t%t\n",
+			key.ID,
+			key.Name,
+			key.Scopes,
+			formatTime(&key.CreatedAt),
+			formatTime(key.LastUsedAt),
+			formatTime(key.ExpiresAt),
+			key.IsActive,
+		)
+	}
+	return table.Flush()
+}
+
+func runRevoke(args []string) error {
+	return setKeyActive("revoke", args, auth.AuthStore.RevokeAPIKey)
+}
+
+func runReactivate(args []string) error {
+	return setKeyActive("reactivate", args, auth.AuthStore.ReactivateAPIKey)
+}
+
+func setKeyActive(name string, args []string, apply func(auth.AuthStore, auth.Actor, auth.APIKeyID) error) error {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	idStr := flags.String("id", "", "ID of the API key")
+	asJSON := flags.Bool("json", false, "Print the result as JSON")
+	flags.Parse(args)
+
+	id, err := parseKeyID(*idStr)
+	if err != nil {
+		return err
+	}
+
+	store, closeStore := openAuthStore()
+	defer closeStore()
+
+	if err := apply(store, cliActor, id); err != nil {
+		return fmt.Errorf("could not %s API key %s: %w", name, id, err)
+	}
+
+	if *asJSON {
+		return printJSON(struct {
+			ID       auth.APIKeyID `json:"id"`
+			IsActive bool          `json:"is_active"`
+		}{
+			ID:       id,
+			IsActive: name == "reactivate",
+		})
+	}
+
+	fmt.Printf("API key %s: %sd\n", id, name)
+	return nil
+}
+
+func runRotate(args []string) error {
+	flags := flag.NewFlagSet("rotate", flag.ExitOnError)
+	idStr := flags.String("id", "", "ID of the API key to rotate")
+	grace := flags.Duration("grace", 24*time.Hour, "How long the old key keeps working (e.g. 1h, 72h)")
+	asJSON := flags.Bool("json", false, "Print the result as JSON")
+	flags.Parse(args)
+
+	id, err := parseKeyID(*idStr)
+	if err != nil {
+		return err
+	}
+
+	if *grace < 0 {
+		return errors.New("-grace cannot be negative")
+	}
+
+	store, closeStore := openAuthStore()
+	defer closeStore()
+
+	key, rawKey, err := store.RotateAPIKey(cliActor, id, *grace)
+	if err != nil {
+		return fmt.Errorf("could not rotate API key %s: %w", id, err)
+	}
+
+	if *asJSON {
+		return printJSON(struct {
+			createdKey
+			ReplacedID auth.APIKeyID `json:"replaced_id"`
+		}{
+			createdKey: createdKey{APIKey: key, Key: rawKey},
+			ReplacedID: id,
+		})
+	}
+
+	fmt.Printf("API Key %s Rotated Successfully! The old key stops working in %s.\n", id, *grace)
+	printNewKey(key, rawKey)
+	return nil
+}
+
+func runPrune(args []string) error {
+	flags := flag.NewFlagSet("prune", flag.ExitOnError)
+	days := flags.Int("days", 90, "Delete keys that have not been used for this many days")
+	asJSON := flags.Bool("json", false, "Print the result as JSON")
+	flags.Parse(args)
+
+	if *days < 1 {
+		return errors.New("-days must be at least 1")
+	}
+
+	store, closeStore := openAuthStore()
+	defer closeStore()
+
+	pruned, err := store.PruneAPIKeys(cliActor, time.Duration(*days)*day)
+	if err != nil {
+		return fmt.Errorf("could not prune API keys: %w", err)
+	}
+
+	if *asJSON {
+		return printJSON(struct {
+			Pruned int64 `json:"pruned"`
+		}{
+			Pruned: pruned,
+		})
+	}
+
+	fmt.Printf("Pruned %d API key(s) unused for %d days\n", pruned, *days)
+	return nil
+}
+
+func runQuota(args []string) error {
+	flags := flag.NewFlagSet("quota", flag.ExitOnError)
+	idStr := flags.String("id", "", "ID of the API key")
+	monthly := flags.Uint64("monthly", 0, "Requests allowed per calendar month (0 removes the quota)")
+	asJSON := flags.Bool("json", false, "Print the result as JSON")
+	flags.Parse(args)
+
+	id, err := parseKeyID(*idStr)
+	if err != nil {
+		return err
+	}
+
+	store, closeStore := openAuthStore()
+	defer closeStore()
+
+	if err := store.SetMonthlyQuota(cliActor, id, *monthly); err != nil {
+		return fmt.Errorf("could not set quota of API key %s: %w", id, err)
+	}
+
+	if *asJSON {
+		return printJSON(struct {
+			ID           auth.APIKeyID `json:"id"`
+			MonthlyQuota uint64        `json:"monthly_quota"`
+		}{
+			ID:           id,
+			MonthlyQuota: *monthly,
+		})
+	}
+
+	if *monthly == 0 {
+		fmt.Printf("API key %s: monthly quota removed\n", id)
+		return nil
+	}
+	fmt.Printf("API key %s: monthly quota set to %d requests\n", id, *monthly)
+	return nil
+}
+
+func parseKeyID(idStr string) (auth.APIKeyID, error) {
+	if idStr == "" {
+		return 0, errors.New("-id is required")
+	}
+
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid API key id: %q", idStr)
+	}
+	return auth.APIKeyID(id), nil
+}
+
+func printNewKey(key *auth.APIKey, rawKey string) {
+	fmt.Printf("ID:      %d\n", key.ID)
+	fmt.Printf("Name:    %s\n", key.Name)
+	fmt.Printf("Scopes:  %s\n", key.Scopes)
+	fmt.Printf("Expires: %s\n", formatTime(key.ExpiresAt))
+	fmt.Printf("Key:     %s\n", rawKey)
+	fmt.Println("\nWARNING: This key will NOT be shown again. Save it securely!")
+}
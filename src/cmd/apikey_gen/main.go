@@ -1,56 +1,110 @@
 package main
 
 import (
-	"flag"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"dZev1/character-gallery/internal/config"
+	"dZev1/character-gallery/internal/database"
 	"dZev1/character-gallery/models/auth"
-	_ "github.com/jackc/pgx/v5/stdlib"
-	"github.com/jmoiron/sqlx"
+
 	"github.com/joho/godotenv"
 )
 
+const usage = `Usage: apikey_gen <command> [flags]
+
+Commands:
+  create      Create a new API key
+  list        List every API key
+  revoke      Disable an API key
+  reactivate  Re-enable a revoked API key
+  rotate      Issue a replacement key and expire the old one after a grace period
+  prune       Delete keys unused for a number of days
+  quota       Set or remove the monthly request quota of a key
+
+Run "apikey_gen <command> -h" to see the flags of a command.
+Running apikey_gen with only flags is the same as "apikey_gen create".
+`
+
+type command func(args []string) error
+
+var commands = map[string]command{
+	"create":     runCreate,
+	"list":       runList,
+	"revoke":     runRevoke,
+	"reactivate": runReactivate,
+	"rotate":     runRotate,
+	"prune":      runPrune,
+	"quota":      runQuota,
+}
+
 func main() {
-	name := flag.String("name", "CLI Generated Key", "Name/Description for the API key")
-	flag.Parse()
+	log.SetFlags(0)
 
-	if err := godotenv.Load(); err != nil {
-		log.Println("Warning: No .env file found")
+	name, args := "create", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
 
-	dbURL := os.Getenv("DATABASE_URL")
-	if dbURL == "" {
-		log.Fatal("DATABASE_URL environment variable is not set")
+	if name == "help" {
+		fmt.Print(usage)
+		return
 	}
 
-	db, err := sqlx.Connect("pgx", dbURL)
-	if err != nil {
-		log.Fatalf("Could not connect to database: %v", err)
+	run, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n%s", name, usage)
+		os.Exit(2)
 	}
-	defer db.Close()
 
-	keyHash, rawKey, err := auth.GenerateAPIKey()
-	if err != nil {
-		log.Fatalf("Error generating API key: %v", err)
+	if err := run(args); err != nil {
+		log.Fatalf("Error: %v", err)
 	}
+}
 
-	query := `
-		INSERT INTO api_keys (name, key_hash)
-		VALUES ($1, $2)
-		RETURNING id
-	`
+// openAuthStore connects to the configured gallery backend and returns its auth store
+// along with a function that closes the connection.
+func openAuthStore() (auth.AuthStore, func()) {
+	for _, file := range []string{".env", "config.env"} {
+		if err := godotenv.Load(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Fatalf("Could not load %s: %v", file, err)
+		}
+	}
 
-	var id int64
-	err = db.QueryRow(query, *name, keyHash).Scan(&id)
+	// The server's flags aren't accepted here, but its config file and environment are.
+	cfg, err := config.Load(nil, os.LookupEnv)
 	if err != nil {
-		log.Fatalf("Error inserting API key into database: %v", err)
+		log.Fatalf("Invalid configuration:\n%v", err)
 	}
 
-	fmt.Println("API Key Generated Successfully!")
-	fmt.Printf("ID:   %d\n", id)
-	fmt.Printf("Name: %s\n", *name)
-	fmt.Printf("Key:  %s\n", rawKey)
-	fmt.Println("\nWARNING: This key will NOT be shown again. Save it securely!")
+	gallery, err := database.NewCharacterGallery(cfg.Database.Type, cfg.Database.URL)
+	if err != nil {
+		log.Fatalf("Could not connect to database: %v", err)
+	}
+
+	return gallery.GetAuthStore(), func() { gallery.Close() }
+}
+
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func newTable() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.DateTime)
 }
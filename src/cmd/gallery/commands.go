@@ -0,0 +1,438 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"dZev1/character-gallery/internal/seeding"
+	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/inventory"
+)
+
+func runCharactersList(args []string) error {
+	flags := flag.NewFlagSet("characters list", flag.ExitOnError)
+	page := flags.Int("page", 0, "Page to show, starting at 0")
+	limit := flags.Int("limit", 20, "Characters per page")
+	asJSON := flags.Bool("json", false, "Print the result as JSON")
+	flags.Parse(args)
+
+	if *page < 0 {
+		return errors.New("-page cannot be negative")
+	}
+	if *limit < 1 {
+		return errors.New("-limit must be at least 1")
+	}
+
+	gallery, closeGallery := openGallery()
+	defer closeGallery()
+
+	chars, total, err := gallery.GetAll(context.Background(), characters.CharacterFilter{}, *page**limit, *limit)
+	if err != nil {
+		return fmt.Errorf("could not list characters: %w", err)
+	}
+
+	if *asJSON {
+		if chars == nil {
+			chars = []characters.Character{}
+		}
+		return printJSON(struct {
+			Data  []characters.Character `json:"data"`
+			Total uint64                 `json:"total"`
+		}{
+			Data:  chars,
+			Total: total,
+		})
+	}
+
+	table := newTable()
+	fmt.Fprintln(table, "ID\tNAME\tSPECIES\tBODY TYPE\tCLASS")
+	for _, char := range chars {
+		fmt.Fprintf(table, "%d\t%s\t%s\t%s\t%s\n", char.ID, char.Name, char.Species, char.BodyType, char.Class)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("\nShowing %d of %d character(s)\n", len(chars), total)
+	return nil
+}
+
+func runCharactersGet(args []string) error {
+	flags := flag.NewFlagSet("characters get", flag.ExitOnError)
+	idStr := flags.String("id", "", "ID of the character")
+	asJSON := flags.Bool("json", false, "Print the result as JSON")
+	flags.Parse(args)
+
+	id, err := parseCharacterID("-id", *idStr)
+	if err != nil {
+		return err
+	}
+
+	gallery, closeGallery := openGallery()
+	defer closeGallery()
+
+	char, err := gallery.Get(context.Background(), id)
+	if err != nil {
+		return fmt.Errorf("could not get character %s: %w", id, err)
+	}
+
+	if *asJSON {
+		return printJSON(char)
+	}
+
+	fmt.Printf("ID: %d", char.ID)
+	fmt.Print(char)
+	return nil
+}
+
+func runCharactersCreate(args []string) error {
+	flags := flag.NewFlagSet("characters create", flag.ExitOnError)
+	file := flags.String("file", "", "JSON file with the character")
+	asJSON := flags.Bool("json", false, "Print the result as JSON")
+	flags.Parse(args)
+
+	char := &characters.Character{}
+	if err := readJSON(*file, char); err != nil {
+		return err
+	}
+	if err := char.Validate(); err != nil {
+		return fmt.Errorf("invalid character: %w", err)
+	}
+
+	gallery, closeGallery := openGallery()
+	defer closeGallery()
+
+	if err := gallery.Create(context.Background(), char); err != nil {
+		return fmt.Errorf("could not create character: %w", err)
+	}
+
+	if *asJSON {
+		return printJSON(char)
+	}
+
+	fmt.Printf("Character %s created\n", char.ID)
+	return nil
+}
+
+func runCharactersEdit(args []string) error {
+	flags := flag.NewFlagSet("characters edit", flag.ExitOnError)
+	idStr := flags.String("id", "", "ID of the character")
+	file := flags.String("file", "", "JSON file with the new contents of the character")
+	asJSON := flags.Bool("json", false, "Print the result as JSON")
+	flags.Parse(args)
+
+	id, err := parseCharacterID("-id", *idStr)
+	if err != nil {
+		return err
+	}
+
+	char := &characters.Character{}
+	if err := readJSON(*file, char); err != nil {
+		return err
+	}
+	if err := char.Validate(); err != nil {
+		return fmt.Errorf("invalid character: %w", err)
+	}
+
+	// -id wins over any id in the file, as the path does in the API.
+	char.ID = id
+	char.Stats.ID = id
+	char.Customization.ID = id
+
+	gallery, closeGallery := openGallery()
+	defer closeGallery()
+
+	if err := gallery.Edit(context.Background(), char); err != nil {
+		return fmt.Errorf("could not edit character %s: %w", id, err)
+	}
+
+	if *asJSON {
+		return printJSON(char)
+	}
+
+	fmt.Printf("Character %s updated\n", id)
+	return nil
+}
+
+func runCharactersDelete(args []string) error {
+	flags := flag.NewFlagSet("characters delete", flag.ExitOnError)
+	idStr := flags.String("id", "", "ID of the character")
+	asJSON := flags.Bool("json", false, "Print the result as JSON")
+	flags.Parse(args)
+
+	id, err := parseCharacterID("-id", *idStr)
+	if err != nil {
+		return err
+	}
+
+	gallery, closeGallery := openGallery()
+	defer closeGallery()
+
+	if err := gallery.Remove(context.Background(), id); err != nil {
+		return fmt.Errorf("could not delete character %s: %w", id, err)
+	}
+
+	if *asJSON {
+		return printJSON(struct {
+			ID      characters.CharacterID `json:"id"`
+			Deleted bool                   `json:"deleted"`
+		}{
+			ID:      id,
+			Deleted: true,
+		})
+	}
+
+	fmt.Printf("Character %s deleted\n", id)
+	return nil
+}
+
+func runItemsList(args []string) error {
+	flags := flag.NewFlagSet("items list", flag.ExitOnError)
+	pack := flags.String("pack", "", "Only list the items of this content pack")
+	asJSON := flags.Bool("json", false, "Print the result as JSON")
+	flags.Parse(args)
+
+	gallery, closeGallery := openGallery()
+	defer closeGallery()
+
+	items, err := gallery.DisplayPoolItems(context.Background(), inventory.ItemFilter{Pack: *pack})
+	if err != nil {
+		return fmt.Errorf("could not list items: %w", err)
+	}
+
+	if *asJSON {
+		if items == nil {
+			items = []inventory.Item{}
+		}
+		return printJSON(items)
+	}
+
+	table := newTable()
+	fmt.Fprintln(table, "ID\tNAME\tTYPE\tRARITY\tEQUIPPABLE\tPACK")
+	for _, item := range items {
+		pack := "-"
+		if item.Slug != "" {
+			pack = item.Pack + "/" + item.Slug
+		}
+		fmt.Fprintf(table, "%d\t%s\t%s\t%d\t%t\t%s\n", item.ID, item.Name, item.Type, item.Rarity, item.Equippable, pack)
+	}
+	return table.Flush()
+}
+
+func runItemsCreate(args []string) error {
+	flags := flag.NewFlagSet("items create", flag.ExitOnError)
+	file := flags.String("file", "", "JSON file with the item")
+	asJSON := flags.Bool("json", false, "Print the result as JSON")
+	flags.Parse(args)
+
+	item := &inventory.Item{}
+	if err := readJSON(*file, item); err != nil {
+		return err
+	}
+	if err := item.Validate(); err != nil {
+		return fmt.Errorf("invalid item: %w", err)
+	}
+
+	gallery, closeGallery := openGallery()
+	defer closeGallery()
+
+	if err := gallery.CreateItem(context.Background(), item); err != nil {
+		return fmt.Errorf("could not create item: %w", err)
+	}
+
+	if *asJSON {
+		return printJSON(item)
+	}
+
+	fmt.Printf("Item %s created\n", item.ID)
+	return nil
+}
+
+func runInventoryList(args []string) error {
+	flags := flag.NewFlagSet("inventory list", flag.ExitOnError)
+	characterStr := flags.String("character", "", "ID of the character")
+	asJSON := flags.Bool("json", false, "Print the result as JSON")
+	flags.Parse(args)
+
+	characterID, err := parseCharacterID("-character", *characterStr)
+	if err != nil {
+		return err
+	}
+
+	gallery, closeGallery := openGallery()
+	defer closeGallery()
+
+	items, err := gallery.GetCharacterInventory(context.Background(), characterID)
+	if err != nil {
+		return fmt.Errorf("could not get inventory of character %s: %w", characterID, err)
+	}
+
+	if *asJSON {
+		if items == nil {
+			items = []inventory.InventoryItem{}
+		}
+		return printJSON(items)
+	}
+
+	table := newTable()
+	fmt.Fprintln(table, "ID\tNAME\tTYPE\tQUANTITY\tEQUIPPED")
+	for _, slot := range items {
+		fmt.Fprintf(table, "%d\t%s\t%s\t%d\t%t\n", slot.Item.ID, slot.Item.Name, slot.Item.Type, slot.Quantity, slot.IsEquipped)
+	}
+	return table.Flush()
+}
+
+func runInventoryAdd(args []string) error {
+	flags := flag.NewFlagSet("inventory add", flag.ExitOnError)
+	characterStr := flags.String("character", "", "ID of the character")
+	itemStr := flags.String("item", "", "ID of the item")
+	quantity := flags.Uint("quantity", 1, "How many items to give (1-255)")
+	asJSON := flags.Bool("json", false, "Print the result as JSON")
+	flags.Parse(args)
+
+	characterID, itemID, err := parseInventoryArgs(*characterStr, *itemStr, *quantity)
+	if err != nil {
+		return err
+	}
+
+	gallery, closeGallery := openGallery()
+	defer closeGallery()
+
+	slot, err := gallery.AddItemToCharacter(context.Background(), characterID, itemID, uint8(*quantity))
+	if err != nil {
+		return fmt.Errorf("could not add item %s to character %s: %w", itemID, characterID, err)
+	}
+
+	if *asJSON {
+		return printJSON(slot)
+	}
+
+	fmt.Printf("Character %s now has %d of item %s\n", characterID, slot.Quantity, itemID)
+	return nil
+}
+
+func runInventoryRemove(args []string) error {
+	flags := flag.NewFlagSet("inventory remove", flag.ExitOnError)
+	characterStr := flags.String("character", "", "ID of the character")
+	itemStr := flags.String("item", "", "ID of the item")
+	quantity := flags.Uint("quantity", 1, "How many items to take (1-255)")
+	asJSON := flags.Bool("json", false, "Print the result as JSON")
+	flags.Parse(args)
+
+	characterID, itemID, err := parseInventoryArgs(*characterStr, *itemStr, *quantity)
+	if err != nil {
+		return err
+	}
+
+	gallery, closeGallery := openGallery()
+	defer closeGallery()
+
+	if err := gallery.RemoveItemFromCharacter(context.Background(), characterID, itemID, uint8(*quantity)); err != nil {
+		return fmt.Errorf("could not remove item %s from character %s: %w", itemID, characterID, err)
+	}
+
+	if *asJSON {
+		return printJSON(struct {
+			CharacterID characters.CharacterID `json:"character_id"`
+			ItemID      inventory.ItemID       `json:"item_id"`
+			Removed     uint                   `json:"removed"`
+		}{
+			CharacterID: characterID,
+			ItemID:      itemID,
+			Removed:     *quantity,
+		})
+	}
+
+	fmt.Printf("Removed %d of item %s from character %s\n", *quantity, itemID, characterID)
+	return nil
+}
+
+func runSeed(args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	file := flags.String("file", "", "JSON file with numbered items to seed (default: the server's item pool file)")
+	packs := flags.String("packs", "", "Directory of content packs to seed (default: the server's packs directory)")
+	dryRun := flags.Bool("dry-run", false, "Print what would change without writing anything")
+	asJSON := flags.Bool("json", false, "Print the result as JSON")
+	flags.Parse(args)
+
+	source := seeding.Source{PoolFile: *file, PacksDir: *packs}
+	if source.PoolFile == "" && source.PacksDir == "" {
+		cfg := loadConfig()
+		source = seeding.Source{PoolFile: cfg.Files.ItemPool, PacksDir: cfg.Files.Packs}
+	}
+
+	gallery, closeGallery := openGallery()
+	defer closeGallery()
+
+	plan, err := seeding.SeedSource(context.Background(), gallery, source, *dryRun)
+	var seedErrs seeding.Errors
+	if errors.As(err, &seedErrs) {
+		if *asJSON {
+			if err := printJSON(struct {
+				Errors seeding.Errors `json:"errors"`
+			}{
+				Errors: seedErrs,
+			}); err != nil {
+				return err
+			}
+			return fmt.Errorf("the item pool has %d problem(s)", len(seedErrs))
+		}
+		return fmt.Errorf("the item pool has %d problem(s):\n%w", len(seedErrs), err)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(struct {
+			seeding.Plan
+			DryRun bool `json:"dry_run"`
+		}{
+			Plan:   plan,
+			DryRun: *dryRun,
+		})
+	}
+
+	if err := plan.Write(os.Stdout); err != nil {
+		return err
+	}
+	if *dryRun {
+		fmt.Println("Dry run: nothing was written")
+	}
+	return nil
+}
+
+func parseCharacterID(name, idStr string) (characters.CharacterID, error) {
+	if idStr == "" {
+		return 0, fmt.Errorf("%s is required", name)
+	}
+
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid character id: %q", idStr)
+	}
+	return characters.CharacterID(id), nil
+}
+
+func parseInventoryArgs(characterStr, itemStr string, quantity uint) (characters.CharacterID, inventory.ItemID, error) {
+	characterID, err := parseCharacterID("-character", characterStr)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if itemStr == "" {
+		return 0, 0, errors.New("-item is required")
+	}
+	itemID, err := strconv.ParseUint(itemStr, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid item id: %q", itemStr)
+	}
+
+	if quantity < 1 || quantity > 255 {
+		return 0, 0, errors.New("-quantity must be between 1 and 255")
+	}
+	return characterID, inventory.ItemID(itemID), nil
+}
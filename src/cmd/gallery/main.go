@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"dZev1/character-gallery/internal/config"
+	"dZev1/character-gallery/internal/database"
+	"dZev1/character-gallery/models"
+
+	"github.com/joho/godotenv"
+)
+
+const usage = `Usage: gallery <command> [flags]
+
+Commands:
+  characters list    List characters a page at a time
+  characters get     Show a character
+  characters create  Create a character from a JSON file
+  characters edit    Replace a character with the contents of a JSON file
+  characters delete  Delete a character
+  items list         List the item pool
+  items create       Add an item to the pool from a JSON file
+  inventory list     Show the inventory of a character
+  inventory add      Give items to a character
+  inventory remove   Take items from a character
+  seed               Seed the item pool and content packs, or preview it with -dry-run
+
+Run "gallery <command> -h" to see the flags of a command.
+Every command accepts -json for machine readable output. Files named "-" are read from stdin.
+`
+
+type command func(args []string) error
+
+var commands = map[string]command{
+	"characters list":   runCharactersList,
+	"characters get":    runCharactersGet,
+	"characters create": runCharactersCreate,
+	"characters edit":   runCharactersEdit,
+	"characters delete": runCharactersDelete,
+	"items list":        runItemsList,
+	"items create":      runItemsCreate,
+	"inventory list":    runInventoryList,
+	"inventory add":     runInventoryAdd,
+	"inventory remove":  runInventoryRemove,
+	"seed":              runSeed,
+}
+
+func main() {
+	log.SetFlags(0)
+
+	args := os.Args[1:]
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "-help" {
+		fmt.Print(usage)
+		return
+	}
+
+	// Commands are either a single word or a group followed by an action.
+	name, args := args[0], args[1:]
+	if len(args) > 0 {
+		if _, ok := commands[name+" "+args[0]]; ok {
+			name, args = name+" "+args[0], args[1:]
+		}
+	}
+
+	run, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n%s", name, usage)
+		os.Exit(2)
+	}
+
+	if err := run(args); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+// loadConfig reads the configuration the server would use from .env, config.env, the config
+// file and the environment.
+func loadConfig() *config.Config {
+	for _, file := range []string{".env", "config.env"} {
+		if err := godotenv.Load(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Fatalf("Could not load %s: %v", file, err)
+		}
+	}
+
+	// The server's flags aren't accepted here, but its config file and environment are.
+	cfg, err := config.Load(nil, os.LookupEnv)
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	return cfg
+}
+
+// openGallery connects to the configured gallery backend and returns it along with a function
+// that closes the connection.
+func openGallery() (models.CharacterGallery, func()) {
+	cfg := loadConfig()
+
+	gallery, err := database.NewCharacterGallery(cfg.Database.Type, cfg.Database.URL)
+	if err != nil {
+		log.Fatalf("Could not connect to database: %v", err)
+	}
+
+	// Changes made here are written to the outbox like any other, and the server delivers them.
+	return gallery, func() { gallery.Close() }
+}
+
+// readJSON decodes the file at path into v, reading stdin when path is "-". Unknown fields are
+// rejected so that a typo doesn't silently drop a value.
+func readJSON(path string, v any) error {
+	if path == "" {
+		return errors.New("-file is required")
+	}
+
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("could not decode %s: %w", path, err)
+	}
+	return nil
+}
+
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func newTable() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+}
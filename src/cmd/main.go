@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
+	"database/sql"
+	"errors"
+	"flag"
+	"io/fs"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,99 +15,211 @@ import (
 	"time"
 
 	"dZev1/character-gallery/handlers"
+	"dZev1/character-gallery/internal/authcache"
+	"dZev1/character-gallery/internal/changes"
+	"dZev1/character-gallery/internal/config"
 	"dZev1/character-gallery/internal/database"
+	"dZev1/character-gallery/internal/graphql"
+	"dZev1/character-gallery/internal/logging"
+	"dZev1/character-gallery/internal/metrics"
 	"dZev1/character-gallery/internal/middleware"
-	"dZev1/character-gallery/models/inventory"
+	"dZev1/character-gallery/internal/outbound"
+	"dZev1/character-gallery/internal/outbox"
+	"dZev1/character-gallery/internal/seeding"
+	"dZev1/character-gallery/internal/tracing"
+	"dZev1/character-gallery/internal/usage"
+	"dZev1/character-gallery/models/events"
 
 	"github.com/joho/godotenv"
 )
 
 func main() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Println("Warning: Could not load .env file, relying on environment variables")
+	// .env and config.env only fill in variables the environment doesn't already set.
+	for _, file := range []string{".env", "config.env"} {
+		if err := godotenv.Load(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Fatalf("Could not load %s: %v", file, err)
+		}
 	}
 
-	connectionString := os.Getenv("DATABASE_URL")
-	currentVersion := os.Getenv("API_VERSION")
+	cfg, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatalf("Could not print configuration: %v", err)
+		}
+		return
+	}
 
-	err = godotenv.Overload("./config.env")
+	logger, err := logging.New(os.Stderr, cfg.Log.Format, cfg.Log.Level)
 	if err != nil {
-		log.Println("Warning: Could not load config.env file, relying on environment variables")
+		log.Fatalf("Could not configure logging: %v", err)
 	}
+	slog.SetDefault(logger)
 
-	dbType := os.Getenv("DATABASE_TYPE")
+	if err := handlers.SetDefaultErrorFormat(cfg.API.ErrorFormat); err != nil {
+		slog.Error("could not configure error responses", "error", err)
+		os.Exit(1)
+	}
 
-	gallery, err := database.NewCharacterGallery(dbType, connectionString)
+	traceExporter, err := tracing.NewExporter(cfg.Trace.Exporter, cfg.Trace.File)
 	if err != nil {
-		panic(err)
+		slog.Error("could not configure tracing", "error", err)
+		os.Exit(1)
 	}
-	defer gallery.Close()
+	shutdownTracing := tracing.Setup(traceExporter)
+	defer shutdownTracing(context.Background())
 
-	itemFile, err := os.Open("./item_pool.json")
+	gallery, err := database.NewCharacterGallery(cfg.Database.Type, cfg.Database.URL)
 	if err != nil {
-		log.Println(fmt.Errorf("could not open seed file: %w", err))
+		panic(err)
 	}
-	defer itemFile.Close()
+	defer gallery.Close()
+	source, listens := gallery.(changes.Source)
 
-	var items []inventory.Item
-	if err := json.NewDecoder(itemFile).Decode(&items); err != nil {
-		log.Println(fmt.Errorf("could not decode items json: %w", err))
+	appMetrics := metrics.New()
+	if pool, ok := gallery.(interface{ Stats() sql.DBStats }); ok {
+		appMetrics.RegisterDBStats(pool.Stats)
+	}
+	appMetrics.RegisterGalleryCounts(gallery)
+	gallery = tracing.TraceGallery(gallery)
+	gallery = metrics.InstrumentGallery(gallery, appMetrics)
+	broker := changes.NewBroker(cfg.Events.Buffer)
+
+	authStore := authcache.New(gallery.GetAuthStore(), 30*time.Second, 10*time.Second)
+	defer authStore.Close()
+
+	// Every write leaves an event in the outbox, which is queued for webhook subscribers, logged
+	// and streamed to /events. Backends that listen broadcast the events, so that the streams of
+	// every instance see them whichever instance delivered them; writes made through other
+	// instances also evict their cached keys, and streams are reset when some were missed.
+	// Backends without an outbox hand the events to the same sinks after each write.
+	outboxStore := gallery.GetOutboxStore()
+	var stream outbox.Sink = outbox.SinkFunc(func(ctx context.Context, event *events.Event) error {
+		broker.Publish(ctx, event)
+		return nil
+	})
+	if listens {
+		if outboxStore != nil {
+			stream = outbox.SinkFunc(outboxStore.Broadcast)
+		}
+		changed := func(change events.Change) {
+			authStore.Changed(change)
+			broker.Changed(change)
+		}
+		source.Listen(changed, func(event *events.Event) { broker.Publish(context.Background(), event) })
+	}
+	sinks := map[string]outbox.Sink{
+		"webhooks": outbound.Queue{Store: gallery.GetWebhookStore()},
+		"stream":   stream,
+		"log":      outbox.LogSink(logger),
+	}
+	if outboxStore != nil {
+		outboxBackoff := outbound.DefaultBackoff
+		outboxBackoff.MaxAttempts = cfg.Outbox.MaxAttempts
+		outboxDispatcher := outbox.NewDispatcher(outboxStore, sinks, cfg.Outbox.PollInterval, cfg.Outbox.Retention, outboxBackoff)
+		defer outboxDispatcher.Close()
+	} else {
+		gallery = outbox.PublishGallery(gallery, sinks)
 	}
 
-	gallery.SeedItems(items)
+	// A pool that can't be seeded leaves the stored one untouched. Strict mode refuses to serve
+	// with it instead.
+	reloader := seeding.NewReloader(gallery, seeding.Source{PoolFile: cfg.Files.ItemPool, PacksDir: cfg.Files.Packs})
+	plan, err := reloader.Reload(context.Background(), seeding.TriggerStartup)
+	if err != nil {
+		if cfg.Seed.Strict {
+			slog.Error("could not seed item pool", "error", err)
+			os.Exit(1)
+		}
+		slog.Warn("could not seed item pool, serving the stored one", "error", err)
+	} else {
+		slog.Info("item pool seeded",
+			"packs", len(plan.Packs),
+			"inserted", plan.Count(seeding.Insert),
+			"updated", plan.Count(seeding.Update),
+			"unchanged", plan.Count(seeding.Unchanged),
+		)
+	}
+	if cfg.Seed.WatchInterval > 0 {
+		watcher := reloader.Watch(cfg.Seed.WatchInterval)
+		defer watcher.Close()
+		slog.Info("watching the item pool for changes", "interval", cfg.Seed.WatchInterval)
+	}
 
 	handler := &handlers.CharacterHandler{
-		Gallery: gallery,
+		Gallery:  gallery,
+		PageSize: cfg.API.PageSize,
 	}
 
-	baseRoute := "/api/" + currentVersion
+	adminHandler := &handlers.AdminHandler{
+		AuthStore: authStore,
+		Webhooks:  gallery.GetWebhookStore(),
+		ItemPool:  reloader,
+	}
 
-	http.HandleFunc("POST "+baseRoute+"/characters", handler.CreateCharacter)
-	http.HandleFunc("GET "+baseRoute+"/characters", handler.GetAllCharacters)
-	http.HandleFunc("GET "+baseRoute+"/characters/{id}", handler.GetCharacter)
-	http.HandleFunc("PUT "+baseRoute+"/characters/{id}", handler.EditCharacter)
-	http.HandleFunc("DELETE "+baseRoute+"/characters/{id}", handler.DeleteCharacter)
+	eventHandler := &handlers.EventHandler{
+		Broker:    broker,
+		Heartbeat: cfg.Events.Heartbeat,
+	}
 
-	http.HandleFunc("POST "+baseRoute+"/characters/{character_id}/inventory/{item_id}", handler.AddItemToCharacter)
-	http.HandleFunc("DELETE "+baseRoute+"/characters/{character_id}/inventory/{item_id}", handler.RemoveItemFromCharacter)
-	http.HandleFunc("GET "+baseRoute+"/characters/{character_id}/inventory", handler.GetCharacterInventory)
+	backoff := outbound.DefaultBackoff
+	backoff.MaxAttempts = cfg.Webhooks.MaxAttempts
+	dispatcher := outbound.NewDispatcher(gallery.GetWebhookStore(), cfg.Webhooks.PollInterval, cfg.Webhooks.Timeout, backoff)
+	defer dispatcher.Close()
 
-	http.HandleFunc("GET "+baseRoute+"/items", handler.ShowPoolItems)
-	http.HandleFunc("POST "+baseRoute+"/items", handler.CreateItem)
-	http.HandleFunc("GET "+baseRoute+"/items/{item_id}", handler.ShowItem)
+	baseRoute := "/api/" + cfg.API.Version
 
 	mux := http.NewServeMux()
-	
-	mux.HandleFunc("POST "+baseRoute+"/characters", handler.CreateCharacter)
-	mux.HandleFunc("GET "+baseRoute+"/characters", handler.GetAllCharacters)
-	mux.HandleFunc("GET "+baseRoute+"/characters/{id}", handler.GetCharacter)
-	mux.HandleFunc("PUT "+baseRoute+"/characters/{id}", handler.EditCharacter)
-	mux.HandleFunc("DELETE "+baseRoute+"/characters/{id}", handler.DeleteCharacter)
+	registerRoutes(mux, baseRoute, handler, adminHandler, eventHandler, graphql.Handler(gallery, cfg.API.PageSize))
+
+	rateLimitPolicy, err := middleware.LoadRateLimitPolicy(cfg.Files.RateLimits)
+	if err != nil {
+		slog.Error("could not load rate limits", "error", err)
+		os.Exit(1)
+	}
+	rateLimit := middleware.RateLimitByKey(rateLimitPolicy, middleware.NewMemoryBucketStore(), baseRoute+"/graphql")
 
-	mux.HandleFunc("POST "+baseRoute+"/characters/{character_id}/inventory/{item_id}", handler.AddItemToCharacter)
-	mux.HandleFunc("DELETE "+baseRoute+"/characters/{character_id}/inventory/{item_id}", handler.RemoveItemFromCharacter)
-	mux.HandleFunc("GET "+baseRoute+"/characters/{character_id}/inventory", handler.GetCharacterInventory)
+	usageRecorder := usage.NewRecorder(authStore, 30*time.Second)
+	defer usageRecorder.Close()
+	trackUsage := middleware.TrackUsage(usageRecorder)
+
+	corsPolicy, err := middleware.LoadCORSPolicy(cfg.Files.CORS)
+	if err != nil {
+		slog.Error("could not load CORS policy", "error", err)
+		os.Exit(1)
+	}
 
-	mux.HandleFunc("GET "+baseRoute+"/items", handler.ShowPoolItems)
-	mux.HandleFunc("POST "+baseRoute+"/items", handler.CreateItem)
-	mux.HandleFunc("GET "+baseRoute+"/items/{item_id}", handler.ShowItem)
+	// CORS sits outside RequireAPIKey so preflights, which never carry a key, are answered.
+	handler_with_middlewares := middleware.CORS(corsPolicy)(middleware.RequireAPIKey(authStore)(rateLimit(trackUsage(mux))))
+	handler_with_middlewares = middleware.InstrumentRequests(appMetrics, mux)(handler_with_middlewares)
+	handler_with_middlewares = middleware.LogRequests(logger, mux)(handler_with_middlewares)
+	handler_with_middlewares = middleware.TraceRequests(mux)(handler_with_middlewares)
 
-	handler_with_middlewares := middleware.EnableCors(middleware.RequireAPIKey(gallery.GetAuthStore())(mux))
+	// Prometheus has no API key, so /metrics is guarded by METRICS_TOKEN instead. The API
+	// description is public.
+	root := http.NewServeMux()
+	registerRootRoutes(root, baseRoute, appMetrics.Handler(cfg.Metrics.Token))
+	root.Handle("/", handler_with_middlewares)
 
 	server := &http.Server{
-		Addr:         ":8080",
-		Handler:      handler_with_middlewares,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		Addr:         cfg.Server.Addr,
+		Handler:      root,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
-	log.Println("Server listening on http://localhost:8080" + baseRoute)
+	slog.Info("server listening", "addr", cfg.Server.Addr, "base_route", baseRoute)
 
 	go func() {
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Could not start server: %v", err)
+			slog.Error("could not start server", "error", err)
+			os.Exit(1)
 		}
 	}()
 
@@ -112,7 +227,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 	server.Shutdown(ctx)
 
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+
+	"dZev1/character-gallery/handlers"
+	"dZev1/character-gallery/internal/middleware"
+	"dZev1/character-gallery/internal/openapi"
+	"dZev1/character-gallery/models/auth"
+)
+
+// router is satisfied by *http.ServeMux; tests pass a recorder to list the registered routes.
+type router interface {
+	Handle(pattern string, handler http.Handler)
+}
+
+// registerRoutes registers every API route under baseRoute.
+func registerRoutes(mux router, baseRoute string, handler *handlers.CharacterHandler, adminHandler *handlers.AdminHandler, eventHandler *handlers.EventHandler, graphqlHandler http.Handler) {
+	// Reading needs the read scope and writing the write scope. GraphQL checks them per field,
+	// since one request can do both.
+	requireRead := middleware.RequireScope(auth.ScopeRead)
+	requireWrite := middleware.RequireScope(auth.ScopeWrite)
+
+	mux.Handle("POST "+baseRoute+"/characters", requireWrite(http.HandlerFunc(handler.CreateCharacter)))
+	mux.Handle("GET "+baseRoute+"/characters", requireRead(http.HandlerFunc(handler.GetAllCharacters)))
+	mux.Handle("GET "+baseRoute+"/characters/{id}", requireRead(http.HandlerFunc(handler.GetCharacter)))
+	mux.Handle("PUT "+baseRoute+"/characters/{id}", requireWrite(http.HandlerFunc(handler.EditCharacter)))
+	mux.Handle("DELETE "+baseRoute+"/characters/{id}", requireWrite(http.HandlerFunc(handler.DeleteCharacter)))
+
+	mux.Handle("POST "+baseRoute+"/characters/{character_id}/inventory/{item_id}", requireWrite(http.HandlerFunc(handler.AddItemToCharacter)))
+	mux.Handle("DELETE "+baseRoute+"/characters/{character_id}/inventory/{item_id}", requireWrite(http.HandlerFunc(handler.RemoveItemFromCharacter)))
+	mux.Handle("GET "+baseRoute+"/characters/{character_id}/inventory", requireRead(http.HandlerFunc(handler.GetCharacterInventory)))
+
+	mux.Handle("GET "+baseRoute+"/items", requireRead(http.HandlerFunc(handler.ShowPoolItems)))
+	mux.Handle("POST "+baseRoute+"/items", requireWrite(http.HandlerFunc(handler.CreateItem)))
+	mux.Handle("GET "+baseRoute+"/items/{item_id}", requireRead(http.HandlerFunc(handler.ShowItem)))
+
+	mux.Handle("POST "+baseRoute+"/batch", requireWrite(http.HandlerFunc(handler.RunBatch)))
+
+	mux.Handle("GET "+baseRoute+"/events", requireRead(http.HandlerFunc(eventHandler.StreamEvents)))
+
+	mux.Handle("POST "+baseRoute+"/graphql", graphqlHandler)
+
+	requireAdmin := middleware.RequireScope(auth.ScopeAdmin)
+
+	mux.Handle("GET "+baseRoute+"/admin/api-keys", requireAdmin(http.HandlerFunc(adminHandler.ListAPIKeys)))
+	mux.Handle("POST "+baseRoute+"/admin/api-keys", requireAdmin(http.HandlerFunc(adminHandler.CreateAPIKey)))
+	mux.Handle("POST "+baseRoute+"/admin/api-keys/{id}/revoke", requireAdmin(http.HandlerFunc(adminHandler.RevokeAPIKey)))
+	mux.Handle("POST "+baseRoute+"/admin/api-keys/{id}/rotate", requireAdmin(http.HandlerFunc(adminHandler.RotateAPIKey)))
+	mux.Handle("GET "+baseRoute+"/admin/api-keys/{id}/usage", requireAdmin(http.HandlerFunc(adminHandler.GetAPIKeyUsage)))
+	mux.Handle("PUT "+baseRoute+"/admin/api-keys/{id}/quota", requireAdmin(http.HandlerFunc(adminHandler.SetAPIKeyQuota)))
+	mux.Handle("GET "+baseRoute+"/admin/webhooks", requireAdmin(http.HandlerFunc(adminHandler.ListWebhooks)))
+	mux.Handle("POST "+baseRoute+"/admin/webhooks", requireAdmin(http.HandlerFunc(adminHandler.CreateWebhook)))
+	mux.Handle("DELETE "+baseRoute+"/admin/webhooks/{id}", requireAdmin(http.HandlerFunc(adminHandler.DeleteWebhook)))
+	mux.Handle("GET "+baseRoute+"/admin/webhooks/{id}/deliveries", requireAdmin(http.HandlerFunc(adminHandler.ListWebhookDeliveries)))
+	mux.Handle("GET "+baseRoute+"/admin/webhooks/{id}/deliveries/{delivery}", requireAdmin(http.HandlerFunc(adminHandler.GetWebhookDelivery)))
+	mux.Handle("POST "+baseRoute+"/admin/webhooks/{id}/deliveries/{delivery}/retry", requireAdmin(http.HandlerFunc(adminHandler.RetryWebhookDelivery)))
+	mux.Handle("GET "+baseRoute+"/admin/item-pool/status", requireAdmin(http.HandlerFunc(adminHandler.GetItemPoolStatus)))
+}
+
+// registerRootRoutes registers the routes served outside baseRoute, which skip the API key check.
+func registerRootRoutes(mux router, baseRoute string, metrics http.Handler) {
+	mux.Handle("GET /metrics", metrics)
+	mux.Handle("GET /openapi.json", openapi.Handler(openapi.Build(baseRoute)))
+	mux.Handle("GET /docs", openapi.DocsHandler())
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"dZev1/character-gallery/handlers"
+	"dZev1/character-gallery/internal/openapi"
+)
+
+type recordingRouter struct {
+	patterns []string
+}
+
+func (r *recordingRouter) Handle(pattern string, handler http.Handler) {
+	r.patterns = append(r.patterns, pattern)
+}
+
+// TestOpenAPICoversRoutes fails when a route is registered without being described in the
+// OpenAPI document, or the document describes a route that doesn't exist.
+func TestOpenAPICoversRoutes(t *testing.T) {
+	const baseRoute = "/api/v1"
+
+	api := &recordingRouter{}
+	registerRoutes(api, baseRoute, &handlers.CharacterHandler{}, &handlers.AdminHandler{}, &handlers.EventHandler{}, http.NotFoundHandler())
+	root := &recordingRouter{}
+	registerRootRoutes(root, baseRoute, http.NotFoundHandler())
+
+	doc := openapi.Build(baseRoute)
+
+	registered := map[string]bool{}
+	check := func(pattern, prefix string) {
+		method, path, _ := strings.Cut(pattern, " ")
+		path = strings.TrimPrefix(path, prefix)
+		registered[method+" "+path] = true
+
+		item, ok := doc.Paths[path]
+		if !ok || item.Operation(method) == nil {
+			t.Errorf("route %q is missing from the OpenAPI document", pattern)
+		}
+	}
+	for _, pattern := range api.patterns {
+		check(pattern, baseRoute)
+	}
+	for _, pattern := range root.patterns {
+		check(pattern, "")
+	}
+
+	for path, item := range doc.Paths {
+		for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete} {
+			if item.Operation(method) != nil && !registered[method+" "+path] {
+				t.Errorf("the OpenAPI document describes %s %s, which is not registered", method, path)
+			}
+		}
+	}
+}
@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"dZev1/character-gallery/internal/seeding"
+	"dZev1/character-gallery/models/auth"
+	"dZev1/character-gallery/models/webhooks"
+)
+
+type AdminHandler struct {
+	AuthStore auth.AuthStore
+	Webhooks  webhooks.WebhookStore
+	// ItemPool reports how the item pool was last seeded.
+	ItemPool interface {
+		Status() (seeding.Status, bool)
+	}
+}
+
+type CreateAPIKeyRequest struct {
+	Name          string      `json:"name"`
+	Scopes        auth.Scopes `json:"scopes"`
+	ExpiresInDays int         `json:"expires_in_days"`
+}
+
+// CreatedAPIKey is the only response that ever carries the raw key.
+type CreatedAPIKey struct {
+	*auth.APIKey
+	Key string `json:"key"`
+}
+
+func (h *AdminHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.AuthStore.ListAPIKeys()
+	if err != nil {
+		throwStoreError(err, "Could not list API keys", nil, w, r)
+		return
+	}
+
+	if keys == nil {
+		keys = []auth.APIKey{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(keys)
+}
+
+func (h *AdminHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	request := &CreateAPIKeyRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil {
+		er := &Error{
+			Error: "Invalid request body",
+			Code:  "BAD_REQUEST",
+		}
+		ThrowError(er, w, r, http.StatusBadRequest)
+		return
+	}
+
+	if request.Name == "" || request.ExpiresInDays < 0 {
+		er := &Error{
+			Error: "API key needs a name and a non-negative expiry",
+			Code:  "BAD_REQUEST",
+		}
+		ThrowError(er, w, r, http.StatusBadRequest)
+		return
+	}
+
+	for _, scope := range request.Scopes {
+		if !scope.Validate() {
+			er := &Error{
+				Error: "Invalid scope",
+				Code:  "BAD_REQUEST",
+				Details: struct {
+					Scope auth.Scope `json:"scope"`
+				}{
+					Scope: scope,
+				},
+			}
+			ThrowError(er, w, r, http.StatusBadRequest)
+			return
+		}
+	}
+
+	key, rawKey, err := h.AuthStore.CreateAPIKey(actor(r), request.Name, request.Scopes, time.Duration(request.ExpiresInDays)*24*time.Hour)
+	if err != nil {
+		throwStoreError(err, "Could not create API key", nil, w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreatedAPIKey{APIKey: key, Key: rawKey})
+}
+
+func (h *AdminHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		er := &Error{
+			Error: "Invalid ID",
+			Code:  "BAD_REQUEST",
+			Details: struct {
+				ID string `json:"id"`
+			}{
+				ID: idStr,
+			},
+		}
+		ThrowError(er, w, r, http.StatusBadRequest)
+		return
+	}
+
+	keyID := auth.APIKeyID(id)
+	err = h.AuthStore.RevokeAPIKey(actor(r), keyID)
+	if err != nil {
+		throwStoreError(err, "Could not revoke API key", idDetails(idStr), w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		ID       auth.APIKeyID `json:"id"`
+		IsActive bool          `json:"is_active"`
+	}{
+		ID:       keyID,
+		IsActive: false,
+	})
+}
+
+func (h *AdminHandler) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	graceStr := r.URL.Query().Get("grace")
+
+	if graceStr == "" {
+		graceStr = "24h"
+	}
+
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		er := &Error{
+			Error: "Invalid ID",
+			Code:  "BAD_REQUEST",
+			Details: struct {
+				ID string `json:"id"`
+			}{
+				ID: idStr,
+			},
+		}
+		ThrowError(er, w, r, http.StatusBadRequest)
+		return
+	}
+
+	grace, err := time.ParseDuration(graceStr)
+	if err != nil || grace < 0 {
+		er := &Error{
+			Error: "Invalid grace period",
+			Code:  "BAD_REQUEST",
+			Details: struct {
+				Grace string `json:"grace"`
+			}{
+				Grace: graceStr,
+			},
+		}
+		ThrowError(er, w, r, http.StatusBadRequest)
+		return
+	}
+
+	keyID := auth.APIKeyID(id)
+	key, rawKey, err := h.AuthStore.RotateAPIKey(actor(r), keyID, grace)
+	if err != nil {
+		throwStoreError(err, "Could not rotate API key", idDetails(idStr), w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(struct {
+		CreatedAPIKey
+		ReplacedID auth.APIKeyID `json:"replaced_id"`
+	}{
+		CreatedAPIKey: CreatedAPIKey{APIKey: key, Key: rawKey},
+		ReplacedID:    keyID,
+	})
+}
+
+func (h *AdminHandler) GetAPIKeyUsage(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		er := &Error{
+			Error: "Invalid ID",
+			Code:  "BAD_REQUEST",
+			Details: struct {
+				ID string `json:"id"`
+			}{
+				ID: idStr,
+			},
+		}
+		ThrowError(er, w, r, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	from, fromOK := parseDate(r.URL.Query().Get("from"), auth.StartOfMonth(now))
+	to, toOK := parseDate(r.URL.Query().Get("to"), auth.StartOfDay(now))
+	if !fromOK || !toOK || to.Before(from) {
+		er := &Error{
+			Error: "Invalid date range, dates must be YYYY-MM-DD and from cannot be after to",
+			Code:  "BAD_REQUEST",
+			Details: struct {
+				From string `json:"from"`
+				To   string `json:"to"`
+			}{
+				From: r.URL.Query().Get("from"),
+				To:   r.URL.Query().Get("to"),
+			},
+		}
+		ThrowError(er, w, r, http.StatusBadRequest)
+		return
+	}
+
+	key, err := h.AuthStore.GetAPIKey(auth.APIKeyID(id))
+	if err != nil {
+		throwStoreError(err, "Could not retrieve API key", idDetails(idStr), w, r)
+		return
+	}
+
+	records, err := h.AuthStore.GetUsage(key.ID, from, to)
+	if err != nil {
+		throwStoreError(err, "Could not retrieve API key usage", nil, w, r)
+		return
+	}
+
+	if records == nil {
+		records = []auth.UsageRecord{}
+	}
+
+	var total uint64
+	for _, record := range records {
+		total += record.Count
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		KeyID        auth.APIKeyID      `json:"key_id"`
+		From         string             `json:"from"`
+		To           string             `json:"to"`
+		Total        uint64             `json:"total"`
+		MonthlyQuota *uint64            `json:"monthly_quota,omitempty"`
+		Usage        []auth.UsageRecord `json:"usage"`
+	}{
+		KeyID:        key.ID,
+		From:         from.Format(time.DateOnly),
+		To:           to.Format(time.DateOnly),
+		Total:        total,
+		MonthlyQuota: key.MonthlyQuota,
+		Usage:        records,
+	})
+}
+
+func (h *AdminHandler) SetAPIKeyQuota(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		er := &Error{
+			Error: "Invalid ID",
+			Code:  "BAD_REQUEST",
+			Details: struct {
+				ID string `json:"id"`
+			}{
+				ID: idStr,
+			},
+		}
+		ThrowError(er, w, r, http.StatusBadRequest)
+		return
+	}
+
+	request := &struct {
+		MonthlyQuota uint64 `json:"monthly_quota"`
+	}{}
+	err = json.NewDecoder(r.Body).Decode(request)
+	if err != nil {
+		er := &Error{
+			Error: "Invalid request body",
+			Code:  "BAD_REQUEST",
+		}
+		ThrowError(er, w, r, http.StatusBadRequest)
+		return
+	}
+
+	keyID := auth.APIKeyID(id)
+	err = h.AuthStore.SetMonthlyQuota(actor(r), keyID, request.MonthlyQuota)
+	if err != nil {
+		throwStoreError(err, "Could not set API key quota", idDetails(idStr), w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		ID           auth.APIKeyID `json:"id"`
+		MonthlyQuota uint64        `json:"monthly_quota"`
+	}{
+		ID:           keyID,
+		MonthlyQuota: request.MonthlyQuota,
+	})
+}
+
+func (h *AdminHandler) GetItemPoolStatus(w http.ResponseWriter, r *http.Request) {
+	var status seeding.Status
+	ok := false
+	if h.ItemPool != nil {
+		status, ok = h.ItemPool.Status()
+	}
+	if !ok {
+		er := &Error{
+			Error: "The item pool has not been seeded yet",
+			Code:  "NOT_FOUND",
+		}
+		ThrowError(er, w, r, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(status)
+}
+
+// actor is the key that authenticated r, which the audit log records key changes under.
+func actor(r *http.Request) auth.Actor {
+	actor := auth.Actor{Source: auth.AuditSourceAPI}
+	if key, ok := auth.APIKeyFromContext(r.Context()); ok {
+		actor.KeyID = &key.ID
+	}
+	return actor
+}
+
+// parseDate parses a YYYY-MM-DD date, returning fallback when s is empty.
+func parseDate(s string, fallback time.Time) (time.Time, bool) {
+	if s == "" {
+		return fallback, true
+	}
+
+	date, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return date, true
+}
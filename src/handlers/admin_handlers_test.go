@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"dZev1/character-gallery/models/auth"
+)
+
+// fakeAuthStore hands out one key; anything else panics through the nil embedded interface.
+type fakeAuthStore struct {
+	auth.AuthStore
+	key auth.APIKey
+}
+
+func (s *fakeAuthStore) ListAPIKeys() ([]auth.APIKey, error) {
+	return []auth.APIKey{s.key}, nil
+}
+
+func (s *fakeAuthStore) CreateAPIKey(actor auth.Actor, name string, scopes auth.Scopes, expiresIn time.Duration) (*auth.APIKey, string, error) {
+	return &s.key, "dz_chars_raw", nil
+}
+
+func TestAPIKeyResponses_LeaveOutHash(t *testing.T) {
+	h := &AdminHandler{AuthStore: &fakeAuthStore{key: auth.APIKey{ID: 1, KeyHash: "5f0c", Name: "bot", Scopes: auth.DefaultScopes, IsActive: true}}}
+
+	for name, serve := range map[string]func() *httptest.ResponseRecorder{
+		"list": func() *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			h.ListAPIKeys(rec, httptest.NewRequest(http.MethodGet, "/admin/api-keys", nil))
+			return rec
+		},
+		"create": func() *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			h.CreateAPIKey(rec, httptest.NewRequest(http.MethodPost, "/admin/api-keys", strings.NewReader(`{"name": "bot"}`)))
+			return rec
+		},
+	} {
+		rec := serve()
+		if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+			t.Fatalf("%s: unexpected status %d: %s", name, rec.Code, rec.Body)
+		}
+
+		body := rec.Body.String()
+		if strings.Contains(body, "key_hash") || strings.Contains(body, "5f0c") {
+			t.Errorf("%s: expected no key hash, got %s", name, body)
+		}
+		if !json.Valid(rec.Body.Bytes()) || !strings.Contains(body, `"name":"bot"`) {
+			t.Errorf("%s: expected the key, got %s", name, body)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"dZev1/character-gallery/models"
+	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/inventory"
+	"dZev1/character-gallery/models/validation"
+)
+
+// MaxBatchOperations is the most operations a batch can hold.
+const MaxBatchOperations = 100
+
+type BatchOp string
+
+const (
+	BatchCreateCharacter BatchOp = "create_character"
+	BatchEditCharacter   BatchOp = "edit_character"
+	BatchAddItem         BatchOp = "add_item"
+	BatchRemoveItem      BatchOp = "remove_item"
+	BatchCreateItem      BatchOp = "create_item"
+)
+
+var AllBatchOps = []BatchOp{BatchCreateCharacter, BatchEditCharacter, BatchAddItem, BatchRemoveItem, BatchCreateItem}
+
+// BatchOperation is one write of a batch. Characters and items are given either by ID, or by the
+// ref of the earlier operation that created them.
+type BatchOperation struct {
+	Op BatchOp `json:"op"`
+	// Ref names the character or item created by the operation, for later operations to use.
+	Ref          string                 `json:"ref,omitempty"`
+	CharacterID  characters.CharacterID `json:"character_id,omitempty"`
+	CharacterRef string                 `json:"character_ref,omitempty"`
+	ItemID       inventory.ItemID       `json:"item_id,omitempty"`
+	ItemRef      string                 `json:"item_ref,omitempty"`
+	// Quantity of add_item and remove_item. It defaults to 1.
+	Quantity  *int                  `json:"quantity,omitempty"`
+	Character *characters.Character `json:"character,omitempty"`
+	Item      *inventory.Item       `json:"item,omitempty"`
+}
+
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchResult is what an operation wrote: the created or edited character, the created item, or
+// the inventory entry an item was added to.
+type BatchResult struct {
+	Op          BatchOp                  `json:"op"`
+	Ref         string                   `json:"ref,omitempty"`
+	CharacterID characters.CharacterID   `json:"character_id,omitempty"`
+	ItemID      inventory.ItemID         `json:"item_id,omitempty"`
+	Character   *characters.Character    `json:"character,omitempty"`
+	Item        *inventory.Item          `json:"item,omitempty"`
+	Entry       *inventory.InventoryItem `json:"entry,omitempty"`
+}
+
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+// BatchFailure is the details of an error answered when an operation failed. Nothing the batch
+// wrote was kept.
+type BatchFailure struct {
+	Operation int     `json:"operation"`
+	Op        BatchOp `json:"op"`
+}
+
+// RunBatch makes the writes of a batch in order, in one transaction: either all of them are
+// kept, or none is.
+func (h *CharacterHandler) RunBatch(w http.ResponseWriter, r *http.Request) {
+	var req BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		er := &Error{
+			Error: "Invalid request body",
+			Code:  "BAD_REQUEST",
+		}
+		ThrowError(er, w, r, http.StatusBadRequest)
+		return
+	}
+
+	if errs := validateBatch(req.Operations); len(errs) > 0 {
+		throwValidationError(errs, w, r)
+		return
+	}
+
+	results := make([]BatchResult, len(req.Operations))
+	failed := -1
+	err := h.Gallery.Batch(r.Context(), func(ctx context.Context, tx models.BatchTx) error {
+		refs := batchRefs{characters: map[string]characters.CharacterID{}, items: map[string]inventory.ItemID{}}
+		for i := range req.Operations {
+			result, err := runBatchOperation(ctx, tx, &req.Operations[i], refs)
+			if err != nil {
+				failed = i
+				return err
+			}
+			results[i] = result
+		}
+		return nil
+	})
+	if err != nil && failed >= 0 {
+		details := BatchFailure{Operation: failed, Op: req.Operations[failed].Op}
+		throwStoreError(err, fmt.Sprintf("Could not run batch operation %d", failed), details, w, r)
+		return
+	}
+	if err != nil {
+		throwStoreError(err, "Could not commit batch", nil, w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(BatchResponse{Results: results})
+}
+
+// validateBatch reports every invalid operation before anything is written, including refs that
+// are not given by an earlier operation creating the right kind of record.
+func validateBatch(operations []BatchOperation) validation.Errors {
+	var errs validation.Errors
+	switch {
+	case len(operations) == 0:
+		errs.Add("operations", validation.CodeRequired, "is required")
+	case len(operations) > MaxBatchOperations:
+		errs.Add("operations", validation.CodeTooLong, fmt.Sprintf("must hold at most %d operations", MaxBatchOperations))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	created := map[string]BatchOp{}
+	for i, op := range operations {
+		path := fmt.Sprintf("operations[%d]", i)
+		var opErrs validation.Errors
+
+		switch op.Op {
+		case BatchCreateCharacter:
+			validateCharacter(&opErrs, op)
+		case BatchEditCharacter:
+			validateCharacterRef(&opErrs, op, created)
+			validateCharacter(&opErrs, op)
+		case BatchAddItem, BatchRemoveItem:
+			validateCharacterRef(&opErrs, op, created)
+			if op.ItemID == 0 && op.ItemRef == "" {
+				opErrs.Add("item_id", validation.CodeRequired, "item_id or item_ref is required")
+			}
+			if op.ItemRef != "" && created[op.ItemRef] != BatchCreateItem {
+				opErrs.Add("item_ref", validation.CodeInvalidValue, fmt.Sprintf("%q is not the ref of an earlier create_item", op.ItemRef))
+			}
+			if op.Quantity != nil && (*op.Quantity < 1 || *op.Quantity > 255) {
+				opErrs.Add("quantity", validation.CodeOutOfRange, "must be between 1 and 255")
+			}
+		case BatchCreateItem:
+			if op.Item == nil {
+				opErrs.Add("item", validation.CodeRequired, "is required")
+			} else {
+				opErrs.Merge("item", op.Item.Validate())
+			}
+		default:
+			opErrs.Merge("op", validation.OneOf(op.Op, AllBatchOps))
+		}
+
+		if op.Ref != "" {
+			_, taken := created[op.Ref]
+			switch {
+			case op.Op != BatchCreateCharacter && op.Op != BatchCreateItem:
+				opErrs.Add("ref", validation.CodeInvalidValue, "only create_character and create_item operations have a ref")
+			case taken:
+				opErrs.Add("ref", validation.CodeInvalidValue, fmt.Sprintf("%q is already the ref of an earlier operation", op.Ref))
+			default:
+				created[op.Ref] = op.Op
+			}
+		}
+
+		errs.Merge(path, opErrs.Err())
+	}
+	return errs
+}
+
+func validateCharacter(errs *validation.Errors, op BatchOperation) {
+	if op.Character == nil {
+		errs.Add("character", validation.CodeRequired, "is required")
+	} else {
+		errs.Merge("character", op.Character.Validate())
+	}
+}
+
+func validateCharacterRef(errs *validation.Errors, op BatchOperation, created map[string]BatchOp) {
+	if op.CharacterID == 0 && op.CharacterRef == "" {
+		errs.Add("character_id", validation.CodeRequired, "character_id or character_ref is required")
+	}
+	if op.CharacterRef != "" && created[op.CharacterRef] != BatchCreateCharacter {
+		errs.Add("character_ref", validation.CodeInvalidValue, fmt.Sprintf("%q is not the ref of an earlier create_character", op.CharacterRef))
+	}
+}
+
+// batchRefs holds the IDs of the records created so far, by ref.
+type batchRefs struct {
+	characters map[string]characters.CharacterID
+	items      map[string]inventory.ItemID
+}
+
+func (refs batchRefs) character(op *BatchOperation) characters.CharacterID {
+	if op.CharacterRef != "" {
+		return refs.characters[op.CharacterRef]
+	}
+	return op.CharacterID
+}
+
+func (refs batchRefs) item(op *BatchOperation) inventory.ItemID {
+	if op.ItemRef != "" {
+		return refs.items[op.ItemRef]
+	}
+	return op.ItemID
+}
+
+func runBatchOperation(ctx context.Context, tx models.BatchTx, op *BatchOperation, refs batchRefs) (BatchResult, error) {
+	result := BatchResult{Op: op.Op, Ref: op.Ref}
+	quantity := uint8(1)
+	if op.Quantity != nil {
+		quantity = uint8(*op.Quantity)
+	}
+
+	switch op.Op {
+	case BatchCreateCharacter:
+		if err := tx.Create(ctx, op.Character); err != nil {
+			return result, err
+		}
+		if op.Ref != "" {
+			refs.characters[op.Ref] = op.Character.ID
+		}
+		result.CharacterID, result.Character = op.Character.ID, op.Character
+
+	case BatchEditCharacter:
+		id := refs.character(op)
+		op.Character.ID = id
+		op.Character.Stats.ID = id
+		op.Character.Customization.ID = id
+		if err := tx.Edit(ctx, op.Character); err != nil {
+			return result, err
+		}
+		result.CharacterID, result.Character = id, op.Character
+
+	case BatchAddItem:
+		result.CharacterID, result.ItemID = refs.character(op), refs.item(op)
+		entry, err := tx.AddItemToCharacter(ctx, result.CharacterID, result.ItemID, quantity)
+		if err != nil {
+			return result, err
+		}
+		result.Entry = entry
+
+	case BatchRemoveItem:
+		result.CharacterID, result.ItemID = refs.character(op), refs.item(op)
+		if err := tx.RemoveItemFromCharacter(ctx, result.CharacterID, result.ItemID, quantity); err != nil {
+			return result, err
+		}
+
+	case BatchCreateItem:
+		if err := tx.CreateItem(ctx, op.Item); err != nil {
+			return result, err
+		}
+		if op.Ref != "" {
+			refs.items[op.Ref] = op.Item.ID
+		}
+		result.ItemID, result.Item = op.Item.ID, op.Item
+	}
+
+	return result, nil
+}
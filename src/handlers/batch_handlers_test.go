@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dZev1/character-gallery/models"
+	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/inventory"
+	"dZev1/character-gallery/models/validation"
+)
+
+// fakeBatchGallery runs batches on a fakeBatchTx; anything else panics through the nil embedded
+// interface.
+type fakeBatchGallery struct {
+	models.CharacterGallery
+	tx      *fakeBatchTx
+	batches int
+}
+
+func (g *fakeBatchGallery) Batch(ctx context.Context, fn func(ctx context.Context, tx models.BatchTx) error) error {
+	g.batches++
+	return fn(ctx, g.tx)
+}
+
+// fakeBatchTx records the writes made, numbering created records from 100.
+type fakeBatchTx struct {
+	writes  []string
+	missing inventory.ItemID
+}
+
+func (tx *fakeBatchTx) Create(ctx context.Context, character *characters.Character) error {
+	character.ID = characters.CharacterID(100 + len(tx.writes))
+	tx.writes = append(tx.writes, "create "+character.Name)
+	return nil
+}
+
+func (tx *fakeBatchTx) Edit(ctx context.Context, character *characters.Character) error {
+	tx.writes = append(tx.writes, "edit "+character.Name)
+	return nil
+}
+
+func (tx *fakeBatchTx) AddItemToCharacter(ctx context.Context, characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) (*inventory.InventoryItem, error) {
+	if itemID == tx.missing {
+		return nil, models.NotFound("item not found", nil)
+	}
+	tx.writes = append(tx.writes, "add")
+	return &inventory.InventoryItem{Item: &inventory.Item{ID: itemID}, Quantity: quantity}, nil
+}
+
+func (tx *fakeBatchTx) RemoveItemFromCharacter(ctx context.Context, characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) error {
+	tx.writes = append(tx.writes, "remove")
+	return nil
+}
+
+func (tx *fakeBatchTx) CreateItem(ctx context.Context, item *inventory.Item) error {
+	item.ID = inventory.ItemID(100 + len(tx.writes))
+	tx.writes = append(tx.writes, "create "+item.Name)
+	return nil
+}
+
+const batchCharacter = `{"name": "Aria", "body_type": "type_a", "species": "elf", "class": "wizard",
+	"stats": {"strength": 8, "dexterity": 14, "constitution": 12, "intelligence": 16, "wisdom": 10, "charisma": 10},
+	"customization": {"hair": 1, "face": 2, "shirt": 3, "pants": 4, "shoes": 5}}`
+
+const batchItem = `{"name": "Staff", "type": "staff", "description": "Gnarled", "rarity": 2}`
+
+func runBatch(t *testing.T, gallery *fakeBatchGallery, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/batch", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	(&CharacterHandler{Gallery: gallery}).RunBatch(rec, req)
+	return rec
+}
+
+func TestRunBatch_ResolvesRefs(t *testing.T) {
+	gallery := &fakeBatchGallery{tx: &fakeBatchTx{}}
+
+	rec := runBatch(t, gallery, `{"operations": [
+		{"op": "create_character", "ref": "hero", "character": `+batchCharacter+`},
+		{"op": "create_item", "ref": "staff", "item": `+batchItem+`},
+		{"op": "add_item", "character_ref": "hero", "item_ref": "staff", "quantity": 2},
+		{"op": "add_item", "character_ref": "hero", "item_id": 7},
+		{"op": "edit_character", "character_ref": "hero", "character": `+batchCharacter+`}
+	]}`)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var res BatchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Results) != 5 {
+		t.Fatalf("expected 5 results, got %+v", res.Results)
+	}
+	if res.Results[0].CharacterID != 100 || res.Results[1].ItemID != 101 {
+		t.Errorf("unexpected created records %+v %+v", res.Results[0], res.Results[1])
+	}
+	if added := res.Results[2]; added.CharacterID != 100 || added.ItemID != 101 || added.Entry == nil || added.Entry.Quantity != 2 {
+		t.Errorf("expected the created item added to the created character, got %+v", added)
+	}
+	if added := res.Results[3]; added.ItemID != 7 || added.Entry.Quantity != 1 {
+		t.Errorf("expected one item 7 added, got %+v", added)
+	}
+	if edited := res.Results[4].Character; edited == nil || edited.ID != 100 || edited.Name != "Aria" {
+		t.Errorf("expected the created character edited, got %+v", edited)
+	}
+}
+
+func TestRunBatch_ValidatesEveryOperationFirst(t *testing.T) {
+	gallery := &fakeBatchGallery{tx: &fakeBatchTx{}}
+
+	rec := runBatch(t, gallery, `{"operations": [
+		{"op": "create_item", "ref": "staff", "item": `+batchItem+`},
+		{"op": "add_item", "character_ref": "staff", "item_ref": "staff", "quantity": 0},
+		{"op": "create_character", "character": {"name": "A"}},
+		{"op": "delete_character", "character_id": 1}
+	]}`)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", rec.Code, rec.Body)
+	}
+	if gallery.batches != 0 {
+		t.Error("expected nothing written for an invalid batch")
+	}
+
+	var body struct {
+		Details validation.Errors `json:"details"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	paths := map[string]bool{}
+	for _, fieldErr := range body.Details {
+		paths[fieldErr.Path] = true
+	}
+	for _, path := range []string{"operations[1].character_ref", "operations[1].quantity", "operations[2].character.name", "operations[2].character.stats", "operations[3].op"} {
+		if !paths[path] {
+			t.Errorf("expected an error at %s, got %+v", path, body.Details)
+		}
+	}
+	if paths["operations[1].item_ref"] {
+		t.Errorf("expected the item ref to be valid, got %+v", body.Details)
+	}
+}
+
+func TestRunBatch_ReportsFailedOperation(t *testing.T) {
+	gallery := &fakeBatchGallery{tx: &fakeBatchTx{missing: 404}}
+
+	rec := runBatch(t, gallery, `{"operations": [
+		{"op": "create_character", "character": `+batchCharacter+`},
+		{"op": "add_item", "character_id": 1, "item_id": 404}
+	]}`)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Details BatchFailure `json:"details"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Details.Operation != 1 || body.Details.Op != BatchAddItem {
+		t.Errorf("expected operation 1 to be reported, got %+v", body.Details)
+	}
+}
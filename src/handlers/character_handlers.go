@@ -11,6 +11,8 @@ import (
 
 type CharacterHandler struct {
 	Gallery models.CharacterGallery
+	// PageSize is how many characters GetAllCharacters returns per page. It defaults to 20.
+	PageSize int
 }
 
 type Pagination struct {
@@ -29,21 +31,17 @@ func (h *CharacterHandler) CreateCharacter(w http.ResponseWriter, r *http.Reques
 			Error: "Invalid request body",
 			Code:  "BAD_REQUEST",
 		}
-		throwError(er, w, http.StatusBadRequest)
+		ThrowError(er, w, r, http.StatusBadRequest)
 		return
 	}
 
-	if valid := validateCharacter(newCharacter, w); !valid {
+	if !validate(newCharacter, w, r) {
 		return
 	}
 
-	err = h.Gallery.Create(newCharacter)
+	err = h.Gallery.Create(r.Context(), newCharacter)
 	if err != nil {
-		er := &Error{
-			Error: "Could not create character",
-			Code:  "INTERNAL_SERVER_ERROR",
-		}
-		throwError(er, w, http.StatusInternalServerError)
+		throwStoreError(err, "Could not create character", nil, w, r)
 		return
 	}
 
@@ -54,6 +52,10 @@ func (h *CharacterHandler) CreateCharacter(w http.ResponseWriter, r *http.Reques
 }
 
 func (h *CharacterHandler) GetAllCharacters(w http.ResponseWriter, r *http.Request) {
+	pageSize := h.PageSize
+	if pageSize == 0 {
+		pageSize = 20
+	}
 	page := 0
 
 	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
@@ -68,13 +70,17 @@ func (h *CharacterHandler) GetAllCharacters(w http.ResponseWriter, r *http.Reque
 					Page: pageStr,
 				},
 			}
-			throwError(er, w, http.StatusBadRequest)
+			ThrowError(er, w, r, http.StatusBadRequest)
 			return
 		}
-		page = p * 20
+		page = p
 	}
 
-	chars, totalChars, err := h.Gallery.GetAll(page)
+	chars, totalChars, err := h.Gallery.GetAll(r.Context(), characters.CharacterFilter{}, page*pageSize, pageSize)
+	if err != nil {
+		throwStoreError(err, "Could not list characters", nil, w, r)
+		return
+	}
 
 	response := struct {
 		Data       []characters.Character `json:"data"`
@@ -82,20 +88,12 @@ func (h *CharacterHandler) GetAllCharacters(w http.ResponseWriter, r *http.Reque
 	}{
 		Data: chars,
 		Pagination: Pagination{
-			Page:    uint8(page / 20),
-			Limit:   20,
+			Page:    uint8(page),
+			Limit:   uint8(pageSize),
 			Total:   totalChars,
-			HasNext: len(chars) == 20,
+			HasNext: uint64((page+1)*pageSize) < totalChars,
 		},
 	}
-	if err != nil {
-		er := &Error{
-			Error: "Page not found",
-			Code:  "NOT_FOUND",
-		}
-		throwError(er, w, http.StatusNotFound)
-		return
-	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -116,22 +114,13 @@ func (h *CharacterHandler) GetCharacter(w http.ResponseWriter, r *http.Request)
 				ID: idStr,
 			},
 		}
-		throwError(er, w, http.StatusBadRequest)
+		ThrowError(er, w, r, http.StatusBadRequest)
 		return
 	}
 
-	character, err := h.Gallery.Get(characters.CharacterID(id))
+	character, err := h.Gallery.Get(r.Context(), characters.CharacterID(id))
 	if err != nil {
-		er := &Error{
-			Error: "Character not found",
-			Code:  "NOT_FOUND",
-			Details: struct {
-				ID string `json:"id"`
-			}{
-				ID: idStr,
-			},
-		}
-		throwError(er, w, http.StatusNotFound)
+		throwStoreError(err, "Could not retrieve character", idDetails(idStr), w, r)
 		return
 	}
 
@@ -154,7 +143,7 @@ func (h *CharacterHandler) EditCharacter(w http.ResponseWriter, r *http.Request)
 				ID: idStr,
 			},
 		}
-		throwError(er, w, http.StatusBadRequest)
+		ThrowError(er, w, r, http.StatusBadRequest)
 		return
 	}
 
@@ -165,16 +154,11 @@ func (h *CharacterHandler) EditCharacter(w http.ResponseWriter, r *http.Request)
 			Error: "Invalid Request Body",
 			Code:  "BAD_REQUEST",
 		}
-		throwError(er, w, http.StatusBadRequest)
+		ThrowError(er, w, r, http.StatusBadRequest)
 		return
 	}
 
-	if valid := validateCharacter(characterToEdit, w); !valid {
-		er := &Error{
-			Error: "Invalid character",
-			Code:  "BAD_REQUEST",
-		}
-		throwError(er, w, http.StatusBadRequest)
+	if !validate(characterToEdit, w, r) {
 		return
 	}
 
@@ -182,13 +166,9 @@ func (h *CharacterHandler) EditCharacter(w http.ResponseWriter, r *http.Request)
 	characterToEdit.Stats.ID = characters.CharacterID(id)
 	characterToEdit.Customization.ID = characters.CharacterID(id)
 
-	err = h.Gallery.Edit(characterToEdit)
+	err = h.Gallery.Edit(r.Context(), characterToEdit)
 	if err != nil {
-		er := &Error{
-			Error: "Could not edit character",
-			Code:  "INTERNAL_SERVER_ERROR",
-		}
-		throwError(er, w, http.StatusInternalServerError)
+		throwStoreError(err, "Could not edit character", idDetails(idStr), w, r)
 		return
 	}
 
@@ -211,22 +191,13 @@ func (h *CharacterHandler) DeleteCharacter(w http.ResponseWriter, r *http.Reques
 				ID: idStr,
 			},
 		}
-		throwError(er, w, http.StatusBadRequest)
+		ThrowError(er, w, r, http.StatusBadRequest)
 		return
 	}
 
-	err = h.Gallery.Remove(characters.CharacterID(id))
+	err = h.Gallery.Remove(r.Context(), characters.CharacterID(id))
 	if err != nil {
-		er := &Error{
-			Error: "Character not found",
-			Code:  "NOT_FOUND",
-			Details: struct {
-				ID string `json:"id"`
-			}{
-				ID: idStr,
-			},
-		}
-		throwError(er, w, http.StatusNotFound)
+		throwStoreError(err, "Could not delete character", idDetails(idStr), w, r)
 		return
 	}
 
@@ -1,8 +1,19 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"dZev1/character-gallery/models"
+	"dZev1/character-gallery/models/auth"
+	"dZev1/character-gallery/models/validation"
 )
 
 type Error struct {
@@ -11,8 +22,138 @@ type Error struct {
 	Details any    `json:"details,omitempty"`
 }
 
-func throwError(er *Error, w http.ResponseWriter, statusCode int) {
+// Problem is an RFC 9457 problem details object. Code and Details carry the fields of Error.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+	Details  any    `json:"details,omitempty"`
+}
+
+// Error formats, chosen per request by preferredErrorFormat.
+const (
+	ErrorFormatJSON    = "json"
+	ErrorFormatProblem = "problem"
+)
+
+const problemContentType = "application/problem+json"
+
+var defaultErrorFormat = ErrorFormatJSON
+
+// SetDefaultErrorFormat sets the format used for clients that don't ask for
+// application/problem+json. It should be called before the server starts.
+func SetDefaultErrorFormat(format string) error {
+	switch format {
+	case "", ErrorFormatJSON:
+		defaultErrorFormat = ErrorFormatJSON
+	case ErrorFormatProblem:
+		defaultErrorFormat = ErrorFormatProblem
+	default:
+		return fmt.Errorf("invalid error format %q, expected %s or %s", format, ErrorFormatJSON, ErrorFormatProblem)
+	}
+	return nil
+}
+
+func ThrowError(er *Error, w http.ResponseWriter, r *http.Request, statusCode int) {
+	w.Header().Add("Vary", "Accept")
+
+	if preferredErrorFormat(r) == ErrorFormatProblem {
+		w.Header().Set("Content-Type", problemContentType)
+		w.WriteHeader(statusCode)
+		json.NewEncoder(w).Encode(&Problem{
+			Type:     "about:blank",
+			Title:    http.StatusText(statusCode),
+			Status:   statusCode,
+			Detail:   er.Error,
+			Instance: r.URL.Path,
+			Code:     er.Code,
+			Details:  er.Details,
+		})
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(er)
 }
+
+// preferredErrorFormat returns problem when the Accept header lists application/problem+json,
+// and the configured default otherwise.
+func preferredErrorFormat(r *http.Request) string {
+	for _, accept := range r.Header.Values("Accept") {
+		for mediaRange := range strings.SplitSeq(accept, ",") {
+			mediaType, params, err := mime.ParseMediaType(mediaRange)
+			if err != nil || mediaType != problemContentType {
+				continue
+			}
+			if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+				return ErrorFormatJSON
+			}
+			return ErrorFormatProblem
+		}
+	}
+	return defaultErrorFormat
+}
+
+// throwStoreError responds to a failed gallery or auth store call with the status matching the
+// kind of failure. message describes the failed operation for errors the client can't act on,
+// and details, such as the requested ID, is added to the response.
+func throwStoreError(err error, message string, details any, w http.ResponseWriter, r *http.Request) {
+	er, status := StoreError(r.Context(), err, message, details)
+	ThrowError(er, w, r, status)
+}
+
+// StoreError returns the error response to a failed gallery or auth store call, and its status,
+// for the APIs that answer with their own envelope, such as GraphQL. Failures the client can't
+// act on are logged.
+func StoreError(ctx context.Context, err error, message string, details any) (*Error, int) {
+	var fieldErrs validation.Errors
+	if errors.As(err, &fieldErrs) {
+		return &Error{Error: "Request validation failed", Code: "VALIDATION_FAILED", Details: fieldErrs}, http.StatusUnprocessableEntity
+	}
+
+	er := &Error{Details: details}
+	var status int
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		er.Error, er.Code, status = modelMessage(err, "Resource not found"), "NOT_FOUND", http.StatusNotFound
+	case errors.Is(err, auth.ErrAPIKeyNotFound):
+		er.Error, er.Code, status = "API key not found", "NOT_FOUND", http.StatusNotFound
+	case errors.Is(err, auth.ErrAPIKeyRevoked):
+		er.Error, er.Code, status = "API key has been revoked", "CONFLICT", http.StatusConflict
+	case errors.Is(err, auth.ErrAPIKeyExpired):
+		er.Error, er.Code, status = "API key has expired", "CONFLICT", http.StatusConflict
+	case errors.Is(err, models.ErrConflict):
+		er.Error, er.Code, status = modelMessage(err, "Resource already exists"), "CONFLICT", http.StatusConflict
+	case errors.Is(err, models.ErrValidation):
+		er.Error, er.Code, status = modelMessage(err, "Request validation failed"), "VALIDATION_FAILED", http.StatusUnprocessableEntity
+	case errors.Is(err, models.ErrUnavailable):
+		slog.WarnContext(ctx, message, "error", err)
+		er.Error, er.Code, status = "Storage is temporarily unavailable", "SERVICE_UNAVAILABLE", http.StatusServiceUnavailable
+	default:
+		slog.ErrorContext(ctx, message, "error", err)
+		er.Error, er.Code, status = message, "INTERNAL_SERVER_ERROR", http.StatusInternalServerError
+	}
+	return er, status
+}
+
+// idDetails identifies the requested resource in error responses.
+func idDetails(idStr string) any {
+	return struct {
+		ID string `json:"id"`
+	}{
+		ID: idStr,
+	}
+}
+
+// modelMessage returns the client-facing message of a models.Error, or fallback.
+func modelMessage(err error, fallback string) string {
+	var modelErr *models.Error
+	if !errors.As(err, &modelErr) || modelErr.Message == "" {
+		return fallback
+	}
+	return strings.ToUpper(modelErr.Message[:1]) + modelErr.Message[1:]
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dZev1/character-gallery/models"
+	"dZev1/character-gallery/models/auth"
+	"dZev1/character-gallery/models/validation"
+)
+
+func TestThrowStoreError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		{"not found", models.NotFound("character not found", errors.New("no rows")), http.StatusNotFound, "NOT_FOUND", "Character not found"},
+		{"wrapped not found", fmt.Errorf("could not get: %w", models.NotFound("item not found", nil)), http.StatusNotFound, "NOT_FOUND", "Item not found"},
+		{"api key not found", auth.ErrAPIKeyNotFound, http.StatusNotFound, "NOT_FOUND", "API key not found"},
+		{"conflict", models.Conflict("item already exists", nil), http.StatusConflict, "CONFLICT", "Item already exists"},
+		{"invalid", models.Invalid("invalid character", nil), http.StatusUnprocessableEntity, "VALIDATION_FAILED", "Invalid character"},
+		{"field errors", validation.Errors{{Path: "name", Code: validation.CodeTooShort}}, http.StatusUnprocessableEntity, "VALIDATION_FAILED", "Request validation failed"},
+		{"unavailable", models.Unavailable("the database is unavailable", nil), http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "Storage is temporarily unavailable"},
+		{"unknown", errors.New("boom"), http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Could not do it"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			throwStoreError(tt.err, "Could not do it", nil, rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, rec.Code)
+			}
+
+			var body Error
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("could not decode body: %v", err)
+			}
+			if body.Code != tt.code || body.Error != tt.message {
+				t.Errorf("expected %s %q, got %s %q", tt.code, tt.message, body.Code, body.Error)
+			}
+		})
+	}
+}
+
+func TestThrowError_Format(t *testing.T) {
+	tests := []struct {
+		name          string
+		defaultFormat string
+		accept        string
+		contentType   string
+	}{
+		{"default", "", "", "application/json"},
+		{"accept problem", "", "application/problem+json", "application/problem+json"},
+		{"accept problem among others", "", "application/json, application/problem+json;q=0.9", "application/problem+json"},
+		{"problem refused", ErrorFormatProblem, "application/problem+json; q=0", "application/json"},
+		{"configured problem", ErrorFormatProblem, "application/json", "application/problem+json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetDefaultErrorFormat(tt.defaultFormat); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			t.Cleanup(func() { SetDefaultErrorFormat(ErrorFormatJSON) })
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/characters/7", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			ThrowError(&Error{Error: "Character not found", Code: "NOT_FOUND"}, rec, r, http.StatusNotFound)
+
+			if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("expected Content-Type %s, got %s", tt.contentType, got)
+			}
+		})
+	}
+}
+
+func TestThrowError_Problem(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/characters/7", nil)
+	r.Header.Set("Accept", "application/problem+json")
+	rec := httptest.NewRecorder()
+
+	details := map[string]string{"id": "7"}
+	ThrowError(&Error{Error: "Character not found", Code: "NOT_FOUND", Details: details}, rec, r, http.StatusNotFound)
+
+	var problem map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+		t.Fatalf("could not decode body: %v", err)
+	}
+
+	want := map[string]any{
+		"type":     "about:blank",
+		"title":    "Not Found",
+		"status":   float64(404),
+		"detail":   "Character not found",
+		"instance": "/api/v1/characters/7",
+		"code":     "NOT_FOUND",
+	}
+	for key, value := range want {
+		if problem[key] != value {
+			t.Errorf("expected %s %v, got %v", key, value, problem[key])
+		}
+	}
+	if got, _ := problem["details"].(map[string]any); got["id"] != "7" {
+		t.Errorf("expected details to be kept, got %v", problem["details"])
+	}
+}
+
+func TestSetDefaultErrorFormat_Invalid(t *testing.T) {
+	if err := SetDefaultErrorFormat("xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"dZev1/character-gallery/internal/changes"
+	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/events"
+)
+
+// EventResetType is sent when a stream resumed from an event that is no longer buffered, or the
+// server missed the events of other instances. Events were missed, so the client should refetch
+// what it shows.
+const EventResetType = "reset"
+
+type EventHandler struct {
+	Broker *changes.Broker
+	// Heartbeat is how often a comment is sent on an idle stream, so that proxies keep it open.
+	Heartbeat time.Duration
+}
+
+// StreamEvents streams the gallery changes as server-sent events until the client disconnects.
+func (h *EventHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	filter, ok := parseEventFilter(w, r)
+	if !ok {
+		return
+	}
+
+	sub, replay, reset := h.Broker.Subscribe(filter, r.Header.Get("Last-Event-ID"))
+	defer sub.Close()
+
+	// The stream outlives the server's write timeout.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		slog.WarnContext(r.Context(), "could not clear the write deadline of an event stream", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if reset {
+		writeReset(w)
+	}
+	for _, message := range replay {
+		writeEvent(w, message)
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(h.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case message, ok := <-sub.Messages():
+			if !ok {
+				// Dropped for falling behind; the client reconnects and resumes from the buffer.
+				return
+			}
+			writeEvent(w, message)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, message changes.Message) {
+	if message.Reset {
+		writeReset(w)
+		return
+	}
+	data, _ := json.Marshal(message.Event)
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", message.ID, message.Event.Type, data)
+}
+
+func writeReset(w http.ResponseWriter) {
+	fmt.Fprintf(w, "event: %s\ndata: {}\n\n", EventResetType)
+}
+
+// parseEventFilter reads the types and character_id query parameters, answering 400 when they
+// are invalid.
+func parseEventFilter(w http.ResponseWriter, r *http.Request) (changes.Filter, bool) {
+	var filter changes.Filter
+
+	types, err := events.ParseEventTypes(r.URL.Query().Get("types"))
+	if err != nil {
+		er := &Error{
+			Error: "Invalid event types",
+			Code:  "BAD_REQUEST",
+			Details: struct {
+				Types   string             `json:"types"`
+				Allowed []events.EventType `json:"allowed"`
+			}{
+				Types:   r.URL.Query().Get("types"),
+				Allowed: events.AllEventTypes,
+			},
+		}
+		ThrowError(er, w, r, http.StatusBadRequest)
+		return filter, false
+	}
+	filter.Types = types
+
+	if idStr := r.URL.Query().Get("character_id"); idStr != "" {
+		id, err := strconv.ParseUint(idStr, 10, 64)
+		if err != nil || id == 0 {
+			er := &Error{
+				Error:   "Invalid character ID",
+				Code:    "BAD_REQUEST",
+				Details: idDetails(idStr),
+			}
+			ThrowError(er, w, r, http.StatusBadRequest)
+			return filter, false
+		}
+		filter.CharacterID = characters.CharacterID(id)
+	}
+
+	return filter, true
+}
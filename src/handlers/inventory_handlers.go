@@ -29,7 +29,7 @@ func (h *CharacterHandler) AddItemToCharacter(w http.ResponseWriter, r *http.Req
 				CharacterID: characterIDStr,
 			},
 		}
-		throwError(er, w, http.StatusBadRequest)
+		ThrowError(er, w, r, http.StatusBadRequest)
 		return
 	}
 
@@ -44,7 +44,7 @@ func (h *CharacterHandler) AddItemToCharacter(w http.ResponseWriter, r *http.Req
 				ItemID: itemIDStr,
 			},
 		}
-		throwError(er, w, http.StatusBadRequest)
+		ThrowError(er, w, r, http.StatusBadRequest)
 		return
 	}
 
@@ -59,17 +59,20 @@ func (h *CharacterHandler) AddItemToCharacter(w http.ResponseWriter, r *http.Req
 				Quantity: quantityStr,
 			},
 		}
-		throwError(er, w, http.StatusBadRequest)
+		ThrowError(er, w, r, http.StatusBadRequest)
 		return
 	}
 
-	item, err := h.Gallery.AddItemToCharacter(characters.CharacterID(characterID), inventory.ItemID(itemID), uint8(quantity))
+	item, err := h.Gallery.AddItemToCharacter(r.Context(), characters.CharacterID(characterID), inventory.ItemID(itemID), uint8(quantity))
 	if err != nil {
-		er := &Error{
-			Error: "Could not add item to character",
-			Code:  "INTERNAL_SERVER_ERROR",
+		details := struct {
+			CharacterID string `json:"character_id"`
+			ItemID      string `json:"item_id"`
+		}{
+			CharacterID: characterIDStr,
+			ItemID:      itemIDStr,
 		}
-		throwError(er, w, http.StatusInternalServerError)
+		throwStoreError(err, "Could not add item to character", details, w, r)
 		return
 	}
 
@@ -98,7 +101,7 @@ func (h *CharacterHandler) RemoveItemFromCharacter(w http.ResponseWriter, r *htt
 				CharacterID: characterIDStr,
 			},
 		}
-		throwError(er, w, http.StatusBadRequest)
+		ThrowError(er, w, r, http.StatusBadRequest)
 		return
 	}
 
@@ -113,7 +116,7 @@ func (h *CharacterHandler) RemoveItemFromCharacter(w http.ResponseWriter, r *htt
 				ItemID: itemIDStr,
 			},
 		}
-		throwError(er, w, http.StatusBadRequest)
+		ThrowError(er, w, r, http.StatusBadRequest)
 		return
 	}
 
@@ -128,30 +131,26 @@ func (h *CharacterHandler) RemoveItemFromCharacter(w http.ResponseWriter, r *htt
 				Quantity: quantityStr,
 			},
 		}
-		throwError(er, w, http.StatusBadRequest)
+		ThrowError(er, w, r, http.StatusBadRequest)
 		return
 	}
 
-	err = h.Gallery.RemoveItemFromCharacter(characters.CharacterID(characterID), inventory.ItemID(itemID), uint8(quantity))
+	err = h.Gallery.RemoveItemFromCharacter(r.Context(), characters.CharacterID(characterID), inventory.ItemID(itemID), uint8(quantity))
 	if err != nil {
-		er := &Error{
-			Error: "Could not remove item from character",
-			Code:  "INTERNAL_SERVER_ERROR",
-			Details: struct {
-				CharacterID string `json:"character_id"`
-				ItemID      string `json:"item_id"`
-				Quantity    string `json:"quantity"`
-			}{
-				CharacterID: characterIDStr,
-				ItemID:      itemIDStr,
-				Quantity:    quantityStr,
-			},
+		details := struct {
+			CharacterID string `json:"character_id"`
+			ItemID      string `json:"item_id"`
+			Quantity    string `json:"quantity"`
+		}{
+			CharacterID: characterIDStr,
+			ItemID:      itemIDStr,
+			Quantity:    quantityStr,
 		}
-		throwError(er, w, http.StatusInternalServerError)
+		throwStoreError(err, "Could not remove item from character", details, w, r)
 		return
 	}
 
-	item, _ := h.Gallery.DisplayItem(inventory.ItemID(itemID))
+	item, _ := h.Gallery.DisplayItem(r.Context(), inventory.ItemID(itemID))
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(item)
@@ -171,17 +170,13 @@ func (h *CharacterHandler) GetCharacterInventory(w http.ResponseWriter, r *http.
 				CharacterID: characterIDStr,
 			},
 		}
-		throwError(er, w, http.StatusBadRequest)
+		ThrowError(er, w, r, http.StatusBadRequest)
 		return
 	}
 
-	invItems, err := h.Gallery.GetCharacterInventory(characters.CharacterID(characterID))
+	invItems, err := h.Gallery.GetCharacterInventory(r.Context(), characters.CharacterID(characterID))
 	if err != nil {
-		er := &Error{
-			Error: "Could not retrieve inventory",
-			Code:  "INTERNAL_SERVER_ERROR",
-		}
-		throwError(er, w, http.StatusInternalServerError)
+		throwStoreError(err, "Could not retrieve inventory", nil, w, r)
 		return
 	}
 
@@ -191,13 +186,10 @@ func (h *CharacterHandler) GetCharacterInventory(w http.ResponseWriter, r *http.
 }
 
 func (h *CharacterHandler) ShowPoolItems(w http.ResponseWriter, r *http.Request) {
-	items, err := h.Gallery.DisplayPoolItems()
+	filter := inventory.ItemFilter{Pack: r.URL.Query().Get("pack")}
+	items, err := h.Gallery.DisplayPoolItems(r.Context(), filter)
 	if err != nil {
-		er := &Error{
-			Error: "Could not retrieve pool items",
-			Code:  "INTERNAL_SERVER_ERROR",
-		}
-		throwError(er, w, http.StatusInternalServerError)
+		throwStoreError(err, "Could not retrieve pool items", nil, w, r)
 		return
 	}
 
@@ -214,13 +206,14 @@ func (h *CharacterHandler) ShowItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	item, err := h.Gallery.DisplayItem(inventory.ItemID(itemID) - 1)
+	item, err := h.Gallery.DisplayItem(r.Context(), inventory.ItemID(itemID)-1)
 	if err != nil {
-		er := &Error{
-			Error: "Could not retrieve item from item pool",
-			Code:  "INTERNAL_SERVER_ERROR",
+		details := struct {
+			ItemID string `json:"item_id"`
+		}{
+			ItemID: itemIDStr,
 		}
-		throwError(er, w, http.StatusInternalServerError)
+		throwStoreError(err, "Could not retrieve item from item pool", details, w, r)
 		return
 	}
 
@@ -238,24 +231,15 @@ func (h *CharacterHandler) CreateItem(w http.ResponseWriter, r *http.Request) {
 			Error: "Invalid request body",
 			Code:  "BAD_REQUEST",
 		}
-		throwError(er, w, http.StatusBadRequest)
+		ThrowError(er, w, r, http.StatusBadRequest)
 		return
 	}
-	if !validateItem(newItem, w) {
-		er := &Error{
-			Error: "Invalid item",
-			Code:  "BAD_REQUEST",
-		}
-		throwError(er, w, http.StatusBadRequest)
+	if !validate(newItem, w, r) {
 		return
 	}
-	err = h.Gallery.CreateItem(newItem)
+	err = h.Gallery.CreateItem(r.Context(), newItem)
 	if err != nil {
-		er := &Error{
-			Error: "Could not create item",
-			Code:  "INTERNAL_SERVER_ERROR",
-		}
-		throwError(er, w, http.StatusInternalServerError)
+		throwStoreError(err, "Could not create item", nil, w, r)
 		return
 	}
 
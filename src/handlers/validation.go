@@ -1,35 +1,37 @@
 package handlers
 
 import (
-	"dZev1/character-gallery/models/characters"
-	"dZev1/character-gallery/models/inventory"
+	"errors"
 	"net/http"
+
+	"dZev1/character-gallery/models/validation"
 )
 
-func validateCharacter(character *characters.Character, w http.ResponseWriter) bool {
-	if len(character.Name) < 2 {
-		http.Error(w, "Character's name is too short", http.StatusBadRequest)
-		return false
-	}
-	if !character.BodyType.Validate() {
-		http.Error(w, "Character's body type not valid", http.StatusBadRequest)
-		return false
-	}
-	if !character.Class.Validate() {
-		http.Error(w, "Character's class not valid", http.StatusBadRequest)
-		return false
+type validator interface {
+	Validate() error
+}
+
+// validate writes a single 422 listing every invalid field of v and reports whether v is valid.
+func validate(v validator, w http.ResponseWriter, r *http.Request) bool {
+	err := v.Validate()
+	if err == nil {
+		return true
 	}
-	if !character.Species.Validate() {
-		http.Error(w, "Character's species not valid", http.StatusBadRequest)
-		return false
+
+	var fieldErrs validation.Errors
+	if !errors.As(err, &fieldErrs) {
+		fieldErrs.Merge("", err)
 	}
-	return true
+
+	throwValidationError(fieldErrs, w, r)
+	return false
 }
 
-func validateItem(item *inventory.Item, w http.ResponseWriter) bool {
-	if !item.Validate() || !item.Type.Validate() {
-		http.Error(w, "Item not valid", http.StatusBadRequest)
-		return false
+func throwValidationError(fieldErrs validation.Errors, w http.ResponseWriter, r *http.Request) {
+	er := &Error{
+		Error:   "Request validation failed",
+		Code:    "VALIDATION_FAILED",
+		Details: fieldErrs,
 	}
-	return true
-}
\ No newline at end of file
+	ThrowError(er, w, r, http.StatusUnprocessableEntity)
+}
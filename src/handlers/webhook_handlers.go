@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"dZev1/character-gallery/models/events"
+	"dZev1/character-gallery/models/validation"
+	"dZev1/character-gallery/models/webhooks"
+)
+
+type CreateWebhookRequest struct {
+	URL         string            `json:"url"`
+	Events      events.EventTypes `json:"events"`
+	Description string            `json:"description"`
+}
+
+func (req *CreateWebhookRequest) Validate() error {
+	var errs validation.Errors
+
+	if req.URL == "" {
+		errs.Add("url", validation.CodeRequired, "is required")
+	} else if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs.Add("url", validation.CodeInvalidValue, "must be an absolute http or https URL")
+	}
+
+	if len(req.Events) == 0 {
+		errs.Add("events", validation.CodeRequired, "must list at least one event type")
+	}
+	for i, eventType := range req.Events {
+		if !eventType.Validate() {
+			errs.Merge(fmt.Sprintf("events[%d]", i), validation.OneOf(eventType, events.AllEventTypes))
+		}
+	}
+
+	return errs.Err()
+}
+
+// CreatedWebhook is the only response that ever carries the signing secret.
+type CreatedWebhook struct {
+	*webhooks.Subscription
+	Secret string `json:"secret"`
+}
+
+func (h *AdminHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := h.Webhooks.ListSubscriptions(r.Context())
+	if err != nil {
+		throwStoreError(err, "Could not list webhooks", nil, w, r)
+		return
+	}
+
+	if subscriptions == nil {
+		subscriptions = []webhooks.Subscription{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(subscriptions)
+}
+
+func (h *AdminHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	request := &CreateWebhookRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil {
+		er := &Error{
+			Error: "Invalid request body",
+			Code:  "BAD_REQUEST",
+		}
+		ThrowError(er, w, r, http.StatusBadRequest)
+		return
+	}
+	if !validate(request, w, r) {
+		return
+	}
+
+	// Duplicates would only queue the same event twice.
+	slices.Sort(request.Events)
+	request.Events = slices.Compact(request.Events)
+
+	subscription, err := h.Webhooks.CreateSubscription(r.Context(), request.URL, request.Events, request.Description)
+	if err != nil {
+		throwStoreError(err, "Could not create webhook", nil, w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreatedWebhook{Subscription: subscription, Secret: subscription.Secret})
+}
+
+func (h *AdminHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := parsePathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	err := h.Webhooks.DeleteSubscription(r.Context(), webhooks.SubscriptionID(id))
+	if err != nil {
+		throwStoreError(err, "Could not delete webhook", idDetails(r.PathValue("id")), w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := parsePathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	status := webhooks.DeliveryStatus(r.URL.Query().Get("status"))
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if r.URL.Query().Get("limit") == "" {
+		limit, err = 50, nil
+	}
+	if err != nil || limit < 1 || limit > 500 || (status != "" && !slices.Contains(webhooks.AllDeliveryStatuses, status)) {
+		er := &Error{
+			Error: "Invalid query, status must be pending, delivered or dead and limit between 1 and 500",
+			Code:  "BAD_REQUEST",
+			Details: struct {
+				Status string `json:"status"`
+				Limit  string `json:"limit"`
+			}{
+				Status: r.URL.Query().Get("status"),
+				Limit:  r.URL.Query().Get("limit"),
+			},
+		}
+		ThrowError(er, w, r, http.StatusBadRequest)
+		return
+	}
+
+	subscription, err := h.Webhooks.GetSubscription(r.Context(), webhooks.SubscriptionID(id))
+	if err != nil {
+		throwStoreError(err, "Could not retrieve webhook", idDetails(r.PathValue("id")), w, r)
+		return
+	}
+
+	deliveries, err := h.Webhooks.ListDeliveries(r.Context(), subscription.ID, status, limit)
+	if err != nil {
+		throwStoreError(err, "Could not list webhook deliveries", nil, w, r)
+		return
+	}
+
+	if deliveries == nil {
+		deliveries = []webhooks.Delivery{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+func (h *AdminHandler) GetWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	id, ok := parsePathID(w, r, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := parsePathID(w, r, "delivery")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.Webhooks.GetDelivery(r.Context(), webhooks.SubscriptionID(id), webhooks.DeliveryID(deliveryID))
+	if err != nil {
+		throwStoreError(err, "Could not retrieve webhook delivery", idDetails(r.PathValue("delivery")), w, r)
+		return
+	}
+
+	attempts, err := h.Webhooks.ListAttempts(r.Context(), delivery.ID)
+	if err != nil {
+		throwStoreError(err, "Could not list webhook delivery attempts", nil, w, r)
+		return
+	}
+
+	if attempts == nil {
+		attempts = []webhooks.Attempt{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		Delivery *webhooks.Delivery `json:"delivery"`
+		Attempts []webhooks.Attempt `json:"attempts"`
+	}{
+		Delivery: delivery,
+		Attempts: attempts,
+	})
+}
+
+func (h *AdminHandler) RetryWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	id, ok := parsePathID(w, r, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := parsePathID(w, r, "delivery")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.Webhooks.RetryDelivery(r.Context(), webhooks.SubscriptionID(id), webhooks.DeliveryID(deliveryID))
+	if err != nil {
+		throwStoreError(err, "Could not retry webhook delivery", idDetails(r.PathValue("delivery")), w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(delivery)
+}
+
+// parsePathID reads the numeric path value name, answering 400 when it isn't one.
+func parsePathID(w http.ResponseWriter, r *http.Request, name string) (uint64, bool) {
+	idStr := r.PathValue(name)
+
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		er := &Error{
+			Error:   "Invalid ID",
+			Code:    "BAD_REQUEST",
+			Details: idDetails(idStr),
+		}
+		ThrowError(er, w, r, http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,215 @@
+package authcache
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"dZev1/character-gallery/models/auth"
+	"dZev1/character-gallery/models/events"
+)
+
+type entry struct {
+	key       *auth.APIKey
+	expiresAt time.Time
+}
+
+// Store wraps an auth.AuthStore so the request path stays off the database: validated keys are
+// cached for a short TTL, and last-used updates are coalesced and written in one batch per
+// flush interval. Every other method goes straight to the wrapped store.
+//
+// Changes made through this Store (revoke, rotate, quota, prune) evict the affected keys at
+// once. Changes made elsewhere, e.g. by apikey_gen or another replica, are evicted when they are
+// passed to Changed, or else picked up when the cached entry expires. Entries never outlive the
+// expiry of their key.
+type Store struct {
+	auth.AuthStore
+
+	ttl time.Duration
+	now func() time.Time
+
+	mu       sync.Mutex
+	entries  map[string]entry
+	lastUsed map[string]struct{}
+	// epoch counts evictions. A key read from the wrapped store is only cached if none happened
+	// meanwhile, since the read may predate the change that was evicted.
+	epoch uint64
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// New wraps store and starts flushing last-used updates every flushInterval until Close is called.
+func New(store auth.AuthStore, ttl time.Duration, flushInterval time.Duration) *Store {
+	s := &Store{
+		AuthStore: store,
+		ttl:       ttl,
+		now:       time.Now,
+		entries:   make(map[string]entry),
+		lastUsed:  make(map[string]struct{}),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go s.run(flushInterval)
+	return s
+}
+
+func (s *Store) run(flushInterval time.Duration) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.Flush()
+			s.dropExpired()
+		case <-s.stop:
+			s.Flush()
+			return
+		}
+	}
+}
+
+// Close stops the background flusher after writing the pending last-used updates. It does not
+// close the wrapped store.
+func (s *Store) Close() {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+	})
+}
+
+func (s *Store) ValidateAPIKey(keyHash string) (*auth.APIKey, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	cached, ok := s.entries[keyHash]
+	epoch := s.epoch
+	s.mu.Unlock()
+
+	// A key that expired while cached is refused at once, not once its entry runs out.
+	if ok && cached.key.ExpiresAt != nil && !now.Before(*cached.key.ExpiresAt) {
+		s.evict(func(hash string, _ *auth.APIKey) bool { return hash == keyHash })
+		return nil, auth.ErrAPIKeyExpired
+	}
+	if ok && now.Before(cached.expiresAt) {
+		return cached.key, nil
+	}
+
+	key, err := s.AuthStore.ValidateAPIKey(keyHash)
+	if err != nil {
+		s.evict(func(hash string, _ *auth.APIKey) bool { return hash == keyHash })
+		return nil, err
+	}
+
+	expiresAt := now.Add(s.ttl)
+	if key.ExpiresAt != nil && key.ExpiresAt.Before(expiresAt) {
+		expiresAt = *key.ExpiresAt
+	}
+
+	s.mu.Lock()
+	if s.epoch == epoch {
+		s.entries[keyHash] = entry{key: key, expiresAt: expiresAt}
+	}
+	s.mu.Unlock()
+
+	return key, nil
+}
+
+// UpdateLastUsed only marks the keys; they are written on the next flush.
+func (s *Store) UpdateLastUsed(keyHashes ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, keyHash := range keyHashes {
+		s.lastUsed[keyHash] = struct{}{}
+	}
+	return nil
+}
+
+// Flush writes the pending last-used updates. On failure they are kept for the next flush.
+func (s *Store) Flush() {
+	s.mu.Lock()
+	if len(s.lastUsed) == 0 {
+		s.mu.Unlock()
+		return
+	}
+	pending := s.lastUsed
+	s.lastUsed = make(map[string]struct{})
+	s.mu.Unlock()
+
+	keyHashes := make([]string, 0, len(pending))
+	for keyHash := range pending {
+		keyHashes = append(keyHashes, keyHash)
+	}
+
+	if err := s.AuthStore.UpdateLastUsed(keyHashes...); err != nil {
+		slog.Error("could not update last used time of API keys, retrying later", "keys", len(keyHashes), "error", err)
+
+		s.mu.Lock()
+		for keyHash := range pending {
+			s.lastUsed[keyHash] = struct{}{}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *Store) RevokeAPIKey(actor auth.Actor, id auth.APIKeyID) error {
+	err := s.AuthStore.RevokeAPIKey(actor, id)
+	s.evictID(id)
+	return err
+}
+
+func (s *Store) RotateAPIKey(actor auth.Actor, id auth.APIKeyID, gracePeriod time.Duration) (*auth.APIKey, string, error) {
+	key, rawKey, err := s.AuthStore.RotateAPIKey(actor, id, gracePeriod)
+	s.evictID(id)
+	return key, rawKey, err
+}
+
+func (s *Store) SetMonthlyQuota(actor auth.Actor, id auth.APIKeyID, quota uint64) error {
+	err := s.AuthStore.SetMonthlyQuota(actor, id, quota)
+	s.evictID(id)
+	return err
+}
+
+func (s *Store) PruneAPIKeys(actor auth.Actor, unusedFor time.Duration) (int64, error) {
+	pruned, err := s.AuthStore.PruneAPIKeys(actor, unusedFor)
+	s.evict(func(string, *auth.APIKey) bool { return true })
+	return pruned, err
+}
+
+// Changed evicts the keys another instance changed. It is handed to the gallery's listener.
+func (s *Store) Changed(change events.Change) {
+	switch {
+	case change.Action == events.ActionMissed, change.Entity == events.EntityAPIKey && change.ID == 0:
+		s.evict(func(string, *auth.APIKey) bool { return true })
+	case change.Entity == events.EntityAPIKey:
+		s.evictID(auth.APIKeyID(change.ID))
+	}
+}
+
+func (s *Store) dropExpired() {
+	now := s.now()
+	s.evict(func(keyHash string, _ *auth.APIKey) bool {
+		return !now.Before(s.entries[keyHash].expiresAt)
+	})
+}
+
+func (s *Store) evictID(id auth.APIKeyID) {
+	s.evict(func(_ string, key *auth.APIKey) bool { return key.ID == id })
+}
+
+func (s *Store) evict(match func(keyHash string, key *auth.APIKey) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.epoch++
+	for keyHash, cached := range s.entries {
+		if match(keyHash, cached.key) {
+			delete(s.entries, keyHash)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package authcache
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"dZev1/character-gallery/models/auth"
+	"dZev1/character-gallery/models/events"
+)
+
+// fakeAuthStore implements the methods Store intercepts; anything else panics through the nil
+// embedded interface.
+type fakeAuthStore struct {
+	auth.AuthStore
+
+	mu              sync.Mutex
+	validateCalls   int
+	validateErr     error
+	expiresAt       *time.Time
+	onValidate      func()
+	lastUsedBatches [][]string
+	lastUsedErr     error
+}
+
+func (f *fakeAuthStore) ValidateAPIKey(keyHash string) (*auth.APIKey, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.validateCalls++
+	if f.onValidate != nil {
+		f.onValidate()
+	}
+	if f.validateErr != nil {
+		return nil, f.validateErr
+	}
+	return &auth.APIKey{ID: 1, KeyHash: keyHash, ExpiresAt: f.expiresAt}, nil
+}
+
+func (f *fakeAuthStore) UpdateLastUsed(keyHashes ...string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.lastUsedErr != nil {
+		return f.lastUsedErr
+	}
+	f.lastUsedBatches = append(f.lastUsedBatches, keyHashes)
+	return nil
+}
+
+func (f *fakeAuthStore) RevokeAPIKey(actor auth.Actor, id auth.APIKeyID) error {
+	return nil
+}
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func newTestStore(inner auth.AuthStore) (*Store, *testClock) {
+	clock := &testClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := New(inner, 30*time.Second, time.Hour)
+	store.now = clock.Now
+	return store, clock
+}
+
+func TestValidateAPIKey_CachesValidKeys(t *testing.T) {
+	inner := &fakeAuthStore{}
+	store, clock := newTestStore(inner)
+	defer store.Close()
+
+	for range 5 {
+		if _, err := store.ValidateAPIKey("hash1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if inner.validateCalls != 1 {
+		t.Fatalf("expected 1 call to the wrapped store, got %d", inner.validateCalls)
+	}
+
+	clock.now = clock.now.Add(31 * time.Second)
+	store.ValidateAPIKey("hash1")
+
+	if inner.validateCalls != 2 {
+		t.Fatalf("expected the entry to expire after the TTL, got %d calls", inner.validateCalls)
+	}
+}
+
+func TestValidateAPIKey_RefusesKeysExpiredWhileCached(t *testing.T) {
+	inner := &fakeAuthStore{}
+	store, clock := newTestStore(inner)
+	defer store.Close()
+
+	expiresAt := clock.now.Add(10 * time.Second)
+	inner.expiresAt = &expiresAt
+
+	if _, err := store.ValidateAPIKey("hash1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	clock.now = expiresAt
+	if _, err := store.ValidateAPIKey("hash1"); !errors.Is(err, auth.ErrAPIKeyExpired) {
+		t.Fatalf("expected ErrAPIKeyExpired, got: %v", err)
+	}
+	if inner.validateCalls != 1 {
+		t.Fatalf("expected the cached key to be refused without the wrapped store, got %d calls", inner.validateCalls)
+	}
+	if _, ok := store.entries["hash1"]; ok {
+		t.Fatal("expected the expired key to be evicted")
+	}
+}
+
+func TestValidateAPIKey_EntriesEndWithTheirKey(t *testing.T) {
+	inner := &fakeAuthStore{}
+	store, clock := newTestStore(inner)
+	defer store.Close()
+
+	expiresAt := clock.now.Add(10 * time.Second)
+	inner.expiresAt = &expiresAt
+
+	store.ValidateAPIKey("hash1")
+
+	if entry := store.entries["hash1"]; !entry.expiresAt.Equal(expiresAt) {
+		t.Fatalf("expected the entry to end when the key expires at %v, got %v", expiresAt, entry.expiresAt)
+	}
+}
+
+func TestValidateAPIKey_DoesNotCacheFailures(t *testing.T) {
+	inner := &fakeAuthStore{validateErr: auth.ErrAPIKeyRevoked}
+	store, _ := newTestStore(inner)
+	defer store.Close()
+
+	for range 2 {
+		if _, err := store.ValidateAPIKey("hash1"); !errors.Is(err, auth.ErrAPIKeyRevoked) {
+			t.Fatalf("expected ErrAPIKeyRevoked, got: %v", err)
+		}
+	}
+
+	if inner.validateCalls != 2 {
+		t.Fatalf("expected failures to reach the wrapped store, got %d calls", inner.validateCalls)
+	}
+}
+
+func TestRevokeAPIKey_EvictsCachedKey(t *testing.T) {
+	inner := &fakeAuthStore{}
+	store, _ := newTestStore(inner)
+	defer store.Close()
+
+	store.ValidateAPIKey("hash1")
+
+	if err := store.RevokeAPIKey(auth.Actor{Source: auth.AuditSourceAPI}, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	inner.validateErr = auth.ErrAPIKeyRevoked
+	if _, err := store.ValidateAPIKey("hash1"); !errors.Is(err, auth.ErrAPIKeyRevoked) {
+		t.Fatalf("expected revoked key to be rejected right away, got: %v", err)
+	}
+}
+
+func TestRevokeAPIKey_DuringValidationIsNotUndone(t *testing.T) {
+	inner := &fakeAuthStore{}
+	store, _ := newTestStore(inner)
+	defer store.Close()
+
+	// The key is read as valid, then revoked before it is cached.
+	inner.onValidate = func() {
+		store.RevokeAPIKey(auth.Actor{Source: auth.AuditSourceAPI}, 1)
+	}
+	if _, err := store.ValidateAPIKey("hash1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	inner.onValidate = nil
+	inner.validateErr = auth.ErrAPIKeyRevoked
+	if _, err := store.ValidateAPIKey("hash1"); !errors.Is(err, auth.ErrAPIKeyRevoked) {
+		t.Fatalf("expected the key read before the revoke not to be cached, got: %v", err)
+	}
+}
+
+func TestChanged_EvictsKeysChangedElsewhere(t *testing.T) {
+	inner := &fakeAuthStore{}
+	store, _ := newTestStore(inner)
+	defer store.Close()
+
+	store.ValidateAPIKey("hash1")
+
+	store.Changed(events.Change{Entity: events.EntityCharacter, Action: events.ActionUpdated, ID: 1})
+	store.ValidateAPIKey("hash1")
+	if inner.validateCalls != 1 {
+		t.Fatalf("expected other changes to keep the key cached, got %d calls", inner.validateCalls)
+	}
+
+	for _, change := range []events.Change{
+		{Entity: events.EntityAPIKey, Action: events.ActionUpdated, ID: 1},
+		{Entity: events.EntityAPIKey, Action: events.ActionDeleted},
+		{Action: events.ActionMissed},
+	} {
+		calls := inner.validateCalls
+		store.Changed(change)
+		store.ValidateAPIKey("hash1")
+		if inner.validateCalls != calls+1 {
+			t.Errorf("%+v: expected the key to be evicted", change)
+		}
+	}
+}
+
+func TestUpdateLastUsed_CoalescesIntoOneBatch(t *testing.T) {
+	inner := &fakeAuthStore{}
+	store, _ := newTestStore(inner)
+
+	for range 10 {
+		store.UpdateLastUsed("hash1")
+		store.UpdateLastUsed("hash2")
+	}
+
+	if len(inner.lastUsedBatches) != 0 {
+		t.Fatal("expected no write before the flush")
+	}
+
+	store.Close()
+
+	if len(inner.lastUsedBatches) != 1 {
+		t.Fatalf("expected one batch on close, got %d", len(inner.lastUsedBatches))
+	}
+
+	batch := slices.Sorted(slices.Values(inner.lastUsedBatches[0]))
+	if !slices.Equal(batch, []string{"hash1", "hash2"}) {
+		t.Fatalf("unexpected batch: %v", batch)
+	}
+}
+
+func TestFlush_KeepsPendingOnError(t *testing.T) {
+	inner := &fakeAuthStore{lastUsedErr: errors.New("database down")}
+	store, _ := newTestStore(inner)
+
+	store.UpdateLastUsed("hash1")
+	store.Flush()
+
+	inner.mu.Lock()
+	inner.lastUsedErr = nil
+	inner.mu.Unlock()
+
+	store.Close()
+
+	if len(inner.lastUsedBatches) != 1 || inner.lastUsedBatches[0][0] != "hash1" {
+		t.Fatalf("expected the failed update to be retried, got %v", inner.lastUsedBatches)
+	}
+}
@@ -0,0 +1,183 @@
+package changes
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync"
+
+	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/events"
+)
+
+// subscriberBuffer is how many messages a stream may fall behind before it is dropped.
+const subscriberBuffer = 64
+
+// Message is an event as numbered by a Broker. IDs are "<epoch>-<sequence>", where the epoch
+// changes every time the process starts, or events may have been missed, so that an ID from
+// before is never mistaken for a current one. A Reset message has no event: the stream missed
+// some, and the client should refetch what it shows.
+type Message struct {
+	ID    string
+	Event *events.Event
+	Reset bool
+	seq   uint64
+}
+
+// Filter selects the events a stream receives. Empty fields match everything.
+type Filter struct {
+	Types       events.EventTypes
+	CharacterID characters.CharacterID
+}
+
+func (f Filter) Match(event *events.Event) bool {
+	if len(f.Types) > 0 && !f.Types.Has(event.Type) {
+		return false
+	}
+	return f.CharacterID == 0 || event.CharacterID == f.CharacterID
+}
+
+// Broker fans published events out to the streams subscribed to them. The last events are kept
+// in a ring buffer, so a stream that reconnects resumes where it left off.
+type Broker struct {
+	mu    sync.Mutex
+	epoch string
+	seq   uint64
+	// ring holds the last len(ring) messages; message n is at ring[n%len(ring)].
+	ring        []Message
+	subscribers map[*Subscription]struct{}
+}
+
+// NewBroker keeps the last size events for resuming streams.
+func NewBroker(size int) *Broker {
+	return &Broker{
+		epoch:       newEpoch(),
+		ring:        make([]Message, size),
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+func newEpoch() string {
+	epoch := make([]byte, 4)
+	rand.Read(epoch)
+	return hex.EncodeToString(epoch)
+}
+
+// Publish numbers the event, keeps it for resuming and sends it to every matching stream. A
+// stream whose buffer is full is dropped rather than holding up the write that published it.
+func (b *Broker) Publish(ctx context.Context, event *events.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	message := Message{ID: b.epoch + "-" + strconv.FormatUint(b.seq, 10), Event: event, seq: b.seq}
+	if len(b.ring) > 0 {
+		b.ring[b.seq%uint64(len(b.ring))] = message
+	}
+
+	for sub := range b.subscribers {
+		if !sub.filter.Match(event) {
+			continue
+		}
+		select {
+		case sub.messages <- message:
+		default:
+			b.drop(sub)
+		}
+	}
+}
+
+// Changed resets the streams when the changes of other instances were missed, since so were
+// their events. It is handed to the gallery's listener.
+func (b *Broker) Changed(change events.Change) {
+	if change.Action == events.ActionMissed {
+		b.Reset()
+	}
+}
+
+// Reset tells every stream that it missed events, and starts a new epoch so that a stream
+// resuming from an earlier event is told so too. A stream whose buffer is full is dropped, and
+// told when it resumes.
+func (b *Broker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.epoch = newEpoch()
+	for sub := range b.subscribers {
+		select {
+		case sub.messages <- Message{Reset: true}:
+		default:
+			b.drop(sub)
+		}
+	}
+}
+
+// Subscription is a stream registered with a Broker. Messages is closed when the stream falls
+// too far behind or is closed.
+type Subscription struct {
+	broker   *Broker
+	filter   Filter
+	messages chan Message
+}
+
+func (s *Subscription) Messages() <-chan Message {
+	return s.messages
+}
+
+// Close unregisters the stream.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.drop(s)
+}
+
+// drop must be called with the lock held.
+func (b *Broker) drop(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.messages)
+	}
+}
+
+// Subscribe registers a stream. When lastEventID is set, the buffered events after it are
+// returned to be sent first; reset is true when they can't be, because the ID is from another
+// run or older than the buffer, and the stream has missed events.
+func (b *Broker) Subscribe(filter Filter, lastEventID string) (sub *Subscription, replay []Message, reset bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub = &Subscription{broker: b, filter: filter, messages: make(chan Message, subscriberBuffer)}
+	b.subscribers[sub] = struct{}{}
+
+	if lastEventID == "" {
+		return sub, nil, false
+	}
+
+	last, ok := b.parseID(lastEventID)
+	if !ok || last > b.seq {
+		return sub, nil, true
+	}
+	// The event right after last must still be buffered, or some were missed.
+	if b.seq-last > uint64(len(b.ring)) {
+		return sub, nil, true
+	}
+
+	for seq := last + 1; seq <= b.seq; seq++ {
+		if message := b.ring[seq%uint64(len(b.ring))]; filter.Match(message.Event) {
+			replay = append(replay, message)
+		}
+	}
+	return sub, replay, false
+}
+
+// parseID returns the sequence of an ID issued by this run.
+func (b *Broker) parseID(id string) (uint64, bool) {
+	epoch, seq, ok := strings.Cut(id, "-")
+	if !ok || epoch != b.epoch {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	return n, err == nil
+}
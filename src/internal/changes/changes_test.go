@@ -0,0 +1,109 @@
+package changes
+
+import (
+	"context"
+	"testing"
+
+	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/events"
+)
+
+func publishEvent(t *testing.T, broker *Broker, eventType events.EventType, characterID characters.CharacterID) {
+	t.Helper()
+	event, err := events.NewEvent(eventType, struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event.CharacterID = characterID
+	broker.Publish(context.Background(), event)
+}
+
+func TestBroker_FiltersLiveEvents(t *testing.T) {
+	broker := NewBroker(10)
+	sub, _, _ := broker.Subscribe(Filter{Types: events.EventTypes{events.InventoryItemAdded}, CharacterID: 3}, "")
+	defer sub.Close()
+
+	publishEvent(t, broker, events.InventoryItemAdded, 4)
+	publishEvent(t, broker, events.CharacterUpdated, 3)
+	publishEvent(t, broker, events.InventoryItemAdded, 3)
+
+	message := <-sub.Messages()
+	if message.Event.Type != events.InventoryItemAdded || message.Event.CharacterID != 3 || message.seq != 3 {
+		t.Errorf("expected only the third event, got %+v", message)
+	}
+	if len(sub.Messages()) != 0 {
+		t.Errorf("expected no other event, got %d", len(sub.Messages()))
+	}
+}
+
+func TestBroker_ResumesFromTheBuffer(t *testing.T) {
+	broker := NewBroker(3)
+	seen, _, _ := broker.Subscribe(Filter{}, "")
+	for range 5 {
+		publishEvent(t, broker, events.CharacterCreated, 1)
+	}
+	seen.Close()
+
+	var ids []string
+	for message := range seen.Messages() {
+		ids = append(ids, message.ID)
+	}
+
+	_, replay, reset := broker.Subscribe(Filter{}, ids[2])
+	if reset || len(replay) != 2 || replay[0].ID != ids[3] || replay[1].ID != ids[4] {
+		t.Errorf("expected events 4 and 5 to be replayed, got %+v (reset %v)", replay, reset)
+	}
+
+	_, replay, reset = broker.Subscribe(Filter{}, ids[4])
+	if reset || len(replay) != 0 {
+		t.Errorf("expected nothing to replay after the last event, got %+v (reset %v)", replay, reset)
+	}
+
+	// Event 2 was pushed out of the buffer, so resuming from event 1 would skip it. IDs of
+	// another run, malformed ones and ones not issued yet can't be resumed from either.
+	for _, id := range []string{ids[0], "0badc0de-2", "garbage", broker.epoch + "-99"} {
+		if _, replay, reset := broker.Subscribe(Filter{}, id); !reset || replay != nil {
+			t.Errorf("%s: expected a reset, got %+v (reset %v)", id, replay, reset)
+		}
+	}
+}
+
+func TestBroker_DropsSlowSubscribers(t *testing.T) {
+	broker := NewBroker(0)
+	slow, _, _ := broker.Subscribe(Filter{}, "")
+
+	for range subscriberBuffer + 1 {
+		publishEvent(t, broker, events.ItemCreated, 0)
+	}
+
+	received := 0
+	for range slow.Messages() {
+		received++
+	}
+	if received != subscriberBuffer {
+		t.Errorf("expected the buffered events then a closed stream, got %d events", received)
+	}
+	slow.Close()
+}
+
+func TestBroker_ResetsStreamsOnMissedChanges(t *testing.T) {
+	broker := NewBroker(10)
+	sub, _, _ := broker.Subscribe(Filter{}, "")
+	defer sub.Close()
+
+	publishEvent(t, broker, events.CharacterCreated, 1)
+	last := (<-sub.Messages()).ID
+
+	broker.Changed(events.Change{Entity: events.EntityCharacter, Action: events.ActionUpdated, ID: 1})
+	if len(sub.Messages()) != 0 {
+		t.Fatalf("expected other changes to leave the stream alone, got %d messages", len(sub.Messages()))
+	}
+
+	broker.Changed(events.Change{Action: events.ActionMissed})
+	if message := <-sub.Messages(); !message.Reset || message.Event != nil {
+		t.Errorf("expected a reset, got %+v", message)
+	}
+	if _, replay, reset := broker.Subscribe(Filter{}, last); !reset || replay != nil {
+		t.Errorf("expected resuming from before the reset to reset, got %+v (reset %v)", replay, reset)
+	}
+}
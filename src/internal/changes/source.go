@@ -0,0 +1,11 @@
+// Package changes streams the events of the writes made to a gallery to the clients listening.
+package changes
+
+import "dZev1/character-gallery/models/events"
+
+// Source is implemented by the backends that report the writes committed through any instance.
+// changed is handed the notices of writes made through other instances, and delivered the events
+// the outbox delivered, whichever instance wrote them. They stop listening when they are closed.
+type Source interface {
+	Listen(changed func(events.Change), delivered func(*events.Event))
+}
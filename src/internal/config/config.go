@@ -0,0 +1,414 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Config holds every setting of the server. Each field can be set, from lowest to highest
+// precedence, by its default, the YAML file, the environment variable named by its env tag and
+// the command-line flag named by its flag tag. Fields tagged secret are redacted when printed
+// and can also be read from the file named by <env>_FILE.
+type Config struct {
+	Server   Server   `yaml:"server"`
+	Database Database `yaml:"database"`
+	API      API      `yaml:"api"`
+	Log      Log      `yaml:"log"`
+	Trace    Trace    `yaml:"trace"`
+	Metrics  Metrics  `yaml:"metrics"`
+	Files    Files    `yaml:"files"`
+	Seed     Seed     `yaml:"seed"`
+	Webhooks Webhooks `yaml:"webhooks"`
+	Events   Events   `yaml:"events"`
+	Outbox   Outbox   `yaml:"outbox"`
+
+	// PrintConfig asks to print the configuration and exit instead of serving.
+	PrintConfig bool `yaml:"-"`
+}
+
+type Server struct {
+	Addr            string        `yaml:"addr" env:"SERVER_ADDR" flag:"addr" usage:"address to listen on"`
+	ReadTimeout     time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT" flag:"read-timeout" usage:"maximum duration for reading a request"`
+	WriteTimeout    time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT" flag:"write-timeout" usage:"maximum duration for writing a response"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT" flag:"idle-timeout" usage:"how long keep-alive connections stay open"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"how long to wait for requests in flight on shutdown"`
+}
+
+type Database struct {
+	Type string `yaml:"type" env:"DATABASE_TYPE" flag:"database-type" usage:"storage backend"`
+	URL  string `yaml:"url" env:"DATABASE_URL" flag:"database-url" usage:"database connection string" secret:"true"`
+}
+
+type API struct {
+	Version     string `yaml:"version" env:"API_VERSION" flag:"api-version" usage:"version in the /api/<version> route prefix"`
+	PageSize    int    `yaml:"page_size" env:"PAGE_SIZE" flag:"page-size" usage:"characters returned per page"`
+	ErrorFormat string `yaml:"error_format" env:"ERROR_FORMAT" flag:"error-format" usage:"default error format, json or problem"`
+}
+
+type Log struct {
+	Format string `yaml:"format" env:"LOG_FORMAT" flag:"log-format" usage:"log format, json or text"`
+	Level  string `yaml:"level" env:"LOG_LEVEL" flag:"log-level" usage:"minimum log level"`
+}
+
+type Trace struct {
+	Exporter string `yaml:"exporter" env:"TRACE_EXPORTER" flag:"trace-exporter" usage:"span exporter: none, stdout or file"`
+	File     string `yaml:"file" env:"TRACE_FILE" flag:"trace-file" usage:"file spans are written to by the file exporter"`
+}
+
+type Metrics struct {
+	Token string `yaml:"token" env:"METRICS_TOKEN" flag:"metrics-token" usage:"bearer token required by /metrics" secret:"true"`
+}
+
+type Files struct {
+	ItemPool   string `yaml:"item_pool" env:"ITEM_POOL_FILE" flag:"item-pool" usage:"JSON file the item pool is seeded from"`
+	RateLimits string `yaml:"rate_limits" env:"RATE_LIMITS_FILE" flag:"rate-limits" usage:"JSON rate limit policy"`
+	CORS       string `yaml:"cors" env:"CORS_FILE" flag:"cors" usage:"JSON CORS policy"`
+	Packs      string `yaml:"packs" env:"CONTENT_PACKS_DIR" flag:"packs" usage:"directory of content packs merged into the item pool"`
+}
+
+type Seed struct {
+	Strict bool `yaml:"strict" env:"SEED_STRICT" flag:"seed-strict" usage:"abort startup when the item pool can't be seeded"`
+	// WatchInterval is how often the item pool files are polled for changes. 0 turns reloading off.
+	WatchInterval time.Duration `yaml:"watch_interval" env:"SEED_WATCH_INTERVAL" flag:"seed-watch-interval" usage:"how often to check the item pool files for changes, 0 to never reload"`
+}
+
+type Webhooks struct {
+	PollInterval time.Duration `yaml:"poll_interval" env:"WEBHOOK_POLL_INTERVAL" flag:"webhook-poll-interval" usage:"how often queued webhook deliveries are posted"`
+	Timeout      time.Duration `yaml:"timeout" env:"WEBHOOK_TIMEOUT" flag:"webhook-timeout" usage:"how long a subscriber has to answer a delivery"`
+	MaxAttempts  int           `yaml:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS" flag:"webhook-max-attempts" usage:"attempts before a delivery is dead-lettered"`
+}
+
+type Events struct {
+	// Buffer is how many recent events are kept for streams resuming with Last-Event-ID.
+	Buffer    int           `yaml:"buffer" env:"EVENTS_BUFFER" flag:"events-buffer" usage:"how many recent events a reconnecting stream can resume from"`
+	Heartbeat time.Duration `yaml:"heartbeat" env:"EVENTS_HEARTBEAT" flag:"events-heartbeat" usage:"how often an idle event stream is sent a heartbeat"`
+}
+
+type Outbox struct {
+	PollInterval time.Duration `yaml:"poll_interval" env:"OUTBOX_POLL_INTERVAL" flag:"outbox-poll-interval" usage:"how often new events are read from the outbox"`
+	// Retention is how long delivered and dead events are kept before they are pruned.
+	Retention   time.Duration `yaml:"retention" env:"OUTBOX_RETENTION" flag:"outbox-retention" usage:"how long delivered events are kept in the outbox"`
+	MaxAttempts int           `yaml:"max_attempts" env:"OUTBOX_MAX_ATTEMPTS" flag:"outbox-max-attempts" usage:"attempts before an event is dead-lettered"`
+}
+
+func Default() Config {
+	return Config{
+		Server: Server{
+			Addr:            ":8080",
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    15 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Database: Database{Type: "postgres"},
+		API:      API{Version: "v1", PageSize: 20, ErrorFormat: "json"},
+		Log:      Log{Format: "text", Level: "info"},
+		Trace:    Trace{Exporter: "none"},
+		Files: Files{
+			ItemPool:   "./item_pool.json",
+			RateLimits: "./rate_limits.json",
+			CORS:       "./cors.json",
+			Packs:      "./packs",
+		},
+		Webhooks: Webhooks{PollInterval: 5 * time.Second, Timeout: 10 * time.Second, MaxAttempts: 8},
+		Events:   Events{Buffer: 1000, Heartbeat: 15 * time.Second},
+		Outbox:   Outbox{PollInterval: time.Second, Retention: 7 * 24 * time.Hour, MaxAttempts: 10},
+	}
+}
+
+// DefaultFile is read when neither -config nor CONFIG_FILE name a file. It may be missing.
+const DefaultFile = "config.yaml"
+
+// Load builds the configuration from args, the command-line arguments without the program
+// name, and the environment seen through lookupEnv, then validates it.
+func Load(args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := Default()
+	fields := fieldsOf(&cfg)
+
+	fs := flag.NewFlagSet("character-gallery", flag.ContinueOnError)
+	configFile := fs.String("config", "", "YAML configuration file (default "+DefaultFile+" or $CONFIG_FILE)")
+	fs.BoolVar(&cfg.PrintConfig, "print-config", false, "print the configuration with secrets redacted and exit")
+	flagValues := map[string]*string{}
+	for _, f := range fields {
+		value, usage := new(string), f.usage+" ($"+f.env+")"
+		// Boolean settings are switched on by their bare flag, as -print-config is.
+		if f.value.Kind() == reflect.Bool {
+			fs.BoolFunc(f.flag, usage, func(s string) error {
+				*value = s
+				return nil
+			})
+		} else {
+			fs.StringVar(value, f.flag, "", usage)
+		}
+		flagValues[f.flag] = value
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	path, explicit := *configFile, *configFile != ""
+	if !explicit {
+		path, explicit = lookupEnv("CONFIG_FILE")
+	}
+	if !explicit {
+		path = DefaultFile
+	}
+	if err := loadFile(&cfg, path, explicit); err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, f := range fields {
+		if err := f.applyEnv(lookupEnv); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	fs.Visit(func(fl *flag.Flag) {
+		f, ok := fieldByFlag(fields, fl.Name)
+		if !ok {
+			return
+		}
+		if err := f.set(*flagValues[fl.Name]); err != nil {
+			errs = append(errs, fmt.Errorf("flag -%s: %w", fl.Name, err))
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func loadFile(cfg *Config, path string, required bool) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("could not decode config file %s: %w", path, err)
+	}
+	return nil
+}
+
+var versionPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Server.Addr == "" {
+		invalid("server.addr is required")
+	}
+	for _, timeout := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+	} {
+		if timeout.value <= 0 {
+			invalid("%s must be positive, got %s", timeout.name, timeout.value)
+		}
+	}
+
+	if c.Database.Type != "postgres" {
+		invalid("database.type %q is not supported, expected postgres", c.Database.Type)
+	}
+	if c.Database.URL == "" {
+		invalid("database.url is required, set DATABASE_URL or DATABASE_URL_FILE")
+	}
+
+	if !versionPattern.MatchString(c.API.Version) {
+		invalid("api.version %q must be letters, digits, '.', '_' or '-'", c.API.Version)
+	}
+	if c.API.PageSize < 1 || c.API.PageSize > 100 {
+		invalid("api.page_size must be between 1 and 100, got %d", c.API.PageSize)
+	}
+	if c.API.ErrorFormat != "json" && c.API.ErrorFormat != "problem" {
+		invalid("api.error_format %q must be json or problem", c.API.ErrorFormat)
+	}
+
+	if f := strings.ToLower(c.Log.Format); f != "json" && f != "text" {
+		invalid("log.format %q must be json or text", c.Log.Format)
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		invalid("log.level %q must be debug, info, warn or error", c.Log.Level)
+	}
+
+	switch c.Trace.Exporter {
+	case "", "none", "stdout":
+	case "file":
+		if c.Trace.File == "" {
+			invalid("trace.file is required by the file exporter")
+		}
+	default:
+		invalid("trace.exporter %q must be none, stdout or file", c.Trace.Exporter)
+	}
+
+	if c.Files.ItemPool == "" && c.Files.Packs == "" {
+		invalid("files.item_pool or files.packs is required")
+	}
+	if c.Seed.WatchInterval < 0 {
+		invalid("seed.watch_interval cannot be negative, got %s", c.Seed.WatchInterval)
+	}
+	if c.Webhooks.PollInterval <= 0 || c.Webhooks.Timeout <= 0 {
+		invalid("webhooks.poll_interval and webhooks.timeout must be positive")
+	}
+	if c.Webhooks.MaxAttempts < 1 {
+		invalid("webhooks.max_attempts must be at least 1, got %d", c.Webhooks.MaxAttempts)
+	}
+	if c.Events.Buffer < 0 {
+		invalid("events.buffer cannot be negative, got %d", c.Events.Buffer)
+	}
+	if c.Events.Heartbeat <= 0 {
+		invalid("events.heartbeat must be positive, got %s", c.Events.Heartbeat)
+	}
+	if c.Outbox.PollInterval <= 0 || c.Outbox.Retention <= 0 {
+		invalid("outbox.poll_interval and outbox.retention must be positive")
+	}
+	if c.Outbox.MaxAttempts < 1 {
+		invalid("outbox.max_attempts must be at least 1, got %d", c.Outbox.MaxAttempts)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Print writes the configuration as YAML with every secret that is set replaced.
+func (c *Config) Print(w io.Writer) error {
+	redacted := *c
+	for _, f := range fieldsOf(&redacted) {
+		if f.secret && f.value.String() != "" {
+			f.value.SetString("REDACTED")
+		}
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	defer encoder.Close()
+	return encoder.Encode(&redacted)
+}
+
+// field is a setting found by walking Config.
+type field struct {
+	value  reflect.Value
+	env    string
+	flag   string
+	usage  string
+	secret bool
+}
+
+func fieldsOf(cfg *Config) []field {
+	var fields []field
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		for sf, fv := range v.Fields() {
+			if sf.Type.Kind() == reflect.Struct && sf.Type != reflect.TypeFor[time.Duration]() {
+				walk(fv)
+				continue
+			}
+			if env := sf.Tag.Get("env"); env != "" {
+				fields = append(fields, field{
+					value:  fv,
+					env:    env,
+					flag:   sf.Tag.Get("flag"),
+					usage:  sf.Tag.Get("usage"),
+					secret: sf.Tag.Get("secret") == "true",
+				})
+			}
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem())
+	return fields
+}
+
+func fieldByFlag(fields []field, name string) (field, bool) {
+	for _, f := range fields {
+		if f.flag == name {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// applyEnv sets the field from its variable, or for secrets from the file named by <env>_FILE.
+func (f field) applyEnv(lookupEnv func(string) (string, bool)) error {
+	value, ok := lookupEnv(f.env)
+	if f.secret {
+		if path, fromFile := lookupEnv(f.env + "_FILE"); fromFile {
+			if ok {
+				return fmt.Errorf("%s and %s_FILE are both set, use only one", f.env, f.env)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("%s_FILE: %w", f.env, err)
+			}
+			value, ok = strings.TrimRight(string(data), "\r\n"), true
+		}
+	}
+	if !ok || value == "" {
+		return nil
+	}
+
+	if err := f.set(value); err != nil {
+		return fmt.Errorf("%s: %w", f.env, err)
+	}
+	return nil
+}
+
+func (f field) set(s string) error {
+	switch f.value.Interface().(type) {
+	case string:
+		f.value.SetString(s)
+	case int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", s)
+		}
+		f.value.SetInt(int64(n))
+	case bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", s)
+		}
+		f.value.SetBool(b)
+	case time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 15s", s)
+		}
+		f.value.SetInt(int64(d))
+	default:
+		return fmt.Errorf("unsupported setting type %s", f.value.Type())
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	file := writeFile(t, "config.yaml", `
+server:
+  addr: ":7000"
+  read_timeout: 5s
+api:
+  version: v2
+  page_size: 50
+database:
+  url: postgres://file
+`)
+
+	cfg, err := Load(
+		[]string{"-config", file, "-page-size", "10"},
+		env(map[string]string{"API_VERSION": "v3", "PAGE_SIZE": "30"}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Server.Addr != ":7000" || cfg.Server.ReadTimeout != 5*time.Second {
+		t.Errorf("expected file values, got %+v", cfg.Server)
+	}
+	if cfg.Server.WriteTimeout != 15*time.Second {
+		t.Errorf("expected the default write timeout, got %s", cfg.Server.WriteTimeout)
+	}
+	if cfg.API.Version != "v3" {
+		t.Errorf("expected the environment to override the file, got %s", cfg.API.Version)
+	}
+	if cfg.API.PageSize != 10 {
+		t.Errorf("expected the flag to override the environment, got %d", cfg.API.PageSize)
+	}
+	if cfg.Database.URL != "postgres://file" {
+		t.Errorf("unexpected database URL %s", cfg.Database.URL)
+	}
+}
+
+func TestLoad_BoolFlag(t *testing.T) {
+	vars := env(map[string]string{"DATABASE_URL": "postgres://env"})
+
+	cfg, err := Load([]string{"-seed-strict"}, vars)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Seed.Strict {
+		t.Error("expected the bare flag to switch strict seeding on")
+	}
+
+	cfg, err = Load([]string{"-seed-strict=false"}, env(map[string]string{"DATABASE_URL": "postgres://env", "SEED_STRICT": "true"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Seed.Strict {
+		t.Error("expected -seed-strict=false to override the environment")
+	}
+}
+
+func TestLoad_ConfigFileFromEnvironment(t *testing.T) {
+	file := writeFile(t, "gallery.yaml", "log:\n  format: json\n")
+
+	cfg, err := Load(nil, env(map[string]string{"CONFIG_FILE": file, "DATABASE_URL": "postgres://env"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Log.Format != "json" {
+		t.Errorf("expected the file named by CONFIG_FILE to be read, got %s", cfg.Log.Format)
+	}
+}
+
+func TestLoad_SecretFile(t *testing.T) {
+	secret := writeFile(t, "db_url", "postgres://secret\n")
+
+	cfg, err := Load(nil, env(map[string]string{"DATABASE_URL_FILE": secret}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Database.URL != "postgres://secret" {
+		t.Errorf("expected the URL from the file without its newline, got %q", cfg.Database.URL)
+	}
+
+	_, err = Load(nil, env(map[string]string{"DATABASE_URL_FILE": secret, "DATABASE_URL": "postgres://env"}))
+	if err == nil || !strings.Contains(err.Error(), "use only one") {
+		t.Errorf("expected an error when both are set, got %v", err)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		want []string
+	}{
+		{"missing database URL", nil, nil, []string{"database.url is required"}},
+		{
+			"every invalid setting",
+			[]string{"-log-format", "xml", "-seed-watch-interval", "-1s"},
+			map[string]string{"DATABASE_URL": "postgres://env", "PAGE_SIZE": "500", "TRACE_EXPORTER": "file"},
+			[]string{"api.page_size", "log.format", "trace.file is required", "seed.watch_interval"},
+		},
+		{"bad duration", nil, map[string]string{"SERVER_IDLE_TIMEOUT": "soon"}, []string{"SERVER_IDLE_TIMEOUT"}},
+		{"missing explicit file", []string{"-config", "missing.yaml"}, nil, []string{"could not read config file"}},
+		{"unknown key", []string{"-config", writeFile(t, "bad.yaml", "server:\n  port: 1\n")}, nil, []string{"field port not found"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.args, env(tt.env))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("expected %q in %q", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestPrint_RedactsSecrets(t *testing.T) {
+	cfg, err := Load([]string{"-print-config"}, env(map[string]string{
+		"DATABASE_URL":  "postgres://user:hunter2@db/gallery",
+		"METRICS_TOKEN": "s3cret",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.PrintConfig {
+		t.Error("expected PrintConfig to be set")
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.Print(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, "hunter2") || strings.Contains(out, "s3cret") {
+		t.Errorf("expected secrets to be redacted, got:\n%s", out)
+	}
+	if !strings.Contains(out, "url: REDACTED") || !strings.Contains(out, "page_size: 20") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+	if cfg.Database.URL != "postgres://user:hunter2@db/gallery" {
+		t.Error("printing must not change the configuration")
+	}
+}
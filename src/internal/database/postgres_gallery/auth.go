@@ -1,12 +1,21 @@
 package postgres_gallery
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
 	"dZev1/character-gallery/models/auth"
+	"dZev1/character-gallery/models/events"
+
 	"github.com/jmoiron/sqlx"
 )
 
 type PGAuthStore struct {
 	db *sqlx.DB
+	notifier
 }
 
 func NewAuthStore(db *sqlx.DB) auth.AuthStore {
@@ -15,26 +24,49 @@ func NewAuthStore(db *sqlx.DB) auth.AuthStore {
 	}
 }
 
-func (s *PGAuthStore) ValidateAPIKey(keyHash string) (bool, error) {
-	var exists bool
+func (s *PGAuthStore) ValidateAPIKey(keyHash string) (*auth.APIKey, error) {
+	key := &struct {
+		auth.APIKey
+		IsExpired bool `db:"is_expired"`
+	}{}
+	// Expiry is compared against the database clock, the same one that fills created_at.
 	query := `
-		SELECT EXISTS(SELECT 1 FROM api_keys WHERE key_hash = $1)
+		SELECT ` + apiKeyColumns + `, (expires_at IS NOT NULL AND expires_at <= NOW()) AS is_expired
+		FROM api_keys WHERE key_hash = $1
 	`
 
-	err := s.db.QueryRowx(query, keyHash).Scan(&exists)
+	err := s.db.Get(key, query, keyHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, auth.ErrAPIKeyNotFound
+	}
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	return exists, nil
+	if !key.IsActive {
+		return nil, auth.ErrAPIKeyRevoked
+	}
+
+	if key.IsExpired {
+		return nil, auth.ErrAPIKeyExpired
+	}
+
+	return &key.APIKey, nil
 }
 
-func (s *PGAuthStore) UpdateLastUsed(keyHash string) error {
-	query := `
-		UPDATE api_keys SET last_used = NOW() WHERE key_hash = $1
-	`
+func (s *PGAuthStore) UpdateLastUsed(keyHashes ...string) error {
+	if len(keyHashes) == 0 {
+		return nil
+	}
 
-	_, err := s.db.Exec(query, keyHash)
+	query, args, err := sqlx.In(`
+		UPDATE api_keys SET last_used_at = NOW() WHERE key_hash IN (?)
+	`, keyHashes)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(s.db.Rebind(query), args...)
 	if err != nil {
 		return err
 	}
@@ -42,31 +74,313 @@ func (s *PGAuthStore) UpdateLastUsed(keyHash string) error {
 	return nil
 }
 
-func (s *PGAuthStore) CreateAPIKey(name string) (string, error) {
-	keyHash, rawKey, err := auth.GenerateAPIKey()
+func (s *PGAuthStore) CreateAPIKey(actor auth.Actor, name string, scopes auth.Scopes, expiresIn time.Duration) (*auth.APIKey, string, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, "", err
+	}
+	defer tx.Rollback()
+
+	key, rawKey, err := insertAPIKey(tx, name, scopes, expiresIn)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err = recordAudit(tx, actor, auth.AuditCreate, &key.ID); err != nil {
+		return nil, "", err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, "", err
+	}
+
+	return key, rawKey, nil
+}
+
+func (s *PGAuthStore) RevokeAPIKey(actor auth.Actor, id auth.APIKeyID) error {
+	return s.setActive(actor, auth.AuditRevoke, id, false)
+}
+
+func (s *PGAuthStore) ReactivateAPIKey(actor auth.Actor, id auth.APIKeyID) error {
+	return s.setActive(actor, auth.AuditReactivate, id, true)
+}
+
+func (s *PGAuthStore) ListAPIKeys() ([]auth.APIKey, error) {
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		ORDER BY id
+	`
+
+	var keys []auth.APIKey
+	err := s.db.Select(&keys, query)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
+	return keys, nil
+}
+
+func (s *PGAuthStore) RotateAPIKey(actor auth.Actor, id auth.APIKeyID, gracePeriod time.Duration) (*auth.APIKey, string, error) {
 	tx, err := s.db.Beginx()
 	if err != nil {
-		return "", err
+		return nil, "", err
 	}
 	defer tx.Rollback()
 
+	old := &struct {
+		auth.APIKey
+		IsExpired bool    `db:"is_expired"`
+		ExpiresIn float64 `db:"expires_in"`
+	}{}
+	// The replacement gets what is left of the old key's life, by the database clock.
+	query := `
+		SELECT ` + apiKeyColumns + `, (expires_at IS NOT NULL AND expires_at <= NOW()) AS is_expired,
+			COALESCE(EXTRACT(EPOCH FROM expires_at - NOW()), 0)::float8 AS expires_in
+		FROM api_keys WHERE id = $1 FOR UPDATE
+	`
+	err = tx.Get(old, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", auth.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !old.IsActive {
+		return nil, "", auth.ErrAPIKeyRevoked
+	}
+	if old.IsExpired {
+		return nil, "", auth.ErrAPIKeyExpired
+	}
+
+	key, rawKey, err := insertAPIKey(tx, old.Name, old.Scopes, time.Duration(old.ExpiresIn*float64(time.Second)))
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The grace period can only shorten the old key's life, never extend it.
+	query = `
+		UPDATE api_keys
+		SET expires_at = LEAST(COALESCE(expires_at, 'infinity'), NOW() + make_interval(secs => $1::float8))
+		WHERE id = $2
+	`
+	_, err = tx.Exec(query, gracePeriod.Seconds(), id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err = recordAudit(tx, actor, auth.AuditRotate, &id); err != nil {
+		return nil, "", err
+	}
+
+	err = s.notify(context.Background(), tx, events.Change{Entity: events.EntityAPIKey, Action: events.ActionUpdated, ID: uint64(id)})
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, "", err
+	}
+
+	return key, rawKey, nil
+}
+
+func (s *PGAuthStore) PruneAPIKeys(actor auth.Actor, unusedFor time.Duration) (int64, error) {
 	query := `
-		INSERT INTO api_keys (name, key_hash)
-		VALUES ($1, $2)
+		DELETE FROM api_keys
+		WHERE COALESCE(last_used_at, created_at) < NOW() - make_interval(secs => $1::float8)
 	`
 
-	_, err = tx.Exec(query, name, keyHash)
+	tx, err := s.db.Beginx()
 	if err != nil {
-		return "", err
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(query, unusedFor.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	pruned, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if pruned > 0 {
+		err = s.notify(context.Background(), tx, events.Change{Entity: events.EntityAPIKey, Action: events.ActionDeleted})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err = recordAudit(tx, actor, auth.AuditPrune, nil); err != nil {
+		return 0, err
 	}
 
 	if err = tx.Commit(); err != nil {
-		return "", err
+		return 0, err
+	}
+
+	return pruned, nil
+}
+
+func (s *PGAuthStore) GetAPIKey(id auth.APIKeyID) (*auth.APIKey, error) {
+	key := &auth.APIKey{}
+	err := s.db.Get(key, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, auth.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (s *PGAuthStore) SetMonthlyQuota(actor auth.Actor, id auth.APIKeyID, quota uint64) error {
+	query := `
+		UPDATE api_keys SET monthly_quota = NULLIF($1::bigint, 0) WHERE id = $2
+	`
+
+	return s.updateKey(actor, auth.AuditSetQuota, id, query, quota, id)
+}
+
+func (s *PGAuthStore) RecordUsage(records []auth.UsageRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO api_key_usage (key_id, day, route, status_class, count)
+		VALUES (:key_id, :day, :route, :status_class, :count)
+		ON CONFLICT (key_id, day, route, status_class) DO UPDATE SET
+			count = api_key_usage.count + EXCLUDED.count
+	`
+
+	_, err := s.db.NamedExec(query, records)
+	if err != nil {
+		return fmt.Errorf("could not record usage: %w", err)
+	}
+
+	return nil
+}
+
+func (s *PGAuthStore) GetUsage(id auth.APIKeyID, from time.Time, to time.Time) ([]auth.UsageRecord, error) {
+	query := `
+		SELECT key_id, day, route, status_class, count
+		FROM api_key_usage
+		WHERE key_id = $1 AND day BETWEEN $2 AND $3
+		ORDER BY day, route, status_class
+	`
+
+	var records []auth.UsageRecord
+	err := s.db.Select(&records, query, id, auth.StartOfDay(from), auth.StartOfDay(to))
+	if err != nil {
+		return nil, fmt.Errorf("could not get usage: %w", err)
+	}
+
+	return records, nil
+}
+
+func (s *PGAuthStore) MonthlyUsage(id auth.APIKeyID, month time.Time) (uint64, error) {
+	query := `
+		SELECT COALESCE(SUM(count), 0)
+		FROM api_key_usage
+		WHERE key_id = $1 AND day >= $2::date AND day < $2::date + INTERVAL '1 month'
+	`
+
+	var total uint64
+	err := s.db.Get(&total, query, id, auth.StartOfMonth(month))
+	if err != nil {
+		return 0, fmt.Errorf("could not get monthly usage: %w", err)
+	}
+
+	return total, nil
+}
+
+func (s *PGAuthStore) setActive(actor auth.Actor, action auth.AuditAction, id auth.APIKeyID, active bool) error {
+	query := `
+		UPDATE api_keys SET is_active = $1 WHERE id = $2
+	`
+
+	return s.updateKey(actor, action, id, query, active, id)
+}
+
+// updateKey runs an UPDATE on the key id, recording it as action, and reports ErrAPIKeyNotFound
+// if nothing matched.
+func (s *PGAuthStore) updateKey(actor auth.Actor, action auth.AuditAction, id auth.APIKeyID, query string, args ...any) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not verify rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return auth.ErrAPIKeyNotFound
+	}
+
+	if err = recordAudit(tx, actor, action, &id); err != nil {
+		return err
+	}
+
+	err = s.notify(context.Background(), tx, events.Change{Entity: events.EntityAPIKey, Action: events.ActionUpdated, ID: uint64(id)})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+const apiKeyColumns = `id, key_hash, name, scopes, created_at, last_used_at, expires_at, monthly_quota, is_active`
+
+func insertAPIKey(tx *sqlx.Tx, name string, scopes auth.Scopes, expiresIn time.Duration) (*auth.APIKey, string, error) {
+	keyHash, rawKey, err := auth.GenerateAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(scopes) == 0 {
+		scopes = auth.DefaultScopes
+	}
+
+	query := `
+		INSERT INTO api_keys (name, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, CASE WHEN $4::float8 > 0 THEN NOW() + make_interval(secs => $4::float8) END)
+		RETURNING ` + apiKeyColumns
+
+	key := &auth.APIKey{}
+	err = tx.Get(key, query, name, keyHash, scopes, expiresIn.Seconds())
+	if err != nil {
+		return nil, "", err
 	}
 
-	return rawKey, nil
-}
\ No newline at end of file
+	return key, rawKey, nil
+}
+
+// recordAudit writes the action to the audit log in tx, so that it is kept exactly when the
+// change it records is.
+func recordAudit(tx *sqlx.Tx, actor auth.Actor, action auth.AuditAction, target *auth.APIKeyID) error {
+	query := `
+		INSERT INTO api_key_audit_log (actor_key_id, action, target_key_id, source)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	_, err := tx.Exec(query, actor.KeyID, action, target, actor.Source)
+	if err != nil {
+		return fmt.Errorf("could not record audit entry: %w", err)
+	}
+
+	return nil
+}
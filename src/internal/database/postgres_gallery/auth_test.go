@@ -1,9 +1,11 @@
 package postgres_gallery
 
 import (
+	"database/sql"
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"dZev1/character-gallery/models/auth"
 	"github.com/DATA-DOG/go-sqlmock"
@@ -14,16 +16,20 @@ func TestValidateAPIKey_Success(t *testing.T) {
 
 	key := createTestAPIKey()
 
-	rows := sqlmock.NewRows([]string{"exists"}).AddRow(true)
-	mock.ExpectQuery(`SELECT EXISTS`).WithArgs(key.KeyHash).WillReturnRows(rows)
+	rows := validateRows().AddRow(key.ID, key.KeyHash, key.Name, "read,admin", key.CreatedAt, nil, nil, nil, true, false)
+	mock.ExpectQuery(`SELECT (.+) FROM api_keys WHERE key_hash`).WithArgs(key.KeyHash).WillReturnRows(rows)
 
-	valid, err := authStore.ValidateAPIKey(key.KeyHash)
+	validated, err := authStore.ValidateAPIKey(key.KeyHash)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	if !valid {
-		t.Fatal("expected key to be valid")
+	if validated.ID != key.ID {
+		t.Fatalf("expected key ID %d, got %d", key.ID, validated.ID)
+	}
+
+	if !validated.Scopes.Has(auth.ScopeAdmin) {
+		t.Fatalf("expected admin scope, got %v", validated.Scopes)
 	}
 
 	if err = mock.ExpectationsWereMet(); err != nil {
@@ -34,16 +40,15 @@ func TestValidateAPIKey_Success(t *testing.T) {
 func TestValidateAPIKey_NotFound(t *testing.T) {
 	authStore, mock := setupMockAuthStore(t)
 
-	rows := sqlmock.NewRows([]string{"exists"}).AddRow(false)
-	mock.ExpectQuery(`SELECT EXISTS`).WithArgs("nonexistent_hash").WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT (.+) FROM api_keys WHERE key_hash`).WithArgs("nonexistent_hash").WillReturnError(sql.ErrNoRows)
 
-	valid, err := authStore.ValidateAPIKey("nonexistent_hash")
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	validated, err := authStore.ValidateAPIKey("nonexistent_hash")
+	if !errors.Is(err, auth.ErrAPIKeyNotFound) {
+		t.Fatalf("expected ErrAPIKeyNotFound, got: %v", err)
 	}
 
-	if valid {
-		t.Fatal("expected key to be invalid")
+	if validated != nil {
+		t.Fatal("expected no key to be returned")
 	}
 
 	if err = mock.ExpectationsWereMet(); err != nil {
@@ -55,7 +60,7 @@ func TestValidateAPIKey_DBError(t *testing.T) {
 	authStore, mock := setupMockAuthStore(t)
 
 	dbErr := errors.New("database connection failed")
-	mock.ExpectQuery(`SELECT EXISTS`).WithArgs("some_hash").WillReturnError(dbErr)
+	mock.ExpectQuery(`SELECT (.+) FROM api_keys WHERE key_hash`).WithArgs("some_hash").WillReturnError(dbErr)
 
 	_, err := authStore.ValidateAPIKey("some_hash")
 	if err == nil {
@@ -67,12 +72,72 @@ func TestValidateAPIKey_DBError(t *testing.T) {
 	}
 }
 
+func TestValidateAPIKey_Revoked(t *testing.T) {
+	authStore, mock := setupMockAuthStore(t)
+
+	rows := validateRows().AddRow(1, "revoked_hash", "Key", "read", time.Now(), nil, nil, nil, false, false)
+	mock.ExpectQuery(`SELECT (.+) FROM api_keys WHERE key_hash`).WithArgs("revoked_hash").WillReturnRows(rows)
+
+	validated, err := authStore.ValidateAPIKey("revoked_hash")
+	if !errors.Is(err, auth.ErrAPIKeyRevoked) {
+		t.Fatalf("expected ErrAPIKeyRevoked, got: %v", err)
+	}
+
+	if validated != nil {
+		t.Fatal("expected revoked key not to be returned")
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestValidateAPIKey_Expired(t *testing.T) {
+	authStore, mock := setupMockAuthStore(t)
+
+	rows := validateRows().AddRow(1, "expired_hash", "Key", "read", time.Now(), nil, nil, nil, true, true)
+	mock.ExpectQuery(`SELECT (.+) FROM api_keys WHERE key_hash`).WithArgs("expired_hash").WillReturnRows(rows)
+
+	validated, err := authStore.ValidateAPIKey("expired_hash")
+	if !errors.Is(err, auth.ErrAPIKeyExpired) {
+		t.Fatalf("expected ErrAPIKeyExpired, got: %v", err)
+	}
+
+	if validated != nil {
+		t.Fatal("expected expired key not to be returned")
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestValidateAPIKey_RevokedTakesPrecedence(t *testing.T) {
+	authStore, mock := setupMockAuthStore(t)
+
+	rows := validateRows().AddRow(1, "revoked_hash", "Key", "read", time.Now(), nil, nil, nil, false, true)
+	mock.ExpectQuery(`SELECT (.+) FROM api_keys WHERE key_hash`).WithArgs("revoked_hash").WillReturnRows(rows)
+
+	validated, err := authStore.ValidateAPIKey("revoked_hash")
+	if !errors.Is(err, auth.ErrAPIKeyRevoked) {
+		t.Fatalf("expected ErrAPIKeyRevoked, got: %v", err)
+	}
+
+	if validated != nil {
+		t.Fatal("expected revoked key not to be returned")
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
 func TestUpdateLastUsed_Success(t *testing.T) {
 	authStore, mock := setupMockAuthStore(t)
 
 	key := createTestAPIKey()
 
-	mock.ExpectExec(`UPDATE api_keys SET last_used = NOW\(\) WHERE key_hash`).WithArgs(key.KeyHash).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE api_keys SET last_used_at = NOW\(\) WHERE key_hash`).WithArgs(key.KeyHash).WillReturnResult(sqlmock.NewResult(0, 1))
 
 	err := authStore.UpdateLastUsed(key.KeyHash)
 	if err != nil {
@@ -84,11 +149,41 @@ func TestUpdateLastUsed_Success(t *testing.T) {
 	}
 }
 
+func TestUpdateLastUsed_Batch(t *testing.T) {
+	authStore, mock := setupMockAuthStore(t)
+
+	mock.ExpectExec(`UPDATE api_keys SET last_used_at = NOW\(\) WHERE key_hash IN \(.+\)`).
+		WithArgs("hash1", "hash2", "hash3").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	err := authStore.UpdateLastUsed("hash1", "hash2", "hash3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestUpdateLastUsed_NoKeys(t *testing.T) {
+	authStore, mock := setupMockAuthStore(t)
+
+	err := authStore.UpdateLastUsed()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
 func TestUpdateLastUsed_DBError(t *testing.T) {
 	authStore, mock := setupMockAuthStore(t)
 
 	dbErr := errors.New("database error")
-	mock.ExpectExec(`UPDATE api_keys SET last_used = NOW\(\) WHERE key_hash`).WithArgs("some_hash").WillReturnError(dbErr)
+	mock.ExpectExec(`UPDATE api_keys SET last_used_at = NOW\(\) WHERE key_hash`).WithArgs("some_hash").WillReturnError(dbErr)
 
 	err := authStore.UpdateLastUsed("some_hash")
 	if err == nil {
@@ -104,14 +199,25 @@ func TestCreateAPIKey_Success(t *testing.T) {
 	authStore, mock := setupMockAuthStore(t)
 
 	mock.ExpectBegin()
-	mock.ExpectExec(`INSERT INTO api_keys`).WithArgs("test-key", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`INSERT INTO api_keys`).
+		WithArgs("test-key", sqlmock.AnyArg(), "read,write", float64(0)).
+		WillReturnRows(apiKeyRows().AddRow(1, "hash", "test-key", "read,write", time.Now(), nil, nil, nil, true))
+	expectAudit(mock, auth.AuditCreate, auth.APIKeyID(1))
 	mock.ExpectCommit()
 
-	rawKey, err := authStore.CreateAPIKey("test-key")
+	key, rawKey, err := authStore.CreateAPIKey(testActor, "test-key", nil, 0)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
+	if key.ID != 1 {
+		t.Fatalf("expected key ID 1, got %d", key.ID)
+	}
+
+	if !key.Scopes.Has(auth.ScopeRead) || !key.Scopes.Has(auth.ScopeWrite) {
+		t.Fatalf("expected default scopes, got %v", key.Scopes)
+	}
+
 	if rawKey == "" {
 		t.Fatal("expected non-empty raw key")
 	}
@@ -131,7 +237,7 @@ func TestCreateAPIKey_BeginError(t *testing.T) {
 	dbErr := errors.New("begin transaction failed")
 	mock.ExpectBegin().WillReturnError(dbErr)
 
-	_, err := authStore.CreateAPIKey("test-key")
+	_, _, err := authStore.CreateAPIKey(testActor, "test-key", nil, 0)
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -146,10 +252,10 @@ func TestCreateAPIKey_InsertError(t *testing.T) {
 
 	dbErr := errors.New("insert failed")
 	mock.ExpectBegin()
-	mock.ExpectExec(`INSERT INTO api_keys`).WithArgs("test-key", sqlmock.AnyArg()).WillReturnError(dbErr)
+	mock.ExpectQuery(`INSERT INTO api_keys`).WithArgs("test-key", sqlmock.AnyArg(), "read,write", float64(0)).WillReturnError(dbErr)
 	mock.ExpectRollback()
 
-	_, err := authStore.CreateAPIKey("test-key")
+	_, _, err := authStore.CreateAPIKey(testActor, "test-key", nil, 0)
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -164,10 +270,13 @@ func TestCreateAPIKey_CommitError(t *testing.T) {
 
 	dbErr := errors.New("commit failed")
 	mock.ExpectBegin()
-	mock.ExpectExec(`INSERT INTO api_keys`).WithArgs("test-key", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`INSERT INTO api_keys`).
+		WithArgs("test-key", sqlmock.AnyArg(), "read,write", float64(0)).
+		WillReturnRows(apiKeyRows().AddRow(1, "hash", "test-key", "read,write", time.Now(), nil, nil, nil, true))
+	expectAudit(mock, auth.AuditCreate, auth.APIKeyID(1))
 	mock.ExpectCommit().WillReturnError(dbErr)
 
-	_, err := authStore.CreateAPIKey("test-key")
+	_, _, err := authStore.CreateAPIKey(testActor, "test-key", nil, 0)
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -177,6 +286,452 @@ func TestCreateAPIKey_CommitError(t *testing.T) {
 	}
 }
 
+func TestCreateAPIKey_WithScopesAndExpiry(t *testing.T) {
+	authStore, mock := setupMockAuthStore(t)
+
+	expiresAt := time.Now().Add(24 * time.Hour)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO api_keys`).
+		WithArgs("admin-key", sqlmock.AnyArg(), "read,admin", float64(86400)).
+		WillReturnRows(apiKeyRows().AddRow(2, "hash", "admin-key", "read,admin", time.Now(), nil, expiresAt, nil, true))
+	expectAudit(mock, auth.AuditCreate, auth.APIKeyID(2))
+	mock.ExpectCommit()
+
+	key, _, err := authStore.CreateAPIKey(testActor, "admin-key", auth.Scopes{auth.ScopeRead, auth.ScopeAdmin}, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !key.Scopes.Has(auth.ScopeAdmin) {
+		t.Fatalf("expected admin scope, got %v", key.Scopes)
+	}
+
+	if key.ExpiresAt == nil {
+		t.Fatal("expected expires_at to be set")
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRevokeAPIKey_Success(t *testing.T) {
+	authStore, mock := setupMockAuthStore(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE api_keys SET is_active`).WithArgs(false, auth.APIKeyID(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectAudit(mock, auth.AuditRevoke, auth.APIKeyID(1))
+	expectNotify(mock, `{"entity":"api_key","action":"updated","id":1}`)
+	mock.ExpectCommit()
+
+	err := authStore.RevokeAPIKey(testActor, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRevokeAPIKey_NotFound(t *testing.T) {
+	authStore, mock := setupMockAuthStore(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE api_keys SET is_active`).WithArgs(false, auth.APIKeyID(999)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := authStore.RevokeAPIKey(testActor, 999)
+	if !errors.Is(err, auth.ErrAPIKeyNotFound) {
+		t.Fatalf("expected ErrAPIKeyNotFound, got: %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestReactivateAPIKey_Success(t *testing.T) {
+	authStore, mock := setupMockAuthStore(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE api_keys SET is_active`).WithArgs(true, auth.APIKeyID(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectAudit(mock, auth.AuditReactivate, auth.APIKeyID(1))
+	expectNotify(mock, `{"entity":"api_key","action":"updated","id":1}`)
+	mock.ExpectCommit()
+
+	err := authStore.ReactivateAPIKey(testActor, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestListAPIKeys_Success(t *testing.T) {
+	authStore, mock := setupMockAuthStore(t)
+
+	key := createTestAPIKey()
+
+	rows := apiKeyRows().
+		AddRow(key.ID, key.KeyHash, key.Name, "read,write", key.CreatedAt, key.LastUsedAt, nil, nil, key.IsActive).
+		AddRow(2, "otherhash", "Other Key", "read", key.CreatedAt, nil, nil, nil, false)
+	mock.ExpectQuery(`SELECT (.+) FROM api_keys`).WillReturnRows(rows)
+
+	keys, err := authStore.ListAPIKeys()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 keys, got %d", len(keys))
+	}
+
+	if keys[1].LastUsedAt != nil {
+		t.Fatal("expected never-used key to have nil last_used_at")
+	}
+
+	if keys[1].IsActive {
+		t.Fatal("expected second key to be inactive")
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRotateAPIKey_Success(t *testing.T) {
+	authStore, mock := setupMockAuthStore(t)
+
+	key := createTestAPIKey()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT (.+) FROM api_keys WHERE id = \$1 FOR UPDATE`).
+		WithArgs(key.ID).
+		WillReturnRows(rotateRows().AddRow(key.ID, key.KeyHash, key.Name, "read", key.CreatedAt, key.LastUsedAt, nil, nil, true, false, 0))
+	mock.ExpectQuery(`INSERT INTO api_keys`).
+		WithArgs(key.Name, sqlmock.AnyArg(), "read", float64(0)).
+		WillReturnRows(apiKeyRows().AddRow(2, "newhash", key.Name, "read", time.Now(), nil, nil, nil, true))
+	mock.ExpectExec(`UPDATE api_keys\s+SET expires_at = LEAST`).
+		WithArgs(float64(3600), key.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectAudit(mock, auth.AuditRotate, key.ID)
+	expectNotify(mock, `{"entity":"api_key","action":"updated","id":1}`)
+	mock.ExpectCommit()
+
+	newKey, rawKey, err := authStore.RotateAPIKey(testActor, key.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if newKey.ID != 2 {
+		t.Fatalf("expected replacement key ID 2, got %d", newKey.ID)
+	}
+
+	if !strings.HasPrefix(rawKey, "dz_chars_") {
+		t.Fatalf("expected key to have prefix 'dz_chars_', got: %s", rawKey)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRotateAPIKey_KeepsExpiry(t *testing.T) {
+	authStore, mock := setupMockAuthStore(t)
+
+	key := createTestAPIKey()
+	expiresAt := time.Now().Add(2 * time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT (.+) FROM api_keys WHERE id = \$1 FOR UPDATE`).
+		WithArgs(key.ID).
+		WillReturnRows(rotateRows().AddRow(key.ID, key.KeyHash, key.Name, "read", key.CreatedAt, key.LastUsedAt, expiresAt, nil, true, false, 7200))
+	mock.ExpectQuery(`INSERT INTO api_keys`).
+		WithArgs(key.Name, sqlmock.AnyArg(), "read", float64(7200)).
+		WillReturnRows(apiKeyRows().AddRow(2, "newhash", key.Name, "read", time.Now(), nil, expiresAt, nil, true))
+	mock.ExpectExec(`UPDATE api_keys\s+SET expires_at = LEAST`).
+		WithArgs(float64(3600), key.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectAudit(mock, auth.AuditRotate, key.ID)
+	expectNotify(mock, `{"entity":"api_key","action":"updated","id":1}`)
+	mock.ExpectCommit()
+
+	newKey, _, err := authStore.RotateAPIKey(testActor, key.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if newKey.ExpiresAt == nil || !newKey.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("expected replacement to expire at %v, got %v", expiresAt, newKey.ExpiresAt)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRotateAPIKey_Revoked(t *testing.T) {
+	authStore, mock := setupMockAuthStore(t)
+
+	key := createTestAPIKey()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT (.+) FROM api_keys WHERE id = \$1 FOR UPDATE`).
+		WithArgs(key.ID).
+		WillReturnRows(rotateRows().AddRow(key.ID, key.KeyHash, key.Name, "read", key.CreatedAt, key.LastUsedAt, nil, nil, false, false, 0))
+	mock.ExpectRollback()
+
+	_, _, err := authStore.RotateAPIKey(testActor, key.ID, time.Hour)
+	if !errors.Is(err, auth.ErrAPIKeyRevoked) {
+		t.Fatalf("expected ErrAPIKeyRevoked, got: %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRotateAPIKey_Expired(t *testing.T) {
+	authStore, mock := setupMockAuthStore(t)
+
+	key := createTestAPIKey()
+	expiresAt := time.Now().Add(-time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT (.+) FROM api_keys WHERE id = \$1 FOR UPDATE`).
+		WithArgs(key.ID).
+		WillReturnRows(rotateRows().AddRow(key.ID, key.KeyHash, key.Name, "read", key.CreatedAt, key.LastUsedAt, expiresAt, nil, true, true, -3600))
+	mock.ExpectRollback()
+
+	_, _, err := authStore.RotateAPIKey(testActor, key.ID, time.Hour)
+	if !errors.Is(err, auth.ErrAPIKeyExpired) {
+		t.Fatalf("expected ErrAPIKeyExpired, got: %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRotateAPIKey_NotFound(t *testing.T) {
+	authStore, mock := setupMockAuthStore(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT (.+) FROM api_keys WHERE id = \$1 FOR UPDATE`).
+		WithArgs(auth.APIKeyID(999)).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	_, _, err := authStore.RotateAPIKey(testActor, 999, time.Hour)
+	if !errors.Is(err, auth.ErrAPIKeyNotFound) {
+		t.Fatalf("expected ErrAPIKeyNotFound, got: %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPruneAPIKeys_Success(t *testing.T) {
+	authStore, mock := setupMockAuthStore(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM api_keys`).
+		WithArgs(float64(90 * 24 * 3600)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	expectNotify(mock, `{"entity":"api_key","action":"deleted"}`)
+	expectAudit(mock, auth.AuditPrune, nil)
+	mock.ExpectCommit()
+
+	pruned, err := authStore.PruneAPIKeys(testActor, 90*24*time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if pruned != 3 {
+		t.Fatalf("expected 3 pruned keys, got %d", pruned)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRevokeAPIKey_FailsWithoutAudit(t *testing.T) {
+	authStore, mock := setupMockAuthStore(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE api_keys SET is_active`).WithArgs(false, auth.APIKeyID(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO api_key_audit_log`).
+		WithArgs(testActor.KeyID, auth.AuditRevoke, auth.APIKeyID(1), auth.AuditSourceAPI).
+		WillReturnError(errors.New("audit log unavailable"))
+	mock.ExpectRollback()
+
+	err := authStore.RevokeAPIKey(testActor, 1)
+	if err == nil {
+		t.Fatal("expected the revoke to fail with its audit entry")
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestGetAPIKey_NotFound(t *testing.T) {
+	authStore, mock := setupMockAuthStore(t)
+
+	mock.ExpectQuery(`SELECT (.+) FROM api_keys WHERE id = \$1`).WithArgs(auth.APIKeyID(999)).WillReturnError(sql.ErrNoRows)
+
+	_, err := authStore.GetAPIKey(999)
+	if !errors.Is(err, auth.ErrAPIKeyNotFound) {
+		t.Fatalf("expected ErrAPIKeyNotFound, got: %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSetMonthlyQuota_Success(t *testing.T) {
+	authStore, mock := setupMockAuthStore(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE api_keys SET monthly_quota`).WithArgs(uint64(10000), auth.APIKeyID(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectAudit(mock, auth.AuditSetQuota, auth.APIKeyID(1))
+	expectNotify(mock, `{"entity":"api_key","action":"updated","id":1}`)
+	mock.ExpectCommit()
+
+	err := authStore.SetMonthlyQuota(testActor, 1, 10000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSetMonthlyQuota_NotFound(t *testing.T) {
+	authStore, mock := setupMockAuthStore(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE api_keys SET monthly_quota`).WithArgs(uint64(0), auth.APIKeyID(999)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := authStore.SetMonthlyQuota(testActor, 999, 0)
+	if !errors.Is(err, auth.ErrAPIKeyNotFound) {
+		t.Fatalf("expected ErrAPIKeyNotFound, got: %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRecordUsage_SingleBatch(t *testing.T) {
+	authStore, mock := setupMockAuthStore(t)
+
+	day := auth.StartOfDay(time.Now())
+	records := []auth.UsageRecord{
+		{KeyID: 1, Day: day, Route: "GET /characters", StatusClass: "2xx", Count: 12},
+		{KeyID: 1, Day: day, Route: "POST /characters", StatusClass: "4xx", Count: 2},
+	}
+
+	mock.ExpectExec(`INSERT INTO api_key_usage (.+) ON CONFLICT`).
+		WithArgs(auth.APIKeyID(1), day, "GET /characters", "2xx", uint64(12),
+			auth.APIKeyID(1), day, "POST /characters", "4xx", uint64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	err := authStore.RecordUsage(records)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRecordUsage_Empty(t *testing.T) {
+	authStore, mock := setupMockAuthStore(t)
+
+	err := authStore.RecordUsage(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestGetUsage_Success(t *testing.T) {
+	authStore, mock := setupMockAuthStore(t)
+
+	from := time.Date(2025, 3, 1, 15, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 3, 31, 8, 0, 0, 0, time.UTC)
+
+	rows := sqlmock.NewRows([]string{"key_id", "day", "route", "status_class", "count"}).
+		AddRow(1, auth.StartOfDay(from), "GET /characters", "2xx", 40)
+	mock.ExpectQuery(`SELECT (.+) FROM api_key_usage`).
+		WithArgs(auth.APIKeyID(1), auth.StartOfDay(from), auth.StartOfDay(to)).
+		WillReturnRows(rows)
+
+	records, err := authStore.GetUsage(1, from, to)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(records) != 1 || records[0].Count != 40 {
+		t.Fatalf("unexpected usage records: %+v", records)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestMonthlyUsage_Success(t *testing.T) {
+	authStore, mock := setupMockAuthStore(t)
+
+	now := time.Date(2025, 3, 17, 15, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT COALESCE\(SUM\(count\), 0\)`).
+		WithArgs(auth.APIKeyID(1), time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(512))
+
+	total, err := authStore.MonthlyUsage(1, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if total != 512 {
+		t.Fatalf("expected 512 requests, got %d", total)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := auth.ParseScopes("read, write,read")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if scopes.String() != "read,write" {
+		t.Fatalf("expected deduplicated scopes 'read,write', got %q", scopes.String())
+	}
+
+	if _, err := auth.ParseScopes("read,superuser"); err == nil {
+		t.Fatal("expected error for unknown scope")
+	}
+}
+
 func TestGenerateAPIKey(t *testing.T) {
 	keyHash, rawKey, err := auth.GenerateAPIKey()
 	if err != nil {
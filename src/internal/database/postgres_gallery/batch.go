@@ -0,0 +1,58 @@
+package postgres_gallery
+
+import (
+	"context"
+	"fmt"
+
+	"dZev1/character-gallery/models"
+	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/inventory"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// batchTx makes the writes of a batch on its transaction, the same way the gallery methods they
+// are named after make them on one of their own.
+type batchTx struct {
+	cg *PostgresCharacterGallery
+	tx *sqlx.Tx
+}
+
+func (cg *PostgresCharacterGallery) Batch(ctx context.Context, fn func(ctx context.Context, tx models.BatchTx) error) error {
+	tx, err := cg.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrFailedInitializeTransaction, translateError(err, "batch"))
+	}
+	defer tx.Rollback()
+
+	err = fn(ctx, &batchTx{cg: cg, tx: tx})
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %w", ErrFailedCommitTransaction, translateError(err, "batch"))
+	}
+
+	return nil
+}
+
+func (b *batchTx) Create(ctx context.Context, character *characters.Character) error {
+	return b.cg.createCharacter(ctx, b.tx, character)
+}
+
+func (b *batchTx) Edit(ctx context.Context, character *characters.Character) error {
+	return b.cg.editCharacter(ctx, b.tx, character)
+}
+
+func (b *batchTx) AddItemToCharacter(ctx context.Context, characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) (*inventory.InventoryItem, error) {
+	return b.cg.addItemToCharacter(ctx, b.tx, characterID, itemID, quantity)
+}
+
+func (b *batchTx) RemoveItemFromCharacter(ctx context.Context, characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) error {
+	return b.cg.removeItemFromCharacter(ctx, b.tx, characterID, itemID, quantity)
+}
+
+func (b *batchTx) CreateItem(ctx context.Context, item *inventory.Item) error {
+	return b.cg.createItem(ctx, b.tx, item)
+}
@@ -0,0 +1,101 @@
+package postgres_gallery
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"dZev1/character-gallery/models"
+	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/events"
+	"dZev1/character-gallery/models/inventory"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestBatch_CommitsEveryWrite(t *testing.T) {
+	gallery, mock := setupMockDB(t)
+
+	item := createTestItem()
+	charID := characters.CharacterID(4)
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(`INSERT INTO items`).
+		ExpectQuery().
+		WithArgs(item.Name, item.Type, item.Description, item.Equippable, item.Rarity,
+			item.Damage, item.Defense, item.HealAmount, item.ManaCost, item.Duration, item.Capacity).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	expectPublish(mock, events.ItemCreated, 0, `{"entity":"item","action":"created","id":9}`)
+	// The quantity is read on the transaction, so it sees the item added before.
+	mock.ExpectQuery(`SELECT quantity FROM inventory`).
+		WithArgs(charID, inventory.ItemID(9)).
+		WillReturnRows(sqlmock.NewRows([]string{"quantity"}).AddRow(3))
+	mock.ExpectExec(`UPDATE inventory`).
+		WithArgs(uint8(1), charID, inventory.ItemID(9)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectPublish(mock, events.InventoryItemRemoved, charID, `{"entity":"inventory","action":"item_removed","id":4}`)
+	mock.ExpectCommit()
+
+	err := gallery.Batch(context.Background(), func(ctx context.Context, tx models.BatchTx) error {
+		if err := tx.CreateItem(ctx, item); err != nil {
+			return err
+		}
+		return tx.RemoveItemFromCharacter(ctx, charID, item.ID, 1)
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestBatch_RollsBackOnError(t *testing.T) {
+	gallery, mock := setupMockDB(t)
+
+	item := createTestItem()
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(`INSERT INTO items`).
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	expectPublish(mock, events.ItemCreated, 0, `{"entity":"item","action":"created","id":9}`)
+	mock.ExpectQuery(`SELECT quantity FROM inventory`).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	err := gallery.Batch(context.Background(), func(ctx context.Context, tx models.BatchTx) error {
+		if err := tx.CreateItem(ctx, item); err != nil {
+			return err
+		}
+		return tx.RemoveItemFromCharacter(ctx, 4, item.ID, 1)
+	})
+
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestBatch_TransactionError(t *testing.T) {
+	gallery, mock := setupMockDB(t)
+
+	mock.ExpectBegin().WillReturnError(errors.New("tx error"))
+
+	called := false
+	err := gallery.Batch(context.Background(), func(ctx context.Context, tx models.BatchTx) error {
+		called = true
+		return nil
+	})
+
+	if !errors.Is(err, ErrFailedInitializeTransaction) {
+		t.Errorf("expected ErrFailedInitializeTransaction, got %v", err)
+	}
+	if called {
+		t.Error("expected the batch not to run without a transaction")
+	}
+}
@@ -1,10 +1,15 @@
 package postgres_gallery
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 
+	"dZev1/character-gallery/models"
 	"dZev1/character-gallery/models/auth"
 	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/events"
+	"dZev1/character-gallery/models/webhooks"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -12,53 +17,68 @@ import (
 // Errors
 
 type PostgresCharacterGallery struct {
-	db *sqlx.DB
-	AuthStore auth.AuthStore
+	db           *sqlx.DB
+	AuthStore    auth.AuthStore
+	WebhookStore webhooks.WebhookStore
+	OutboxStore  events.OutboxStore
+
+	notifier
+	connStr  string
+	listener *Listener
 }
 
-func (cg *PostgresCharacterGallery) Create(character *characters.Character) error {
-	tx, err := cg.db.Beginx()
+func (cg *PostgresCharacterGallery) Create(ctx context.Context, character *characters.Character) error {
+	tx, err := cg.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrFailedInitializeTransaction, err)
+		return fmt.Errorf("%w: %w", ErrFailedInitializeTransaction, translateError(err, "character"))
 	}
 	defer tx.Rollback()
 
-	err = cg.insertBaseCharacter(tx, character)
+	err = cg.createCharacter(ctx, tx, character)
 	if err != nil {
 		return err
 	}
 
-	character.Stats.ID = character.ID
-	err = cg.insertStats(tx, character.Stats)
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %w", ErrFailedCommitTransaction, translateError(err, "character"))
+	}
+
+	return nil
+}
+
+func (cg *PostgresCharacterGallery) createCharacter(ctx context.Context, tx *sqlx.Tx, character *characters.Character) error {
+	err := cg.insertBaseCharacter(ctx, tx, character)
 	if err != nil {
 		return err
 	}
 
-	character.Customization.ID = character.ID
-	err = cg.insertCustomization(tx, character.Customization)
+	character.Stats.ID = character.ID
+	err = cg.insertStats(ctx, tx, character.Stats)
 	if err != nil {
 		return err
 	}
 
-	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("%w: %w", ErrFailedCommitTransaction, err)
+	character.Customization.ID = character.ID
+	err = cg.insertCustomization(ctx, tx, character.Customization)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return cg.publish(ctx, tx, events.Change{Entity: events.EntityCharacter, Action: events.ActionCreated, ID: uint64(character.ID)}, character)
 }
 
-func (cg *PostgresCharacterGallery) Get(id characters.CharacterID) (*characters.Character, error) {
-	character, err := cg.getBaseCharacter(id)
+func (cg *PostgresCharacterGallery) Get(ctx context.Context, id characters.CharacterID) (*characters.Character, error) {
+	character, err := cg.getBaseCharacter(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	character.Stats, err = cg.getStatsByID(id)
+	character.Stats, err = cg.getStatsByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	character.Customization, err = cg.getCustomizationByID(id)
+	character.Customization, err = cg.getCustomizationByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -66,7 +86,7 @@ func (cg *PostgresCharacterGallery) Get(id characters.CharacterID) (*characters.
 	return character, nil
 }
 
-func (cg *PostgresCharacterGallery) GetAll(page int) ([]characters.Character, uint64, error) {
+func (cg *PostgresCharacterGallery) GetAll(ctx context.Context, filter characters.CharacterFilter, offset, limit int) ([]characters.Character, uint64, error) {
 	var chars []characters.Character
 	query := `
 		SELECT
@@ -91,57 +111,77 @@ func (cg *PostgresCharacterGallery) GetAll(page int) ([]characters.Character, ui
             	stats s ON c.id = s.id
         	LEFT JOIN
             	customizations cust ON c.id = cust.id
+		WHERE ` + characterFilterClause + `
 		ORDER BY c.id
-		LIMIT 20 OFFSET $1
+		LIMIT $5 OFFSET $6
 	`
 
-	err := cg.db.Select(&chars, query, page)
+	args := []any{filter.Name, filter.BodyType, filter.Species, filter.Class}
+	err := cg.db.SelectContext(ctx, &chars, query, append(args, limit, offset)...)
 	if err != nil {
-		return nil, 0, fmt.Errorf("%w: %v", ErrCouldNotGet, err)
+		return nil, 0, fmt.Errorf("%w: %w", ErrCouldNotGet, translateError(err, "character"))
 	}
 
 	var total uint64
-	err = cg.db.Get(&total, `SELECT COUNT(*) FROM characters`)
+	err = cg.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM characters c WHERE `+characterFilterClause, args...)
 	if err != nil {
-		return nil, 0, fmt.Errorf("%w: %v", ErrCouldNotGetTotalCount, err)
+		return nil, 0, fmt.Errorf("%w: %w", ErrCouldNotGetTotalCount, translateError(err, "character"))
 	}
 
 	return chars, total, nil
 }
 
-func (cg *PostgresCharacterGallery) Edit(character *characters.Character) error {
-	tx, err := cg.db.Beginx()
+// characterFilterClause matches the characters c of a CharacterFilter given as $1 to $4. Empty
+// fields match every character.
+const characterFilterClause = `
+	($1 = '' OR c.name ILIKE '%' || $1 || '%') AND
+	($2 = '' OR c.body_type = $2) AND
+	($3 = '' OR c.species = $3) AND
+	($4 = '' OR c.class = $4)
+`
+
+func (cg *PostgresCharacterGallery) Edit(ctx context.Context, character *characters.Character) error {
+	tx, err := cg.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrFailedInitializeTransaction, err)
+		return fmt.Errorf("%w: %w", ErrFailedInitializeTransaction, translateError(err, "character"))
 	}
 	defer tx.Rollback()
 
-	err = cg.updateBaseCharacters(tx, character)
+	err = cg.editCharacter(ctx, tx, character)
 	if err != nil {
 		return err
 	}
 
-	err = cg.updateCustomization(tx, character.Customization)
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %w", ErrFailedCommitTransaction, translateError(err, "character"))
+	}
+
+	return nil
+}
+
+func (cg *PostgresCharacterGallery) editCharacter(ctx context.Context, tx *sqlx.Tx, character *characters.Character) error {
+	err := cg.updateBaseCharacters(ctx, tx, character)
 	if err != nil {
 		return err
 	}
 
-	err = cg.updateStats(tx, character.Stats)
+	err = cg.updateCustomization(ctx, tx, character.Customization)
 	if err != nil {
 		return err
 	}
 
-	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("%w: %w", ErrFailedCommitTransaction, err)
+	err = cg.updateStats(ctx, tx, character.Stats)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return cg.publish(ctx, tx, events.Change{Entity: events.EntityCharacter, Action: events.ActionUpdated, ID: uint64(character.ID)}, character)
 }
 
-func (cg *PostgresCharacterGallery) Remove(id characters.CharacterID) error {
-	tx, err := cg.db.Beginx()
+func (cg *PostgresCharacterGallery) Remove(ctx context.Context, id characters.CharacterID) error {
+	tx, err := cg.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrFailedInitializeTransaction, err)
+		return fmt.Errorf("%w: %w", ErrFailedInitializeTransaction, translateError(err, "character"))
 	}
 	defer tx.Rollback()
 
@@ -150,9 +190,9 @@ func (cg *PostgresCharacterGallery) Remove(id characters.CharacterID) error {
 		WHERE ID=$1
 	`
 
-	result, err := tx.Exec(query, id)
+	result, err := tx.ExecContext(ctx, query, id)
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrCouldNotFind, err)
+		return fmt.Errorf("%w: %w", ErrCouldNotFind, translateError(err, "character"))
 	}
 
 	rowsAffected, err := result.RowsAffected()
@@ -161,16 +201,44 @@ func (cg *PostgresCharacterGallery) Remove(id characters.CharacterID) error {
 	}
 
 	if rowsAffected == 0 {
-		return ErrCouldNotFind
+		return models.NotFound("character not found", ErrCouldNotFind)
+	}
+
+	err = cg.publish(ctx, tx, events.Change{Entity: events.EntityCharacter, Action: events.ActionDeleted, ID: uint64(id)}, events.DeletedCharacter{ID: id})
+	if err != nil {
+		return err
 	}
 
 	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("%w: %w", ErrFailedCommitTransaction, err)
+		return fmt.Errorf("%w: %w", ErrFailedCommitTransaction, translateError(err, "character"))
 	}
 
 	return nil
 }
 
+func (cg *PostgresCharacterGallery) CountCharacters(ctx context.Context) (uint64, error) {
+	var total uint64
+	err := cg.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM characters`)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrCouldNotGetTotalCount, translateError(err, "character"))
+	}
+
+	return total, nil
+}
+
+// Stats reports the state of the connection pool.
+func (cg *PostgresCharacterGallery) Stats() sql.DBStats {
+	return cg.db.Stats()
+}
+
 func (cg *PostgresCharacterGallery) GetAuthStore() auth.AuthStore {
 	return cg.AuthStore
 }
+
+func (cg *PostgresCharacterGallery) GetWebhookStore() webhooks.WebhookStore {
+	return cg.WebhookStore
+}
+
+func (cg *PostgresCharacterGallery) GetOutboxStore() events.OutboxStore {
+	return cg.OutboxStore
+}
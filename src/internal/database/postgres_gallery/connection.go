@@ -1,37 +1,68 @@
 package postgres_gallery
 
 import (
+	"context"
+	"database/sql/driver"
 	_ "embed"
 	"fmt"
-	"log"
+	"log/slog"
 
 	"dZev1/character-gallery/models"
+
+	"github.com/XSAM/otelsql"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 //go:embed schema.sql
 var schemaSQL string
 
 func NewPostgresCharacterGallery(connStr string) (models.CharacterGallery, error) {
-	var err error
-	db, err := sqlx.Connect("pgx", connStr)
+	// Queries run on behalf of a traced request show up as child spans carrying the statement.
+	sqlDB, err := otelsql.Open("pgx", connStr,
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitRows:             true,
+			SpanFilter: func(ctx context.Context, _ otelsql.Method, _ string, _ []driver.NamedValue) bool {
+				return trace.SpanContextFromContext(ctx).IsValid()
+			},
+		}),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("could not establish connection to database: %v", err)
 	}
 
+	db := sqlx.NewDb(sqlDB, "pgx")
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("could not establish connection to database: %v", err)
+	}
+
 	db.MustExec(string(schemaSQL))
 
-	log.Println("Database connection established")
+	slog.Info("database connection established")
+
+	// Every write is announced with this origin, so that this instance's listener skips its own.
+	notifier := notifier{origin: newOrigin()}
 
 	return &PostgresCharacterGallery{
-		db:        db,
-		AuthStore: NewAuthStore(db),
+		db:           db,
+		AuthStore:    &PGAuthStore{db: db, notifier: notifier},
+		WebhookStore: NewWebhookStore(db),
+		OutboxStore:  NewOutboxStore(db),
+		notifier:     notifier,
+		connStr:      connStr,
 	}, nil
 }
 
 func (cg *PostgresCharacterGallery) Close() error {
-	log.Println("Database connection terminated")
+	if cg.listener != nil {
+		cg.listener.Close()
+	}
+	slog.Info("database connection terminated")
 	err := cg.db.Close()
 	if err != nil {
 		return fmt.Errorf("error closing database connection: %v\n", err)
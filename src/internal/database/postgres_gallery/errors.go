@@ -1,6 +1,17 @@
 package postgres_gallery
 
-import "errors"
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+
+	"dZev1/character-gallery/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
 
 var (
 	ErrCouldNotInsert                 = errors.New(`could not insert character`)
@@ -11,3 +22,67 @@ var (
 	ErrFailedSelectCharacterInventory = errors.New(`failed to select character inventory`)
 	ErrCouldNotGetTotalCount          = errors.New("could not get total character count")
 )
+
+// Resources named by the foreign keys in schema.sql, reported when a referenced row is missing.
+// schema.sql adds the keys without naming them each time it runs, so databases created earlier
+// hold copies numbered after these names, which foreignKeyResource matches too.
+var foreignKeyResources = map[string]string{
+	"inventory_character_id_fkey": "character",
+	"inventory_item_id_fkey":      "item",
+	"stats_id_fkey":               "character",
+	"customizations_id_fkey":      "character",
+}
+
+var uniqueViolationMessages = map[string]string{
+	"items_name_rarity_unique": "an item with this name and rarity already exists",
+}
+
+// foreignKeyResource returns the resource of the foreign key named constraint, whatever its
+// number: inventory_item_id_fkey1 is the same key as inventory_item_id_fkey.
+func foreignKeyResource(constraint string) (string, bool) {
+	resource, ok := foreignKeyResources[strings.TrimRight(constraint, "0123456789")]
+	return resource, ok
+}
+
+// translateError wraps err in the models error matching its cause. resource names what the
+// query works on, such as "character", for the client-facing message. Errors without a
+// matching kind are returned unchanged.
+func translateError(err error, resource string) error {
+	if err == nil {
+		return nil
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case pgErr.Code == "23503":
+			if referenced, ok := foreignKeyResource(pgErr.ConstraintName); ok {
+				return models.NotFound(referenced+" not found", err)
+			}
+			return models.NotFound("referenced resource not found", err)
+		case pgErr.Code == "23505":
+			if message, ok := uniqueViolationMessages[pgErr.ConstraintName]; ok {
+				return models.Conflict(message, err)
+			}
+			return models.Conflict(resource+" already exists", err)
+		case pgErr.Code == "23502", pgErr.Code == "23514", strings.HasPrefix(pgErr.Code, "22"):
+			return models.Invalid("invalid "+resource, err)
+		case strings.HasPrefix(pgErr.Code, "08"), strings.HasPrefix(pgErr.Code, "53"), strings.HasPrefix(pgErr.Code, "57P"):
+			return models.Unavailable("the database is unavailable", err)
+		}
+		return err
+	}
+
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return models.NotFound(resource+" not found", err)
+	case errors.As(err, &connectErr), errors.As(err, &netErr),
+		errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone),
+		errors.Is(err, context.DeadlineExceeded):
+		return models.Unavailable("the database is unavailable", err)
+	}
+
+	return err
+}
@@ -1,12 +1,17 @@
 package postgres_gallery
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"testing"
 
+	"dZev1/character-gallery/models"
 	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/events"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 func TestCreateCharacter_Success(t *testing.T) {
@@ -27,9 +32,10 @@ func TestCreateCharacter_Success(t *testing.T) {
 		WithArgs(1, char.Customization.Hair, char.Customization.Face,
 			char.Customization.Shirt, char.Customization.Pants, char.Customization.Shoes).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectPublish(mock, events.CharacterCreated, 1, `{"entity":"character","action":"created","id":1}`)
 	mock.ExpectCommit()
 
-	err := gallery.Create(char)
+	err := gallery.Create(context.Background(), char)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -51,7 +57,7 @@ func TestCreateCharacter_InsertError(t *testing.T) {
 		WillReturnError(errors.New("insert error"))
 	mock.ExpectRollback()
 
-	err := gallery.Create(char)
+	err := gallery.Create(context.Background(), char)
 	if err == nil {
 		t.Error("expected error, got nil")
 	}
@@ -87,7 +93,7 @@ func TestGet_Success(t *testing.T) {
 		WithArgs(charID).
 		WillReturnRows(custRows)
 
-	char, err := gallery.Get(charID)
+	char, err := gallery.Get(context.Background(), charID)
 
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
@@ -117,12 +123,12 @@ func TestGet_NotFound(t *testing.T) {
 
 	mock.ExpectQuery(`SELECT \* FROM characters`).
 		WithArgs(charID).
-		WillReturnError(errors.New("no rows"))
+		WillReturnError(sql.ErrNoRows)
 
-	char, err := gallery.Get(charID)
+	char, err := gallery.Get(context.Background(), charID)
 
-	if err == nil {
-		t.Error("expected error, got nil")
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("expected models.ErrNotFound, got %v", err)
 	}
 	if char != nil {
 		t.Error("expected nil character")
@@ -147,7 +153,7 @@ func TestGetAll_Success(t *testing.T) {
 		AddRow(2, "TestHero2", "type_b", "elf", "mage", 10, 14, 12, 15, 9, 13, 2, 3, 4, 5, 6)
 
 	mock.ExpectQuery(`SELECT`).
-		WithArgs(0).
+		WithArgs("", "", "", "", 20, 0).
 		WillReturnRows(rows)
 
 	countRows := sqlmock.NewRows([]string{"COUNT(*)"}).
@@ -155,7 +161,7 @@ func TestGetAll_Success(t *testing.T) {
 	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM characters`).
 		WillReturnRows(countRows)
 
-	chars, total, err := gallery.GetAll(0)
+	chars, total, err := gallery.GetAll(context.Background(), characters.CharacterFilter{}, 0, 20)
 
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
@@ -184,7 +190,7 @@ func TestGetAll_Empty(t *testing.T) {
 	})
 
 	mock.ExpectQuery(`SELECT`).
-		WithArgs(0).
+		WithArgs("", "", "", "", 20, 0).
 		WillReturnRows(rows)
 
 	countRows := sqlmock.NewRows([]string{"COUNT(*)"}).
@@ -193,7 +199,7 @@ func TestGetAll_Empty(t *testing.T) {
 	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM characters`).
 		WillReturnRows(countRows)
 
-	chars, total, err := gallery.GetAll(0)
+	chars, total, err := gallery.GetAll(context.Background(), characters.CharacterFilter{}, 0, 20)
 
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
@@ -213,6 +219,53 @@ func TestGetAll_Empty(t *testing.T) {
 	}
 }
 
+func TestGetAll_Filtered(t *testing.T) {
+	gallery, mock := setupMockDB(t)
+
+	rows := sqlmock.NewRows([]string{"id", "name", "body_type", "species", "class"}).
+		AddRow(2, "TestHero2", "type_b", "elf", "wizard")
+	mock.ExpectQuery(`SELECT (.+) WHERE (.+) ILIKE`).
+		WithArgs("hero", "", "elf", "wizard", 10, 10).
+		WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM characters c WHERE`).
+		WithArgs("hero", "", "elf", "wizard").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(11))
+
+	filter := characters.CharacterFilter{Name: "hero", Species: characters.Elf, Class: characters.Wizard}
+	chars, total, err := gallery.GetAll(context.Background(), filter, 10, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(chars) != 1 || total != 11 {
+		t.Errorf("expected 1 character of 11 matching, got %d of %d", len(chars), total)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetAll_Unavailable(t *testing.T) {
+	gallery, mock := setupMockDB(t)
+
+	mock.ExpectQuery(`SELECT`).
+		WithArgs("", "", "", "", 20, 0).
+		WillReturnError(&pgconn.PgError{Code: "08006"})
+
+	_, _, err := gallery.GetAll(context.Background(), characters.CharacterFilter{}, 0, 20)
+
+	if !errors.Is(err, models.ErrUnavailable) {
+		t.Errorf("expected models.ErrUnavailable, got %v", err)
+	}
+	if errors.Is(err, models.ErrNotFound) {
+		t.Error("an outage must not be reported as not found")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
 func TestUpdate_Success(t *testing.T) {
 	gallery, mock := setupMockDB(t)
 
@@ -230,9 +283,10 @@ func TestUpdate_Success(t *testing.T) {
 		WithArgs(char.Stats.Strength, char.Stats.Dexterity, char.Stats.Constitution,
 			char.Stats.Intelligence, char.Stats.Wisdom, char.Stats.Charisma, char.Stats.ID).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectPublish(mock, events.CharacterUpdated, 0, `{"entity":"character","action":"updated"}`)
 	mock.ExpectCommit()
 
-	err := gallery.Edit(char)
+	err := gallery.Edit(context.Background(), char)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -254,13 +308,16 @@ func TestUpdate_NotFound(t *testing.T) {
 		WillReturnResult(sqlmock.NewResult(0, 0))
 	mock.ExpectRollback()
 
-	err := gallery.Edit(char)
+	err := gallery.Edit(context.Background(), char)
 	if err == nil {
 		t.Error("expected error for non-existent character")
 	}
 	if !errors.Is(err, ErrCouldNotFind) {
 		t.Errorf("expected ErrCouldNotFind, got %v", err)
 	}
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("expected models.ErrNotFound, got %v", err)
+	}
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("unfulfilled expectations: %v", err)
@@ -276,9 +333,10 @@ func TestRemove_Success(t *testing.T) {
 	mock.ExpectExec(`DELETE FROM characters`).
 		WithArgs(charID).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectPublish(mock, events.CharacterDeleted, 1, `{"entity":"character","action":"deleted","id":1}`)
 	mock.ExpectCommit()
 
-	err := gallery.Remove(charID)
+	err := gallery.Remove(context.Background(), charID)
 
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
@@ -300,7 +358,7 @@ func TestRemove_NotFound(t *testing.T) {
 		WillReturnResult(sqlmock.NewResult(0, 0))
 	mock.ExpectRollback()
 
-	err := gallery.Remove(charID)
+	err := gallery.Remove(context.Background(), charID)
 
 	if err == nil {
 		t.Error("expected error for non-existent character")
@@ -308,6 +366,9 @@ func TestRemove_NotFound(t *testing.T) {
 	if !errors.Is(err, ErrCouldNotFind) {
 		t.Errorf("expected ErrCouldNotFind, got %v", err)
 	}
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("expected models.ErrNotFound, got %v", err)
+	}
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("unfulfilled expectations: %v", err)
@@ -321,7 +382,7 @@ func TestRemove_TransactionError(t *testing.T) {
 
 	mock.ExpectBegin().WillReturnError(errors.New("tx error"))
 
-	err := gallery.Remove(charID)
+	err := gallery.Remove(context.Background(), charID)
 
 	if err == nil {
 		t.Error("expected error")
@@ -334,3 +395,23 @@ func TestRemove_TransactionError(t *testing.T) {
 		t.Errorf("unfulfilled expectations: %v", err)
 	}
 }
+
+func TestCountCharacters(t *testing.T) {
+	gallery, mock := setupMockDB(t)
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM characters`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+
+	total, err := gallery.CountCharacters(context.Background())
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if total != 7 {
+		t.Errorf("expected 7, got %d", total)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -1,24 +1,38 @@
 package postgres_gallery
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 
+	"dZev1/character-gallery/models"
 	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/events"
 	"dZev1/character-gallery/models/inventory"
+
+	"github.com/jmoiron/sqlx"
 )
 
-func (cg *PostgresCharacterGallery) SeedItems(items []inventory.Item) error {
-	tx, err := cg.db.Beginx()
+func (cg *PostgresCharacterGallery) SeedItems(ctx context.Context, items []inventory.Item) error {
+	for _, item := range items {
+		if err := item.Validate(); err != nil {
+			return models.Invalid(fmt.Sprintf("item %s is invalid", item.Key()), err)
+		}
+	}
+
+	tx, err := cg.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrFailedInitializeTransaction, err)
+		return fmt.Errorf("%w: %w", ErrFailedInitializeTransaction, translateError(err, "inventory"))
 	}
 	defer tx.Rollback()
 
-	for _, item := range items {
-		err := cg.seedItemPool(tx, &item)
-		if err != nil {
-			fmt.Println("Error inserting item:", err)
+	// Numbered items go first so that the sequence is moved past their IDs before pack items
+	// are given one.
+	for i := range items {
+		if items[i].ID == 0 && items[i].Slug != "" {
+			continue
+		}
+		if err := cg.seedItemPool(ctx, tx, &items[i]); err != nil {
 			return err
 		}
 	}
@@ -26,33 +40,60 @@ func (cg *PostgresCharacterGallery) SeedItems(items []inventory.Item) error {
 	resetSeqQuery := `
         SELECT setval(pg_get_serial_sequence('items', 'id'), (SELECT MAX(id) FROM items));
     `
-	_, err = tx.Exec(resetSeqQuery)
+	_, err = tx.ExecContext(ctx, resetSeqQuery)
 	if err != nil {
-		return fmt.Errorf("Error reseteando la secuencia de IDs: %w", err)
+		return fmt.Errorf("could not reset the item ID sequence: %w", translateError(err, "item"))
+	}
+
+	for i := range items {
+		if items[i].ID != 0 || items[i].Slug == "" {
+			continue
+		}
+		if err := cg.seedPackItem(ctx, tx, &items[i]); err != nil {
+			return err
+		}
+	}
+
+	seeded := events.Change{Entity: events.EntityItemPool, Action: events.ActionSeeded}
+	if err = cg.publish(ctx, tx, seeded, events.SeededItems{Items: items}); err != nil {
+		return err
 	}
 
 	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("%w: %w", ErrFailedCommitTransaction, err)
+		return fmt.Errorf("%w: %w", ErrFailedCommitTransaction, translateError(err, "inventory"))
 	}
 
 	return nil
 }
 
-func (cg *PostgresCharacterGallery) AddItemToCharacter(characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) (*inventory.InventoryItem, error) {
-	tx, err := cg.db.Beginx()
+func (cg *PostgresCharacterGallery) AddItemToCharacter(ctx context.Context, characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) (*inventory.InventoryItem, error) {
+	tx, err := cg.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %w", ErrFailedInitializeTransaction, err)
+		return nil, fmt.Errorf("%w: %w", ErrFailedInitializeTransaction, translateError(err, "inventory"))
 	}
 	defer tx.Rollback()
 	
-	err = insertIntoCharacterInventory(tx, characterID, itemID, quantity)
+	item, err := cg.addItemToCharacter(ctx, tx, characterID, itemID, quantity)
 	if err != nil {
-		log.Print("Error luego de insert")
 		return nil, err
 	}
 
+	if err = tx.Commit(); err != nil {
+		return nil,fmt.Errorf("%w: %w", ErrFailedCommitTransaction, translateError(err, "inventory"))
+	}
+
+	return item, nil
+}
+
+func (cg *PostgresCharacterGallery) addItemToCharacter(ctx context.Context, tx *sqlx.Tx, characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) (*inventory.InventoryItem, error) {
+	err := insertIntoCharacterInventory(ctx, tx, characterID, itemID, quantity)
+	if err != nil {
+		slog.ErrorContext(ctx, "could not add item to inventory", "character_id", characterID, "item_id", itemID, "error", err)
+		return nil, translateError(err, "inventory item")
+	}
+
 	item := &inventory.InventoryItem{}
-	err = tx.Get(item, `
+	err = tx.GetContext(ctx, item, `
 		SELECT
 			i.id          AS "item.id",
 			i.name        AS "item.name",
@@ -72,48 +113,58 @@ func (cg *PostgresCharacterGallery) AddItemToCharacter(characterID characters.Ch
 		WHERE ci.character_id = $1 AND ci.item_id = $2;
 	`, characterID, itemID)
 	if err != nil {
-		return nil, fmt.Errorf("could not retrieve item after adding to character: %w", err)
+		return nil, fmt.Errorf("could not retrieve item after adding to character: %w", translateError(err, "inventory item"))
 	}
 
-	if err = tx.Commit(); err != nil {
-		return nil,fmt.Errorf("%w: %w", ErrFailedCommitTransaction, err)
+	err = cg.publish(ctx, tx, events.Change{Entity: events.EntityInventory, Action: events.ActionItemAdded, ID: uint64(characterID)}, events.InventoryChange{CharacterID: characterID, ItemID: itemID, Quantity: quantity, Entry: item})
+	if err != nil {
+		return nil, err
 	}
 
 	return item, nil
 }
 
-func (cg *PostgresCharacterGallery) RemoveItemFromCharacter(characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) error {
-	tx, err := cg.db.Beginx()
+func (cg *PostgresCharacterGallery) RemoveItemFromCharacter(ctx context.Context, characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) error {
+	tx, err := cg.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrFailedInitializeTransaction, err)
+		return fmt.Errorf("%w: %w", ErrFailedInitializeTransaction, translateError(err, "inventory"))
 	}
 	defer tx.Rollback()
 
-	currentQuantity, err := cg.selectCurrentQuantity(characterID, itemID)
+	err = cg.removeItemFromCharacter(ctx, tx, characterID, itemID, quantity)
 	if err != nil {
 		return err
 	}
 
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %w", ErrFailedCommitTransaction, translateError(err, "inventory"))
+	}
+
+	return nil
+}
+
+func (cg *PostgresCharacterGallery) removeItemFromCharacter(ctx context.Context, tx *sqlx.Tx, characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) error {
+	currentQuantity, err := selectCurrentQuantity(ctx, tx, characterID, itemID)
+	if err != nil {
+		return translateError(err, "inventory item")
+	}
+
 	if currentQuantity > quantity {
-		err = updateItemQuantity(tx, quantity, characterID, itemID)
+		err = updateItemQuantity(ctx, tx, quantity, characterID, itemID)
 		if err != nil {
-			return err
+			return translateError(err, "inventory item")
 		}
 	} else {
-		err = deleteItemFromCharacter(tx, characterID, itemID)
+		err = deleteItemFromCharacter(ctx, tx, characterID, itemID)
 		if err != nil {
-			return err
+			return translateError(err, "inventory item")
 		}
 	}
 
-	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("%w: %w", ErrFailedCommitTransaction, err)
-	}
-
-	return nil
+	return cg.publish(ctx, tx, events.Change{Entity: events.EntityInventory, Action: events.ActionItemRemoved, ID: uint64(characterID)}, events.InventoryChange{CharacterID: characterID, ItemID: itemID, Quantity: quantity})
 }
 
-func (cg *PostgresCharacterGallery) GetCharacterInventory(characterID characters.CharacterID) ([]inventory.InventoryItem, error) {
+func (cg *PostgresCharacterGallery) GetCharacterInventory(ctx context.Context, characterID characters.CharacterID) ([]inventory.InventoryItem, error) {
 	query := `
 		SELECT
 			i.id          AS "item.id",
@@ -136,61 +187,128 @@ func (cg *PostgresCharacterGallery) GetCharacterInventory(characterID characters
 	`
 
 	var characterInventory []inventory.InventoryItem
-	err := cg.db.Select(&characterInventory, query, characterID)
+	err := cg.db.SelectContext(ctx, &characterInventory, query, characterID)
 	if err != nil {
-		fmt.Println("Error al seleccionar el inventario del personaje")
-		return nil, fmt.Errorf("%w: %w", ErrFailedSelectCharacterInventory, err)
+		slog.ErrorContext(ctx, "could not select character inventory", "character_id", characterID, "error", err)
+		return nil, fmt.Errorf("%w: %w", ErrFailedSelectCharacterInventory, translateError(err, "inventory"))
 	}
 	return characterInventory, nil
 }
 
-func (cg *PostgresCharacterGallery) DisplayPoolItems() ([]inventory.Item, error) {
+func (cg *PostgresCharacterGallery) GetCharacterInventories(ctx context.Context, characterIDs []characters.CharacterID) (map[characters.CharacterID][]inventory.InventoryItem, error) {
+	inventories := make(map[characters.CharacterID][]inventory.InventoryItem)
+	if len(characterIDs) == 0 {
+		return inventories, nil
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT
+			ci.character_id,
+			i.id          AS "item.id",
+			i.name        AS "item.name",
+			i.type        AS "item.type",
+			i.description AS "item.description",
+			i.equippable  AS "item.equippable",
+			i.rarity      AS "item.rarity",
+			i.damage      AS "item.damage",
+			i.defense     AS "item.defense",
+			i.heal_amount AS "item.heal_amount",
+			i.mana_cost   AS "item.mana_cost",
+			i.duration    AS "item.duration",
+			ci.quantity,
+			ci.is_equipped
+		FROM items i
+		JOIN inventory ci ON ci.item_id = i.id
+		WHERE ci.character_id IN (?)
+		ORDER BY ci.character_id, i.id
+	`, characterIDs)
+	if err != nil {
+		return nil, fmt.Errorf("could not build inventory query: %w", err)
+	}
+
+	var rows []struct {
+		CharacterID characters.CharacterID `db:"character_id"`
+		inventory.InventoryItem
+	}
+	err = cg.db.SelectContext(ctx, &rows, cg.db.Rebind(query), args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "could not select character inventories", "characters", len(characterIDs), "error", err)
+		return nil, fmt.Errorf("%w: %w", ErrFailedSelectCharacterInventory, translateError(err, "inventory"))
+	}
+
+	for _, row := range rows {
+		inventories[row.CharacterID] = append(inventories[row.CharacterID], row.InventoryItem)
+	}
+	return inventories, nil
+}
+
+func (cg *PostgresCharacterGallery) DisplayPoolItems(ctx context.Context, filter inventory.ItemFilter) ([]inventory.Item, error) {
 	query := `
 		SELECT *
 		FROM items
+		WHERE $1 = '' OR pack = $1
 		ORDER BY id;
 	`
 
 	var items []inventory.Item
-	err := cg.db.Select(&items, query)
+	err := cg.db.SelectContext(ctx, &items, query, filter.Pack)
 	if err != nil {
-		return nil, fmt.Errorf("could not retrieve items from pool: %v", err)
+		return nil, fmt.Errorf("could not retrieve items from pool: %w", translateError(err, "item"))
 	}
 
 	return items, nil
 }
 
-func (cg *PostgresCharacterGallery) DisplayItem(itemID inventory.ItemID) (*inventory.Item, error) {
+func (cg *PostgresCharacterGallery) DisplayItem(ctx context.Context, itemID inventory.ItemID) (*inventory.Item, error) {
 	query := `
 		SELECT *
 		FROM items
 		WHERE id = $1; 
 	`
 	item := &inventory.Item{}
-	err := cg.db.Get(item, query, itemID)
+	err := cg.db.GetContext(ctx, item, query, itemID)
 
 	if err != nil {
-		return nil, fmt.Errorf("could not retrieve item from item pool: %v", err)
+		return nil, fmt.Errorf("could not retrieve item from item pool: %w", translateError(err, "item"))
 	}
 
 	return item, nil
 }
 
-func (cg *PostgresCharacterGallery) CreateItem(item *inventory.Item) error {
-	tx, err := cg.db.Beginx()
+func (cg *PostgresCharacterGallery) CountItems(ctx context.Context) (uint64, error) {
+	var total uint64
+	err := cg.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM items`)
+	if err != nil {
+		return 0, fmt.Errorf("could not count items in pool: %w", translateError(err, "item"))
+	}
+
+	return total, nil
+}
+
+func (cg *PostgresCharacterGallery) CreateItem(ctx context.Context, item *inventory.Item) error {
+	tx, err := cg.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrFailedInitializeTransaction, err)
+		return fmt.Errorf("%w: %w", ErrFailedInitializeTransaction, translateError(err, "inventory"))
 	}
 	defer tx.Rollback()
 
-	err = cg.insertIntoItemPool(tx, item)
+	err = cg.createItem(ctx, tx, item)
 	if err != nil {
 		return err
 	}
 
 	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("%w: %w", ErrFailedCommitTransaction, err)
+		return fmt.Errorf("%w: %w", ErrFailedCommitTransaction, translateError(err, "inventory"))
 	}
 
 	return nil
 }
+
+func (cg *PostgresCharacterGallery) createItem(ctx context.Context, tx *sqlx.Tx, item *inventory.Item) error {
+	err := cg.insertIntoItemPool(ctx, tx, item)
+	if err != nil {
+		return err
+	}
+
+	return cg.publish(ctx, tx, events.Change{Entity: events.EntityItem, Action: events.ActionCreated, ID: uint64(item.ID)}, item)
+}
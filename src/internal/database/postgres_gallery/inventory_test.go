@@ -1,13 +1,17 @@
 package postgres_gallery
 
 import (
+	"context"
 	"errors"
 	"testing"
 
+	"dZev1/character-gallery/models"
 	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/events"
 	"dZev1/character-gallery/models/inventory"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 func uint64Ptr(i uint64) *uint64 {
@@ -27,13 +31,14 @@ func TestSeedItems_Success(t *testing.T) {
 		mock.ExpectExec(`INSERT INTO items`).
 			WithArgs(item.ID, item.Name, item.Type, item.Description, item.Equippable, item.Rarity,
 				item.Damage, item.Defense, item.HealAmount, item.ManaCost, item.Duration,
-				item.Cooldown, item.Capacity).
+				item.Cooldown, item.Capacity, item.Pack, item.Slug).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 	}
 	mock.ExpectExec(`SELECT setval`).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectPublish(mock, events.ItemPoolSeeded, 0, `{"entity":"item_pool","action":"seeded"}`)
 	mock.ExpectCommit()
 
-	err := gallery.SeedItems(items)
+	err := gallery.SeedItems(context.Background(), items)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -54,7 +59,7 @@ func TestSeedItems_TransactionError(t *testing.T) {
 
 	mock.ExpectBegin().WillReturnError(errors.New("tx error"))
 
-	err := gallery.SeedItems(items)
+	err := gallery.SeedItems(context.Background(), items)
 	if err == nil {
 		t.Error("expected error, got nil")
 	}
@@ -67,6 +72,71 @@ func TestSeedItems_TransactionError(t *testing.T) {
 	}
 }
 
+func TestSeedItems_PackItems(t *testing.T) {
+	gallery, mock := setupMockDB(t)
+
+	items := []inventory.Item{
+		{Name: "Buckler", Type: inventory.Shield, Description: "A small shield", Rarity: 1, Pack: "core", Slug: "buckler"},
+		{ID: 4, Name: "Shield", Type: inventory.Shield, Description: "Made from metal", Rarity: 2},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO items`).
+		WithArgs(4, "Shield", inventory.Shield, "Made from metal", false, 2,
+			nil, nil, nil, nil, nil, nil, nil, "", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`SELECT setval`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare(`ON CONFLICT \(pack, slug\)`).
+		ExpectQuery().
+		WithArgs("Buckler", inventory.Shield, "A small shield", false, 1,
+			nil, nil, nil, nil, nil, nil, nil, "core", "buckler").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12))
+	expectPublish(mock, events.ItemPoolSeeded, 0, `{"entity":"item_pool","action":"seeded"}`)
+	mock.ExpectCommit()
+
+	if err := gallery.SeedItems(context.Background(), items); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if items[0].ID != 12 {
+		t.Errorf("expected the pack item to get ID 12, got %d", items[0].ID)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSeedItems_InvalidItem(t *testing.T) {
+	gallery, mock := setupMockDB(t)
+
+	items := []inventory.Item{
+		{ID: 8, Name: "Shortbow", Type: inventory.Weapon, Description: "A bow", Damage: uint64Ptr(10)},
+	}
+
+	err := gallery.SeedItems(context.Background(), items)
+	if !errors.Is(err, models.ErrValidation) {
+		t.Errorf("expected models.ErrValidation, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSeedItems_InvalidPackItem(t *testing.T) {
+	gallery, _ := setupMockDB(t)
+
+	items := []inventory.Item{
+		{Pack: "core", Slug: "shortbow", Name: "Shortbow", Type: inventory.Weapon, Description: "A bow", Damage: uint64Ptr(10)},
+	}
+
+	err := gallery.SeedItems(context.Background(), items)
+	var modelErr *models.Error
+	if !errors.As(err, &modelErr) || modelErr.Message != "item core/shortbow is invalid" {
+		t.Errorf("expected the pack item to be named, got %v", err)
+	}
+}
+
 func TestDisplayPoolItems_Success(t *testing.T) {
 	gallery, mock := setupMockDB(t)
 
@@ -79,7 +149,7 @@ func TestDisplayPoolItems_Success(t *testing.T) {
 
 	mock.ExpectQuery(`SELECT \*`).WillReturnRows(rows)
 
-	items, err := gallery.DisplayPoolItems()
+	items, err := gallery.DisplayPoolItems(context.Background(), inventory.ItemFilter{})
 
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
@@ -99,6 +169,27 @@ func TestDisplayPoolItems_Success(t *testing.T) {
 	}
 }
 
+func TestDisplayPoolItems_ByPack(t *testing.T) {
+	gallery, mock := setupMockDB(t)
+
+	rows := sqlmock.NewRows([]string{"id", "name", "type", "description", "equippable", "rarity", "pack", "slug"}).
+		AddRow(12, "Buckler", "shield", "A small shield", false, 1, "core", "buckler")
+
+	mock.ExpectQuery(`WHERE \$1 = '' OR pack = \$1`).WithArgs("core").WillReturnRows(rows)
+
+	items, err := gallery.DisplayPoolItems(context.Background(), inventory.ItemFilter{Pack: "core"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 1 || items[0].Pack != "core" || items[0].Slug != "buckler" {
+		t.Errorf("unexpected items %+v", items)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
 func TestDisplayPoolItems_Empty(t *testing.T) {
 	gallery, mock := setupMockDB(t)
 
@@ -109,7 +200,7 @@ func TestDisplayPoolItems_Empty(t *testing.T) {
 
 	mock.ExpectQuery(`SELECT \*`).WillReturnRows(rows)
 
-	items, err := gallery.DisplayPoolItems()
+	items, err := gallery.DisplayPoolItems(context.Background(), inventory.ItemFilter{})
 
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
@@ -128,7 +219,7 @@ func TestDisplayPoolItems_Error(t *testing.T) {
 
 	mock.ExpectQuery(`SELECT \*`).WillReturnError(errors.New("db error"))
 
-	items, err := gallery.DisplayPoolItems()
+	items, err := gallery.DisplayPoolItems(context.Background(), inventory.ItemFilter{})
 
 	if err == nil {
 		t.Error("expected error, got nil")
@@ -154,7 +245,7 @@ func TestDisplayItem_Success(t *testing.T) {
 
 	mock.ExpectQuery(`SELECT \*`).WithArgs(itemID).WillReturnRows(rows)
 
-	item, err := gallery.DisplayItem(itemID)
+	item, err := gallery.DisplayItem(context.Background(), itemID)
 
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
@@ -181,7 +272,7 @@ func TestDisplayItem_NotFound(t *testing.T) {
 
 	mock.ExpectQuery(`SELECT \*`).WithArgs(itemID).WillReturnError(errors.New("no rows"))
 
-	item, err := gallery.DisplayItem(itemID)
+	item, err := gallery.DisplayItem(context.Background(), itemID)
 
 	if err == nil {
 		t.Error("expected error, got nil")
@@ -210,7 +301,7 @@ func TestGetCharacterInventory_Success(t *testing.T) {
 
 	mock.ExpectQuery(`SELECT`).WithArgs(charID).WillReturnRows(rows)
 
-	inv, err := gallery.GetCharacterInventory(charID)
+	inv, err := gallery.GetCharacterInventory(context.Background(), charID)
 
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
@@ -233,6 +324,38 @@ func TestGetCharacterInventory_Success(t *testing.T) {
 	}
 }
 
+func TestGetCharacterInventories_Success(t *testing.T) {
+	gallery, mock := setupMockDB(t)
+
+	rows := sqlmock.NewRows([]string{
+		"character_id",
+		"item.id", "item.name", "item.type", "item.description", "item.equippable", "item.rarity",
+		"item.damage", "item.defense", "item.heal_amount", "item.mana_cost", "item.duration",
+		"quantity", "is_equipped",
+	}).
+		AddRow(1, 1, "Sword", "weapon", "A sharp sword", true, 3, 50, nil, nil, nil, nil, 1, true).
+		AddRow(1, 2, "Potion", "potion", "Heals", false, 1, nil, nil, 60, nil, nil, 5, false).
+		AddRow(3, 2, "Potion", "potion", "Heals", false, 1, nil, nil, 60, nil, nil, 2, false)
+
+	mock.ExpectQuery(`SELECT (.+) WHERE ci.character_id IN \(\?, \?, \?\)`).WithArgs(1, 2, 3).WillReturnRows(rows)
+
+	inventories, err := gallery.GetCharacterInventories(context.Background(), []characters.CharacterID{1, 2, 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(inventories[1]) != 2 || len(inventories[2]) != 0 || len(inventories[3]) != 1 {
+		t.Fatalf("expected the items grouped by character, got %+v", inventories)
+	}
+	if inventories[3][0].Item.Name != "Potion" || inventories[3][0].Quantity != 2 {
+		t.Errorf("unexpected entry %+v", inventories[3][0])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
 func TestGetCharacterInventory_Empty(t *testing.T) {
 	gallery, mock := setupMockDB(t)
 
@@ -246,7 +369,7 @@ func TestGetCharacterInventory_Empty(t *testing.T) {
 
 	mock.ExpectQuery(`SELECT`).WithArgs(charID).WillReturnRows(rows)
 
-	inv, err := gallery.GetCharacterInventory(charID)
+	inv, err := gallery.GetCharacterInventory(context.Background(), charID)
 
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
@@ -267,7 +390,7 @@ func TestGetCharacterInventory_Error(t *testing.T) {
 
 	mock.ExpectQuery(`SELECT`).WithArgs(charID).WillReturnError(errors.New("db error"))
 
-	inv, err := gallery.GetCharacterInventory(charID)
+	inv, err := gallery.GetCharacterInventory(context.Background(), charID)
 
 	if err == nil {
 		t.Error("expected error, got nil")
@@ -301,9 +424,10 @@ func TestAddItemToCharacter_Success(t *testing.T) {
 		WithArgs(charID, itemID).
 		WillReturnRows(sqlmock.NewRows([]string{"item.id", "item.name", "item.type", "item.description", "item.equippable", "item.rarity", "item.damage", "item.defense", "item.heal_amount", "item.mana_cost", "item.duration", "quantity", "is_equipped"}).
 			AddRow(1, "Sword", "weapon", "A sharp sword", true, 3, 50, nil, nil, nil, nil, 1, false))
+	expectPublish(mock, events.InventoryItemAdded, 1, `{"entity":"inventory","action":"item_added","id":1}`)
 	mock.ExpectCommit()
 
-	_, err := gallery.AddItemToCharacter(charID, itemID, 1)
+	_, err := gallery.AddItemToCharacter(context.Background(), charID, itemID, 1)
 
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
@@ -332,9 +456,10 @@ func TestAddItemToCharacter_UpdateExisting(t *testing.T) {
 		WithArgs(charID, itemID).
 		WillReturnRows(sqlmock.NewRows([]string{"item.id", "item.name", "item.type", "item.description", "item.equippable", "item.rarity", "item.damage", "item.defense", "item.heal_amount", "item.mana_cost", "item.duration", "quantity", "is_equipped"}).
 			AddRow(1, "Sword", "weapon", "A sharp sword", true, 3, 50, nil, nil, nil, nil, 3, false))
+	expectPublish(mock, events.InventoryItemAdded, 1, `{"entity":"inventory","action":"item_added","id":1}`)
 	mock.ExpectCommit()
 
-	_, err := gallery.AddItemToCharacter(charID, itemID, 3)
+	_, err := gallery.AddItemToCharacter(context.Background(), charID, itemID, 3)
 
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
@@ -345,6 +470,37 @@ func TestAddItemToCharacter_UpdateExisting(t *testing.T) {
 	}
 }
 
+func TestAddItemToCharacter_MissingCharacter(t *testing.T) {
+	gallery, mock := setupMockDB(t)
+
+	charID := characters.CharacterID(999)
+	itemID := inventory.ItemID(1)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM inventory`).
+		WithArgs(itemID, charID).
+		WillReturnRows(sqlmock.NewRows([]string{"character_id", "item_id", "quantity", "is_equipped"}))
+	// Databases created earlier hold numbered copies of the key, added each time schema.sql ran.
+	mock.ExpectExec(`INSERT INTO inventory`).
+		WithArgs(charID, itemID, uint8(1)).
+		WillReturnError(&pgconn.PgError{Code: "23503", ConstraintName: "inventory_character_id_fkey2"})
+	mock.ExpectRollback()
+
+	_, err := gallery.AddItemToCharacter(context.Background(), charID, itemID, 1)
+
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("expected models.ErrNotFound, got %v", err)
+	}
+	var modelErr *models.Error
+	if !errors.As(err, &modelErr) || modelErr.Message != "character not found" {
+		t.Errorf("expected the missing character to be named, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
 func TestAddItemToCharacter_TransactionError(t *testing.T) {
 	gallery, mock := setupMockDB(t)
 
@@ -353,7 +509,7 @@ func TestAddItemToCharacter_TransactionError(t *testing.T) {
 
 	mock.ExpectBegin().WillReturnError(errors.New("tx error"))
 
-	_, err := gallery.AddItemToCharacter(charID, itemID, 1)
+	_, err := gallery.AddItemToCharacter(context.Background(), charID, itemID, 1)
 
 	if err == nil {
 		t.Error("expected error")
@@ -380,9 +536,10 @@ func TestRemoveItemFromCharacter_ReduceQuantity(t *testing.T) {
 	mock.ExpectExec(`UPDATE inventory`).
 		WithArgs(uint8(2), charID, itemID).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectPublish(mock, events.InventoryItemRemoved, 1, `{"entity":"inventory","action":"item_removed","id":1}`)
 	mock.ExpectCommit()
 
-	err := gallery.RemoveItemFromCharacter(charID, itemID, 2)
+	err := gallery.RemoveItemFromCharacter(context.Background(), charID, itemID, 2)
 
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
@@ -406,9 +563,10 @@ func TestRemoveItemFromCharacter_DeleteItem(t *testing.T) {
 	mock.ExpectExec(`DELETE FROM inventory`).
 		WithArgs(charID, itemID).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectPublish(mock, events.InventoryItemRemoved, 1, `{"entity":"inventory","action":"item_removed","id":1}`)
 	mock.ExpectCommit()
 
-	err := gallery.RemoveItemFromCharacter(charID, itemID, 5)
+	err := gallery.RemoveItemFromCharacter(context.Background(), charID, itemID, 5)
 
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
@@ -427,7 +585,7 @@ func TestRemoveItemFromCharacter_TransactionError(t *testing.T) {
 
 	mock.ExpectBegin().WillReturnError(errors.New("tx error"))
 
-	err := gallery.RemoveItemFromCharacter(charID, itemID, 1)
+	err := gallery.RemoveItemFromCharacter(context.Background(), charID, itemID, 1)
 
 	if err == nil {
 		t.Error("expected error")
@@ -459,15 +617,35 @@ func TestCreateItem_Success(t *testing.T) {
 		WithArgs(item.Name, item.Type, item.Description, item.Equippable, item.Rarity,
 			item.Damage, item.Defense, item.HealAmount, item.ManaCost, item.Duration, item.Capacity).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectPublish(mock, events.ItemCreated, 0, `{"entity":"item","action":"created","id":1}`)
 	mock.ExpectCommit()
 
-	err := gallery.CreateItem(item)
+	err := gallery.CreateItem(context.Background(), item)
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+func TestCountItems(t *testing.T) {
+	gallery, mock := setupMockDB(t)
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM items`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+
+	total, err := gallery.CountItems(context.Background())
 
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
+	if total != 12 {
+		t.Errorf("expected 12, got %d", total)
+	}
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("unfulfilled expectations: %v", err)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,212 @@
+package postgres_gallery
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/events"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jmoiron/sqlx"
+)
+
+// ChangesChannel is the channel every committed write is announced on.
+const ChangesChannel = "gallery_changes"
+
+// EventsChannel is the channel the IDs of delivered outbox events are broadcast on.
+const EventsChannel = "gallery_events"
+
+// notifier announces the writes of the transactions it is handed. NOTIFY is transactional, so a
+// notice is only delivered once its write is committed, and never for a rolled back one.
+type notifier struct {
+	origin string
+}
+
+func newOrigin() string {
+	origin := make([]byte, 8)
+	rand.Read(origin)
+	return hex.EncodeToString(origin)
+}
+
+func (n notifier) notify(ctx context.Context, tx *sqlx.Tx, change events.Change) error {
+	change.Origin = n.origin
+
+	payload, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("could not encode change: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_notify($1, $2)`, ChangesChannel, string(payload)); err != nil {
+		return fmt.Errorf("could not notify change: %w", translateError(err, string(change.Entity)))
+	}
+	return nil
+}
+
+// Reconnection delays of a Listener: the first retry waits listenRetryMin, and every later one
+// twice as long, up to listenRetryMax.
+const (
+	listenRetryMin = time.Second
+	listenRetryMax = time.Minute
+)
+
+// Listener holds a connection of its own listening on ChangesChannel and EventsChannel. It hands
+// the changes made by other instances and the events broadcast by any instance to its handlers,
+// one at a time. A lost connection is reopened with backoff.
+type Listener struct {
+	connStr   string
+	origin    string
+	changed   func(events.Change)
+	delivered func(*events.Event)
+	retryMin  time.Duration
+	retryMax  time.Duration
+
+	// pid is the backend of the current connection, so that tests can drop it.
+	pid atomic.Uint32
+
+	cancel    context.CancelFunc
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Listen starts handing the changes committed by other instances to changed, and the events
+// broadcast by any instance to delivered, until the gallery is closed.
+func (cg *PostgresCharacterGallery) Listen(changed func(events.Change), delivered func(*events.Event)) {
+	cg.listener = newListener(cg.connStr, cg.notifier.origin, changed, delivered, listenRetryMin, listenRetryMax)
+}
+
+func newListener(connStr, origin string, changed func(events.Change), delivered func(*events.Event), retryMin, retryMax time.Duration) *Listener {
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &Listener{
+		connStr:   connStr,
+		origin:    origin,
+		changed:   changed,
+		delivered: delivered,
+		retryMin:  retryMin,
+		retryMax:  retryMax,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	go l.run(ctx)
+	return l
+}
+
+func (l *Listener) run(ctx context.Context) {
+	defer close(l.done)
+
+	delay := l.retryMin
+	for attempt := 0; ; attempt++ {
+		connected, err := l.listen(ctx, attempt > 0)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			delay = l.retryMin
+		}
+		slog.Warn("lost the change notification connection, reconnecting", "in", delay, "error", err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		if !connected {
+			delay = min(delay*2, l.retryMax)
+		}
+	}
+}
+
+// listen connects and hands notices to the handlers until the connection fails or ctx is done.
+// When reconnecting, the handlers are told that changes may have been missed once it listens
+// again.
+func (l *Listener) listen(ctx context.Context, reconnecting bool) (bool, error) {
+	conn, err := pgx.Connect(ctx, l.connStr)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close(context.Background())
+
+	for _, channel := range []string{ChangesChannel, EventsChannel} {
+		if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+			return false, err
+		}
+	}
+	l.pid.Store(conn.PgConn().PID())
+	slog.Info("listening for changes made by other instances", "channels", []string{ChangesChannel, EventsChannel})
+
+	if reconnecting {
+		l.handle(events.Change{Action: events.ActionMissed})
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return true, err
+		}
+
+		if notification.Channel == EventsChannel {
+			event, err := loadEvent(ctx, conn, notification.Payload)
+			if err != nil {
+				slog.Error("could not load broadcast event", "event_id", notification.Payload, "error", err)
+				continue
+			}
+			if l.delivered != nil {
+				l.delivered(event)
+			}
+			continue
+		}
+
+		var change events.Change
+		if err := json.Unmarshal([]byte(notification.Payload), &change); err != nil {
+			slog.Error("could not decode change notification", "payload", notification.Payload, "error", err)
+			continue
+		}
+		if change.Origin == l.origin {
+			continue
+		}
+		l.handle(change)
+	}
+}
+
+func (l *Listener) handle(change events.Change) {
+	if l.changed != nil {
+		l.changed(change)
+	}
+}
+
+// loadEvent reads a broadcast event back from the outbox, since notices are too small to carry
+// its data.
+func loadEvent(ctx context.Context, conn *pgx.Conn, id string) (*events.Event, error) {
+	var (
+		eventType   string
+		characterID int64
+		data        string
+	)
+	event := &events.Event{ID: id}
+	err := conn.QueryRow(ctx, `
+		SELECT event_type, character_id, data, created_at
+		FROM outbox
+		WHERE event_id = $1
+	`, id).Scan(&eventType, &characterID, &data, &event.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	event.Type = events.EventType(eventType)
+	event.CharacterID = characters.CharacterID(characterID)
+	event.Data = json.RawMessage(data)
+	return event, nil
+}
+
+// Close stops listening. A change being handled is finished first.
+func (l *Listener) Close() {
+	l.closeOnce.Do(l.cancel)
+	<-l.done
+}
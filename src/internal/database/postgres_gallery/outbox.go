@@ -0,0 +1,148 @@
+package postgres_gallery
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/events"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type PGOutboxStore struct {
+	db *sqlx.DB
+}
+
+func NewOutboxStore(db *sqlx.DB) events.OutboxStore {
+	return &PGOutboxStore{
+		db: db,
+	}
+}
+
+// publish writes the event reporting a write to the outbox and announces the change, both in the
+// transaction of the write, so that the event is kept exactly when the write is.
+func (n notifier) publish(ctx context.Context, tx *sqlx.Tx, change events.Change, data any) error {
+	event, err := events.NewEvent(change.EventType(), data)
+	if err != nil {
+		return fmt.Errorf("could not build event: %w", err)
+	}
+	if change.Entity == events.EntityCharacter || change.Entity == events.EntityInventory {
+		event.CharacterID = characters.CharacterID(change.ID)
+	}
+
+	query := `
+		INSERT INTO outbox (event_id, event_type, character_id, data, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err = tx.ExecContext(ctx, query, event.ID, event.Type, event.CharacterID, string(event.Data), event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("could not write event to the outbox: %w", translateError(err, "event"))
+	}
+
+	return n.notify(ctx, tx, change)
+}
+
+type outboxRow struct {
+	ID          int64                  `db:"id"`
+	EventID     string                 `db:"event_id"`
+	EventType   events.EventType       `db:"event_type"`
+	CharacterID characters.CharacterID `db:"character_id"`
+	Data        string                 `db:"data"`
+	CreatedAt   time.Time              `db:"created_at"`
+	Attempts    int                    `db:"attempts"`
+	Sinks       string                 `db:"delivered_sinks"`
+}
+
+func (r outboxRow) event() *events.OutboxEvent {
+	event := &events.OutboxEvent{
+		Event: &events.Event{
+			ID:          r.EventID,
+			Type:        r.EventType,
+			CreatedAt:   r.CreatedAt,
+			Data:        json.RawMessage(r.Data),
+			CharacterID: r.CharacterID,
+		},
+		Attempts: r.Attempts,
+	}
+	if r.Sinks != "" {
+		event.Sinks = strings.Split(r.Sinks, ",")
+	}
+	return event
+}
+
+func (s *PGOutboxStore) Dispatch(ctx context.Context, limit int, lease time.Duration, deliver func(context.Context, *events.OutboxEvent) events.OutboxOutcome) (int, error) {
+	// Events are claimed and committed before they are delivered, so that no row stays locked
+	// while sinks are called. SKIP LOCKED lets dispatchers of other replicas claim the next
+	// events instead of waiting, and pushing next_attempt_at out hides the claimed ones until
+	// the lease ends.
+	query := `
+		UPDATE outbox
+		SET next_attempt_at = NOW() + make_interval(secs => $2::float8)
+		WHERE id IN (
+			SELECT id FROM outbox
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, event_id, event_type, character_id, data, created_at, attempts, delivered_sinks
+	`
+	var rows []outboxRow
+	if err := s.db.SelectContext(ctx, &rows, query, limit, lease.Seconds()); err != nil {
+		return 0, translateError(err, "event")
+	}
+	slices.SortFunc(rows, func(a, b outboxRow) int { return cmp.Compare(a.ID, b.ID) })
+
+	query = `
+		UPDATE outbox
+		SET status = $2,
+			attempts = attempts + 1,
+			delivered_sinks = $3,
+			last_error = $4,
+			next_attempt_at = NOW() + make_interval(secs => $5::float8),
+			delivered_at = CASE WHEN $2 = 'delivered' THEN NOW() END
+		WHERE id = $1
+	`
+	delivered := 0
+	for _, row := range rows {
+		outcome := deliver(ctx, row.event())
+		_, err := s.db.ExecContext(ctx, query, row.ID, outcome.Status, strings.Join(outcome.Sinks, ","), outcome.Error, outcome.RetryIn.Seconds())
+		if err != nil {
+			return delivered, translateError(err, "event")
+		}
+		if outcome.Status == events.OutboxDelivered {
+			delivered++
+		}
+	}
+
+	return delivered, nil
+}
+
+func (s *PGOutboxStore) Prune(ctx context.Context, olderThan time.Duration) (int64, error) {
+	// The cutoff is taken from the database clock, the one that fills the compared columns.
+	query := `
+		DELETE FROM outbox
+		WHERE delivered_at < NOW() - make_interval(secs => $1::float8)
+			OR (status = 'dead' AND created_at < NOW() - make_interval(secs => $1::float8))
+	`
+	result, err := s.db.ExecContext(ctx, query, olderThan.Seconds())
+	if err != nil {
+		return 0, translateError(err, "event")
+	}
+
+	return result.RowsAffected()
+}
+
+func (s *PGOutboxStore) Broadcast(ctx context.Context, event *events.Event) error {
+	// Notices are too small for the data of some events, so listeners read it back by ID.
+	if _, err := s.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, EventsChannel, event.ID); err != nil {
+		return fmt.Errorf("could not broadcast event: %w", translateError(err, "event"))
+	}
+	return nil
+}
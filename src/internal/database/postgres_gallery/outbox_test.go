@@ -0,0 +1,87 @@
+package postgres_gallery
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"dZev1/character-gallery/models/events"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+func setupMockOutboxStore(t *testing.T) (*PGOutboxStore, sqlmock.Sqlmock) {
+	t.Helper()
+
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+
+	return &PGOutboxStore{db: sqlx.NewDb(mockDB, "sqlmock")}, mock
+}
+
+func TestDispatch_RecordsOutcomes(t *testing.T) {
+	store, mock := setupMockOutboxStore(t)
+
+	// RETURNING gives no order, so the claimed events come back shuffled.
+	rows := sqlmock.NewRows([]string{"id", "event_id", "event_type", "character_id", "data", "created_at", "attempts", "delivered_sinks"}).
+		AddRow(2, "evt_2", "item.created", 0, `{"id":7}`, time.Now(), 2, "log,stream").
+		AddRow(1, "evt_1", "character.deleted", 4, `{"id":4}`, time.Now(), 0, "")
+	mock.ExpectQuery(`UPDATE outbox\s+SET next_attempt_at = (.+) WHERE id IN \(\s+SELECT id FROM outbox\s+WHERE status = 'pending' AND next_attempt_at <= NOW\(\) (.+) FOR UPDATE SKIP LOCKED\s+\)\s+RETURNING`).
+		WithArgs(10, float64(60)).
+		WillReturnRows(rows)
+	mock.ExpectExec(`UPDATE outbox\s+SET status = \$2`).
+		WithArgs(1, events.OutboxDelivered, "log,stream,webhooks", "", float64(0)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE outbox\s+SET status = \$2`).
+		WithArgs(2, events.OutboxPending, "log,stream", "webhooks: sink down", float64(120)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	var handed []*events.OutboxEvent
+	delivered, err := store.Dispatch(context.Background(), 10, time.Minute, func(ctx context.Context, event *events.OutboxEvent) events.OutboxOutcome {
+		handed = append(handed, event)
+		if event.Type == events.ItemCreated {
+			return events.OutboxOutcome{Status: events.OutboxPending, Sinks: event.Sinks, Error: "webhooks: sink down", RetryIn: 2 * time.Minute}
+		}
+		return events.OutboxOutcome{Status: events.OutboxDelivered, Sinks: []string{"log", "stream", "webhooks"}}
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if delivered != 1 || len(handed) != 2 {
+		t.Fatalf("expected 2 events handed over and 1 delivered, got %d of %+v", delivered, handed)
+	}
+	if handed[0].ID != "evt_1" || handed[0].CharacterID != 4 || string(handed[0].Data) != `{"id":4}` || handed[0].Sinks != nil {
+		t.Errorf("unexpected event %+v", handed[0])
+	}
+	if handed[1].Attempts != 2 || !slices.Equal(handed[1].Sinks, []string{"log", "stream"}) {
+		t.Errorf("expected the earlier attempts of %s, got %+v", handed[1].ID, handed[1])
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPrune_DeletesDeliveredEvents(t *testing.T) {
+	store, mock := setupMockOutboxStore(t)
+
+	mock.ExpectExec(`DELETE FROM outbox\s+WHERE delivered_at < NOW\(\) - make_interval\(secs => \$1::float8\)\s+OR \(status = 'dead'`).
+		WithArgs(float64(3600)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	pruned, err := store.Prune(context.Background(), time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pruned != 3 {
+		t.Errorf("expected 3 events pruned, got %d", pruned)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -1,8 +1,11 @@
 package postgres_gallery
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 
+	"dZev1/character-gallery/models"
 	"dZev1/character-gallery/models/characters"
 	"dZev1/character-gallery/models/inventory"
 
@@ -15,96 +18,96 @@ import (
  *
  */
 
-func (cg *PostgresCharacterGallery) insertBaseCharacter(tx *sqlx.Tx, character *characters.Character) error {
+func (cg *PostgresCharacterGallery) insertBaseCharacter(ctx context.Context, tx *sqlx.Tx, character *characters.Character) error {
 	query := `
 		INSERT INTO characters (name, body_type, species, class)
 		VALUES (:name, :body_type, :species, :class) RETURNING id
 	`
 
-	stmt, err := tx.PrepareNamed(query)
+	stmt, err := tx.PrepareNamedContext(ctx, query)
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrCouldNotInsert, err)
+		return fmt.Errorf("%w: %w", ErrCouldNotInsert, translateError(err, "character"))
 	}
 	defer stmt.Close()
 
-	err = stmt.Get(&character.ID, character)
+	err = stmt.GetContext(ctx, &character.ID, character)
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrCouldNotInsert, err)
+		return fmt.Errorf("%w: %w", ErrCouldNotInsert, translateError(err, "character"))
 	}
 
 	return nil
 }
 
-func (cg *PostgresCharacterGallery) insertStats(tx *sqlx.Tx, stats *characters.Stats) error {
+func (cg *PostgresCharacterGallery) insertStats(ctx context.Context, tx *sqlx.Tx, stats *characters.Stats) error {
 	query := `
 		INSERT INTO stats (id, strength, dexterity, constitution, intelligence, wisdom, charisma)
 		VALUES(:id, :strength, :dexterity, :constitution, :intelligence, :wisdom, :charisma)
 	`
 
-	_, err := tx.NamedExec(query, stats)
+	_, err := tx.NamedExecContext(ctx, query, stats)
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrCouldNotInsert, err)
+		return fmt.Errorf("%w: %w", ErrCouldNotInsert, translateError(err, "character"))
 	}
 	return nil
 }
 
-func (cg *PostgresCharacterGallery) insertCustomization(tx *sqlx.Tx, customization *characters.Customization) error {
+func (cg *PostgresCharacterGallery) insertCustomization(ctx context.Context, tx *sqlx.Tx, customization *characters.Customization) error {
 	query := `
 		INSERT INTO customizations (id, hair, face, shirt, pants, shoes)
 		VALUES(:id, :hair, :face, :shirt, :pants, :shoes)
 	`
-	_, err := tx.NamedExec(query, customization)
+	_, err := tx.NamedExecContext(ctx, query, customization)
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrCouldNotInsert, err)
+		return fmt.Errorf("%w: %w", ErrCouldNotInsert, translateError(err, "character"))
 	}
 	return nil
 }
 
-func (cg *PostgresCharacterGallery) getBaseCharacter(id characters.CharacterID) (*characters.Character, error) {
+func (cg *PostgresCharacterGallery) getBaseCharacter(ctx context.Context, id characters.CharacterID) (*characters.Character, error) {
 	character := &characters.Character{}
 	query := `
 		SELECT * FROM characters
 		WHERE id=$1
 	`
 
-	err := cg.db.Get(character, query, id)
+	err := cg.db.GetContext(ctx, character, query, id)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrCouldNotGet, err)
+		return nil, fmt.Errorf("%w: %w", ErrCouldNotGet, translateError(err, "character"))
 	}
 	return character, nil
 }
 
-func (cg *PostgresCharacterGallery) getCustomizationByID(id characters.CharacterID) (*characters.Customization, error) {
+func (cg *PostgresCharacterGallery) getCustomizationByID(ctx context.Context, id characters.CharacterID) (*characters.Customization, error) {
 	customization := &characters.Customization{}
 	query := `
 			SELECT * FROM customizations
 			WHERE id = $1
 		`
 
-	err := cg.db.Get(customization, query, id)
+	err := cg.db.GetContext(ctx, customization, query, id)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrCouldNotGet, err)
+		return nil, fmt.Errorf("%w: %w", ErrCouldNotGet, translateError(err, "character"))
 	}
 
 	return customization, nil
 }
 
-func (cg *PostgresCharacterGallery) getStatsByID(id characters.CharacterID) (*characters.Stats, error) {
+func (cg *PostgresCharacterGallery) getStatsByID(ctx context.Context, id characters.CharacterID) (*characters.Stats, error) {
 	stats := &characters.Stats{}
 	query := `
 			SELECT * FROM stats
 			WHERE id = $1
 		`
 
-	err := cg.db.Get(stats, query, id)
+	err := cg.db.GetContext(ctx, stats, query, id)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrCouldNotGet, err)
+		return nil, fmt.Errorf("%w: %w", ErrCouldNotGet, translateError(err, "character"))
 	}
 
 	return stats, nil
 }
 
-func (cg *PostgresCharacterGallery) updateBaseCharacters(tx *sqlx.Tx, character *characters.Character) error {
+func (cg *PostgresCharacterGallery) updateBaseCharacters(ctx context.Context, tx *sqlx.Tx, character *characters.Character) error {
 	query := `
 		UPDATE characters
 		SET name = :name,
@@ -114,15 +117,23 @@ func (cg *PostgresCharacterGallery) updateBaseCharacters(tx *sqlx.Tx, character
 		WHERE id = :id
 	`
 
-	_, err := tx.NamedExec(query, character)
+	result, err := tx.NamedExecContext(ctx, query, character)
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrCouldNotFind, err)
+		return fmt.Errorf("%w: %w", ErrCouldNotFind, translateError(err, "character"))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not verify rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return models.NotFound("character not found", ErrCouldNotFind)
 	}
 
 	return nil
 }
 
-func (cg *PostgresCharacterGallery) updateCustomization(tx *sqlx.Tx, customization *characters.Customization) error {
+func (cg *PostgresCharacterGallery) updateCustomization(ctx context.Context, tx *sqlx.Tx, customization *characters.Customization) error {
 	query := `
 		UPDATE customizations
 		SET hair = :hair,
@@ -133,15 +144,15 @@ func (cg *PostgresCharacterGallery) updateCustomization(tx *sqlx.Tx, customizati
 		WHERE id = :id
 	`
 
-	_, err := tx.NamedExec(query, customization)
+	_, err := tx.NamedExecContext(ctx, query, customization)
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrCouldNotFind, err)
+		return fmt.Errorf("%w: %w", ErrCouldNotFind, translateError(err, "character"))
 	}
 
 	return nil
 }
 
-func (cg *PostgresCharacterGallery) updateStats(tx *sqlx.Tx, stats *characters.Stats) error {
+func (cg *PostgresCharacterGallery) updateStats(ctx context.Context, tx *sqlx.Tx, stats *characters.Stats) error {
 	query := `
 		UPDATE stats
 		SET strength = :strength,
@@ -153,9 +164,9 @@ func (cg *PostgresCharacterGallery) updateStats(tx *sqlx.Tx, stats *characters.S
 		WHERE id = :id
 	`
 
-	_, err := tx.NamedExec(query, stats)
+	_, err := tx.NamedExecContext(ctx, query, stats)
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrCouldNotFind, err)
+		return fmt.Errorf("%w: %w", ErrCouldNotFind, translateError(err, "character"))
 	}
 
 	return nil
@@ -167,10 +178,10 @@ func (cg *PostgresCharacterGallery) updateStats(tx *sqlx.Tx, stats *characters.S
  *
  */
 
-func (cg *PostgresCharacterGallery) seedItemPool(tx *sqlx.Tx, item *inventory.Item) error {
+func (cg *PostgresCharacterGallery) seedItemPool(ctx context.Context, tx *sqlx.Tx, item *inventory.Item) error {
 	query := `
-	INSERT INTO items (id, name, type, description, equippable, rarity, damage, defense, heal_amount, mana_cost, duration, cooldown, capacity)
-	VALUES (:id, :name, :type, :description, :equippable, :rarity, :damage, :defense, :heal_amount, :mana_cost, :duration, :cooldown, :capacity)
+	INSERT INTO items (id, name, type, description, equippable, rarity, damage, defense, heal_amount, mana_cost, duration, cooldown, capacity, pack, slug)
+	VALUES (:id, :name, :type, :description, :equippable, :rarity, :damage, :defense, :heal_amount, :mana_cost, :duration, :cooldown, :capacity, :pack, :slug)
 	ON CONFLICT (id) DO UPDATE SET
 		name = EXCLUDED.name,
 		type = EXCLUDED.type,
@@ -182,43 +193,82 @@ func (cg *PostgresCharacterGallery) seedItemPool(tx *sqlx.Tx, item *inventory.It
 		heal_amount = EXCLUDED.heal_amount,
 		mana_cost = EXCLUDED.mana_cost,
 		duration = EXCLUDED.duration,
-		cooldown = EXCLUDED.cooldown;
+		cooldown = EXCLUDED.cooldown,
+		capacity = EXCLUDED.capacity,
+		pack = EXCLUDED.pack,
+		slug = EXCLUDED.slug;
 	`
 
-	_, err := tx.NamedExec(query, item)
+	_, err := tx.NamedExecContext(ctx, query, item)
 
 	if err != nil {
-		fmt.Println("NO PUDE CARGAR NADA MACHO")
-		return fmt.Errorf("could not add item to database: %v", err)
+		slog.ErrorContext(ctx, "could not seed item", "item_id", item.ID, "item_name", item.Name, "error", err)
+		return fmt.Errorf("could not add item to database: %w", translateError(err, "item"))
+	}
+
+	return nil
+}
+
+// seedPackItem inserts or updates a content pack item by its pack and slug, storing the ID it
+// gets in item.
+func (cg *PostgresCharacterGallery) seedPackItem(ctx context.Context, tx *sqlx.Tx, item *inventory.Item) error {
+	query := `
+	INSERT INTO items (name, type, description, equippable, rarity, damage, defense, heal_amount, mana_cost, duration, cooldown, capacity, pack, slug)
+	VALUES (:name, :type, :description, :equippable, :rarity, :damage, :defense, :heal_amount, :mana_cost, :duration, :cooldown, :capacity, :pack, :slug)
+	ON CONFLICT (pack, slug) WHERE slug <> '' DO UPDATE SET
+		name = EXCLUDED.name,
+		type = EXCLUDED.type,
+		description = EXCLUDED.description,
+		equippable = EXCLUDED.equippable,
+		rarity = EXCLUDED.rarity,
+		damage = EXCLUDED.damage,
+		defense = EXCLUDED.defense,
+		heal_amount = EXCLUDED.heal_amount,
+		mana_cost = EXCLUDED.mana_cost,
+		duration = EXCLUDED.duration,
+		cooldown = EXCLUDED.cooldown,
+		capacity = EXCLUDED.capacity
+	RETURNING id;
+	`
+
+	stmt, err := tx.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("could not prepare statement: %w", translateError(err, "item"))
+	}
+	defer stmt.Close()
+
+	if err := stmt.GetContext(ctx, &item.ID, item); err != nil {
+		slog.ErrorContext(ctx, "could not seed item", "item_pack", item.Pack, "item_slug", item.Slug, "error", err)
+		return fmt.Errorf("could not add item to database: %w", translateError(err, "item"))
 	}
 
 	return nil
 }
 
-func (cg *PostgresCharacterGallery) insertIntoItemPool(tx *sqlx.Tx, item *inventory.Item) error {
+func (cg *PostgresCharacterGallery) insertIntoItemPool(ctx context.Context, tx *sqlx.Tx, item *inventory.Item) error {
 	query := `
 	INSERT INTO items (name, type, description, equippable, rarity, damage, defense, heal_amount, mana_cost, duration, capacity)
 	VALUES (:name, :type, :description, :equippable, :rarity, :damage, :defense, :heal_amount, :mana_cost, :duration, :capacity)
 	RETURNING id;
 	`
 
-	stmt, err := tx.PrepareNamed(query)
+	stmt, err := tx.PrepareNamedContext(ctx, query)
 	if err != nil {
-		return fmt.Errorf("could not prepare statement: %w", err)
+		return fmt.Errorf("could not prepare statement: %w", translateError(err, "item"))
 	}
-	err = stmt.Get(&item.ID, item)
+	err = stmt.GetContext(ctx, &item.ID, item)
 	if err != nil {
-		return fmt.Errorf("could not insert item (duplicate?): %w", err)
+		return fmt.Errorf("could not insert item: %w", translateError(err, "item"))
 	}
 
 	return nil
 }
 
-func insertIntoCharacterInventory(tx *sqlx.Tx, characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) error {
+func insertIntoCharacterInventory(ctx context.Context, tx *sqlx.Tx, characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) error {
 	selectQuery := `
 		SELECT * FROM inventory WHERE item_id = $1 AND character_id = $2;
 	`
-	rows, err := tx.Query(selectQuery, itemID, characterID)
+	rows, err := tx.QueryContext(ctx, selectQuery, itemID, characterID)
 	if err != nil {
 		return err
 	}
@@ -231,7 +281,7 @@ func insertIntoCharacterInventory(tx *sqlx.Tx, characterID characters.CharacterI
 			SET quantity = quantity + $1
 			WHERE character_id = $2 AND item_id = $3;
 		`
-		_, err = tx.Exec(updateQuery, quantity, characterID, itemID)
+		_, err = tx.ExecContext(ctx, updateQuery, quantity, characterID, itemID)
 		if err != nil {
 			return err
 		}
@@ -244,46 +294,46 @@ func insertIntoCharacterInventory(tx *sqlx.Tx, characterID characters.CharacterI
 		VALUES ($1, $2, $3, FALSE);
 	`
 
-	_, err = tx.Exec(query, characterID, itemID, quantity)
+	_, err = tx.ExecContext(ctx, query, characterID, itemID, quantity)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (cg *PostgresCharacterGallery) selectCurrentQuantity(characterID characters.CharacterID, itemID inventory.ItemID) (uint8, error) {
+func selectCurrentQuantity(ctx context.Context, tx *sqlx.Tx, characterID characters.CharacterID, itemID inventory.ItemID) (uint8, error) {
 	querySelect := `
 		SELECT quantity FROM inventory
 		WHERE character_id = $1 AND item_id = $2;
 	`
 
 	var currentQuantity uint8
-	err := cg.db.QueryRow(querySelect, characterID, itemID).Scan(&currentQuantity)
+	err := tx.QueryRowContext(ctx, querySelect, characterID, itemID).Scan(&currentQuantity)
 	if err != nil {
 		return 0, err
 	}
 	return currentQuantity, nil
 }
 
-func updateItemQuantity(tx *sqlx.Tx, quantity uint8, characterID characters.CharacterID, itemID inventory.ItemID) error {
+func updateItemQuantity(ctx context.Context, tx *sqlx.Tx, quantity uint8, characterID characters.CharacterID, itemID inventory.ItemID) error {
 	queryUpdate := `
 			UPDATE inventory
 			SET quantity = quantity - $1
 			WHERE character_id = $2 AND item_id = $3;
 		`
-	_, err := tx.Exec(queryUpdate, quantity, characterID, itemID)
+	_, err := tx.ExecContext(ctx, queryUpdate, quantity, characterID, itemID)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func deleteItemFromCharacter(tx *sqlx.Tx, characterID characters.CharacterID, itemID inventory.ItemID) error {
+func deleteItemFromCharacter(ctx context.Context, tx *sqlx.Tx, characterID characters.CharacterID, itemID inventory.ItemID) error {
 	queryDelete := `
 			DELETE FROM inventory
 			WHERE character_id = $1 AND item_id = $2;
 		`
-	_, err := tx.Exec(queryDelete, characterID, itemID)
+	_, err := tx.ExecContext(ctx, queryDelete, characterID, itemID)
 	if err != nil {
 		return err
 	}
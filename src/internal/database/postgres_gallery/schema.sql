@@ -119,11 +119,121 @@ CREATE TABLE IF NOT EXISTS "api_keys" (
   "id" BIGSERIAL PRIMARY KEY,
   "key_hash" TEXT NOT NULL UNIQUE,
   "name" TEXT NOT NULL,
+  "scopes" TEXT NOT NULL DEFAULT 'read,write',
   "created_at" TIMESTAMP NOT NULL DEFAULT NOW(),
   "last_used_at" TIMESTAMP,
+  "expires_at" TIMESTAMPTZ,
+  "monthly_quota" BIGINT,
   "is_active" BOOLEAN NOT NULL DEFAULT TRUE
 );
 
+ALTER TABLE "api_keys"
+ADD COLUMN IF NOT EXISTS "expires_at" TIMESTAMPTZ;
+ALTER TABLE "api_keys"
+ADD COLUMN IF NOT EXISTS "scopes" TEXT NOT NULL DEFAULT 'read,write';
+ALTER TABLE "api_keys"
+ADD COLUMN IF NOT EXISTS "monthly_quota" BIGINT;
+
+-- Expiry is kept as an instant, since the API key cache compares it with the server clock.
+-- Expiries kept without a time zone were written in the session one, which the conversion reads
+-- them in.
+DO $$ BEGIN IF EXISTS (
+  SELECT 1
+  FROM information_schema.columns
+  WHERE table_name = 'api_keys' AND column_name = 'expires_at' AND data_type = 'timestamp without time zone'
+) THEN
+ALTER TABLE "api_keys"
+ALTER COLUMN "expires_at" TYPE TIMESTAMPTZ;
+END IF;
+END $$;
+
+ALTER TABLE "items"
+ADD COLUMN IF NOT EXISTS "pack" TEXT NOT NULL DEFAULT '';
+ALTER TABLE "items"
+ADD COLUMN IF NOT EXISTS "slug" TEXT NOT NULL DEFAULT '';
+
+-- Content pack items are matched by pack and slug when seeded. Other items have no slug.
+CREATE UNIQUE INDEX IF NOT EXISTS "items_pack_slug_unique" ON "items" ("pack", "slug") WHERE "slug" <> '';
+
+-- Key IDs are kept without foreign keys so entries outlive pruned keys.
+CREATE TABLE IF NOT EXISTS "api_key_audit_log" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "actor_key_id" BIGINT,
+  "action" TEXT NOT NULL,
+  "target_key_id" BIGINT,
+  "source" TEXT NOT NULL,
+  "created_at" TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS "api_key_usage" (
+  "key_id" BIGINT NOT NULL,
+  "day" DATE NOT NULL,
+  "route" TEXT NOT NULL,
+  "status_class" TEXT NOT NULL,
+  "count" BIGINT NOT NULL DEFAULT 0,
+  PRIMARY KEY ("key_id", "day", "route", "status_class")
+);
+
+-- Events are kept as a comma separated list, like API key scopes. Secrets are stored as is since
+-- every delivery is signed with them.
+CREATE TABLE IF NOT EXISTS "webhook_subscriptions" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "url" TEXT NOT NULL,
+  "events" TEXT NOT NULL,
+  "description" TEXT NOT NULL DEFAULT '',
+  "secret" TEXT NOT NULL,
+  "created_at" TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Payloads are kept as text so that every attempt posts, and signs, the same bytes.
+CREATE TABLE IF NOT EXISTS "webhook_deliveries" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "subscription_id" BIGINT NOT NULL REFERENCES "webhook_subscriptions" ("id") ON DELETE CASCADE,
+  "event_id" TEXT NOT NULL,
+  "event_type" TEXT NOT NULL,
+  "payload" TEXT NOT NULL,
+  "status" TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'dead')),
+  "attempts" INT NOT NULL DEFAULT 0,
+  "next_attempt_at" TIMESTAMP DEFAULT NOW(),
+  "last_status_code" INT,
+  "last_error" TEXT NOT NULL DEFAULT '',
+  "created_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+  "delivered_at" TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "webhook_deliveries_due" ON "webhook_deliveries" ("next_attempt_at") WHERE "status" = 'pending';
+
+-- An event is queued once per subscription, however often the outbox hands it over.
+CREATE UNIQUE INDEX IF NOT EXISTS "webhook_deliveries_event" ON "webhook_deliveries" ("subscription_id", "event_id");
+
+CREATE TABLE IF NOT EXISTS "webhook_delivery_attempts" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "delivery_id" BIGINT NOT NULL REFERENCES "webhook_deliveries" ("id") ON DELETE CASCADE,
+  "status_code" INT,
+  "error" TEXT NOT NULL DEFAULT '',
+  "duration_ms" BIGINT NOT NULL,
+  "attempted_at" TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Events are written in the transaction of the write they report, and delivered from here. The
+-- sinks that accepted an event are kept as a comma separated list, so that retries skip them.
+CREATE TABLE IF NOT EXISTS "outbox" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "event_id" TEXT NOT NULL UNIQUE,
+  "event_type" TEXT NOT NULL,
+  "character_id" BIGINT NOT NULL DEFAULT 0,
+  "data" TEXT NOT NULL,
+  "created_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+  "status" TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'dead')),
+  "attempts" INT NOT NULL DEFAULT 0,
+  "next_attempt_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+  "delivered_sinks" TEXT NOT NULL DEFAULT '',
+  "last_error" TEXT NOT NULL DEFAULT '',
+  "delivered_at" TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "outbox_pending" ON "outbox" ("id") WHERE "status" = 'pending';
+
 ALTER TABLE "inventory"
 ADD FOREIGN KEY ("item_id") REFERENCES "items" ("id") ON DELETE CASCADE;
 ALTER TABLE "stats"
@@ -6,6 +6,7 @@ import (
 
 	"dZev1/character-gallery/models/auth"
 	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/events"
 	"dZev1/character-gallery/models/inventory"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -22,7 +23,7 @@ func setupMockDB(t *testing.T) (*PostgresCharacterGallery, sqlmock.Sqlmock) {
 
 	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
 
-	gallery := &PostgresCharacterGallery{db: sqlxDB, AuthStore: NewAuthStore(sqlxDB)}
+	gallery := &PostgresCharacterGallery{db: sqlxDB, AuthStore: NewAuthStore(sqlxDB), WebhookStore: NewWebhookStore(sqlxDB), OutboxStore: NewOutboxStore(sqlxDB)}
 
 	return gallery, mock
 }
@@ -64,12 +65,13 @@ func createTestItem() *inventory.Item {
 }
 
 func createTestAPIKey() *auth.APIKey {
+	now := time.Now()
 	return &auth.APIKey{
 		ID:         1,
 		Name:       "Test API Key",
 		KeyHash:    "testhash123",
-		CreatedAt:  time.Now(),
-		LastUsedAt: time.Now(),
+		CreatedAt:  now,
+		LastUsedAt: &now,
 		IsActive:   true,
 	}
 }
@@ -87,3 +89,58 @@ func setupMockAuthStore(t *testing.T) (*PGAuthStore, sqlmock.Sqlmock) {
 
 	return authStore, mock
 }
+
+func apiKeyRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "key_hash", "name", "scopes", "created_at", "last_used_at", "expires_at", "monthly_quota", "is_active"})
+}
+
+func validateRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "key_hash", "name", "scopes", "created_at", "last_used_at", "expires_at", "monthly_quota", "is_active", "is_expired"})
+}
+
+var testActorID = auth.APIKeyID(9)
+
+// testActor performs the key changes of the tests.
+var testActor = auth.Actor{KeyID: &testActorID, Source: auth.AuditSourceAPI}
+
+// expectAudit expects action on target to be recorded in the audit log as performed by
+// testActor. A nil target is written as NULL.
+func expectAudit(mock sqlmock.Sqlmock, action auth.AuditAction, target any) {
+	mock.ExpectExec(`INSERT INTO api_key_audit_log`).
+		WithArgs(testActor.KeyID, action, target, testActor.Source).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+func rotateRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "key_hash", "name", "scopes", "created_at", "last_used_at", "expires_at", "monthly_quota", "is_active", "is_expired", "expires_in"})
+}
+
+func setupMockWebhookStore(t *testing.T) (*PGWebhookStore, sqlmock.Sqlmock) {
+	t.Helper()
+
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+
+	return &PGWebhookStore{db: sqlx.NewDb(mockDB, "sqlmock")}, mock
+}
+
+func deliveryRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "subscription_id", "event_id", "event_type", "payload", "status", "attempts", "next_attempt_at", "last_status_code", "last_error", "created_at", "delivered_at"})
+}
+
+// expectNotify expects payload to be announced on ChangesChannel. Mocked stores have no origin,
+// so it is left out of the payload.
+func expectNotify(mock sqlmock.Sqlmock, payload string) {
+	mock.ExpectExec(`SELECT pg_notify`).WithArgs(ChangesChannel, payload).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+// expectPublish expects an event of eventType to be written to the outbox, followed by the
+// notice of its change.
+func expectPublish(mock sqlmock.Sqlmock, eventType events.EventType, characterID characters.CharacterID, payload string) {
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WithArgs(sqlmock.AnyArg(), eventType, characterID, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectNotify(mock, payload)
+}
@@ -0,0 +1,242 @@
+package postgres_gallery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"dZev1/character-gallery/models"
+	"dZev1/character-gallery/models/events"
+	"dZev1/character-gallery/models/webhooks"
+	"github.com/jmoiron/sqlx"
+)
+
+type PGWebhookStore struct {
+	db *sqlx.DB
+}
+
+func NewWebhookStore(db *sqlx.DB) webhooks.WebhookStore {
+	return &PGWebhookStore{
+		db: db,
+	}
+}
+
+func (s *PGWebhookStore) CreateSubscription(ctx context.Context, url string, types events.EventTypes, description string) (*webhooks.Subscription, error) {
+	secret, err := webhooks.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO webhook_subscriptions (url, events, description, secret)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + subscriptionColumns
+
+	subscription := &webhooks.Subscription{}
+	err = s.db.GetContext(ctx, subscription, query, url, types, description, secret)
+	if err != nil {
+		return nil, translateError(err, "webhook")
+	}
+
+	return subscription, nil
+}
+
+func (s *PGWebhookStore) ListSubscriptions(ctx context.Context) ([]webhooks.Subscription, error) {
+	var subscriptions []webhooks.Subscription
+	err := s.db.SelectContext(ctx, &subscriptions, `SELECT `+subscriptionColumns+` FROM webhook_subscriptions ORDER BY id`)
+	if err != nil {
+		return nil, translateError(err, "webhook")
+	}
+
+	return subscriptions, nil
+}
+
+func (s *PGWebhookStore) GetSubscription(ctx context.Context, id webhooks.SubscriptionID) (*webhooks.Subscription, error) {
+	subscription := &webhooks.Subscription{}
+	err := s.db.GetContext(ctx, subscription, `SELECT `+subscriptionColumns+` FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return nil, translateError(err, "webhook")
+	}
+
+	return subscription, nil
+}
+
+func (s *PGWebhookStore) DeleteSubscription(ctx context.Context, id webhooks.SubscriptionID) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return translateError(err, "webhook")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not verify rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("webhook not found", nil)
+	}
+
+	return nil
+}
+
+func (s *PGWebhookStore) Enqueue(ctx context.Context, event *events.Event) (int64, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("could not encode event: %w", err)
+	}
+
+	// Subscriptions keep their events as a comma separated list, like API key scopes.
+	query := `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+		SELECT id, $1::text, $2::text, $3::text
+		FROM webhook_subscriptions
+		WHERE $2 = ANY(string_to_array(events, ','))
+		ON CONFLICT (subscription_id, event_id) DO NOTHING
+	`
+
+	result, err := s.db.ExecContext(ctx, query, event.ID, event.Type, webhooks.Payload(payload))
+	if err != nil {
+		return 0, translateError(err, "webhook delivery")
+	}
+
+	return result.RowsAffected()
+}
+
+func (s *PGWebhookStore) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]webhooks.DueDelivery, error) {
+	// SKIP LOCKED lets dispatchers of other replicas claim the next deliveries instead of
+	// waiting, and pushing next_attempt_at out hides the claimed ones until the lease ends.
+	query := `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + make_interval(secs => $2::float8)
+		FROM webhook_subscriptions s
+		WHERE s.id = d.subscription_id AND d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at, id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + prefixedDeliveryColumns + `, s.url, s.secret`
+
+	var due []webhooks.DueDelivery
+	err := s.db.SelectContext(ctx, &due, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, translateError(err, "webhook delivery")
+	}
+
+	return due, nil
+}
+
+func (s *PGWebhookStore) RecordAttempt(ctx context.Context, attempt *webhooks.Attempt, outcome webhooks.Outcome) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrFailedInitializeTransaction, translateError(err, "webhook delivery"))
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO webhook_delivery_attempts (delivery_id, status_code, error, duration_ms)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, attempted_at
+	`
+	err = tx.QueryRowxContext(ctx, query, attempt.DeliveryID, attempt.StatusCode, attempt.Error, attempt.DurationMS).
+		Scan(&attempt.ID, &attempt.AttemptedAt)
+	if err != nil {
+		return translateError(err, "webhook delivery")
+	}
+
+	query = `
+		UPDATE webhook_deliveries
+		SET status = $2,
+			attempts = attempts + 1,
+			next_attempt_at = CASE WHEN $2 = 'pending' THEN NOW() + make_interval(secs => $3::float8) END,
+			last_status_code = $4,
+			last_error = $5,
+			delivered_at = CASE WHEN $2 = 'delivered' THEN NOW() END
+		WHERE id = $1
+	`
+	_, err = tx.ExecContext(ctx, query, attempt.DeliveryID, outcome.Status, outcome.RetryIn.Seconds(), attempt.StatusCode, attempt.Error)
+	if err != nil {
+		return translateError(err, "webhook delivery")
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %w", ErrFailedCommitTransaction, translateError(err, "webhook delivery"))
+	}
+
+	return nil
+}
+
+func (s *PGWebhookStore) ListDeliveries(ctx context.Context, id webhooks.SubscriptionID, status webhooks.DeliveryStatus, limit int) ([]webhooks.Delivery, error) {
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries
+		WHERE subscription_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY id DESC
+		LIMIT $3
+	`
+
+	var deliveries []webhooks.Delivery
+	err := s.db.SelectContext(ctx, &deliveries, query, id, status, limit)
+	if err != nil {
+		return nil, translateError(err, "webhook delivery")
+	}
+
+	return deliveries, nil
+}
+
+func (s *PGWebhookStore) GetDelivery(ctx context.Context, id webhooks.SubscriptionID, deliveryID webhooks.DeliveryID) (*webhooks.Delivery, error) {
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries
+		WHERE id = $1 AND subscription_id = $2
+	`
+
+	delivery := &webhooks.Delivery{}
+	err := s.db.GetContext(ctx, delivery, query, deliveryID, id)
+	if err != nil {
+		return nil, translateError(err, "webhook delivery")
+	}
+
+	return delivery, nil
+}
+
+func (s *PGWebhookStore) ListAttempts(ctx context.Context, id webhooks.DeliveryID) ([]webhooks.Attempt, error) {
+	query := `
+		SELECT id, delivery_id, status_code, error, duration_ms, attempted_at
+		FROM webhook_delivery_attempts
+		WHERE delivery_id = $1
+		ORDER BY id
+	`
+
+	var attempts []webhooks.Attempt
+	err := s.db.SelectContext(ctx, &attempts, query, id)
+	if err != nil {
+		return nil, translateError(err, "webhook delivery")
+	}
+
+	return attempts, nil
+}
+
+func (s *PGWebhookStore) RetryDelivery(ctx context.Context, id webhooks.SubscriptionID, deliveryID webhooks.DeliveryID) (*webhooks.Delivery, error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+		WHERE id = $1 AND subscription_id = $2
+		RETURNING ` + deliveryColumns
+
+	delivery := &webhooks.Delivery{}
+	err := s.db.GetContext(ctx, delivery, query, deliveryID, id)
+	if err != nil {
+		return nil, translateError(err, "webhook delivery")
+	}
+
+	return delivery, nil
+}
+
+const subscriptionColumns = `id, url, events, description, secret, created_at`
+
+const deliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, delivered_at`
+
+const prefixedDeliveryColumns = `d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_at, d.last_status_code, d.last_error, d.created_at, d.delivered_at`
@@ -0,0 +1,140 @@
+package postgres_gallery
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"dZev1/character-gallery/models"
+	"dZev1/character-gallery/models/events"
+	"dZev1/character-gallery/models/webhooks"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCreateSubscription_Success(t *testing.T) {
+	store, mock := setupMockWebhookStore(t)
+
+	rows := sqlmock.NewRows([]string{"id", "url", "events", "description", "secret", "created_at"}).
+		AddRow(1, "https://example.com/hook", "character.created,item.created", "bot", "whsec_x", time.Now())
+	mock.ExpectQuery(`INSERT INTO webhook_subscriptions`).
+		WithArgs("https://example.com/hook", "character.created,item.created", "bot", sqlmock.AnyArg()).
+		WillReturnRows(rows)
+
+	subscription, err := store.CreateSubscription(context.Background(), "https://example.com/hook",
+		events.EventTypes{events.CharacterCreated, events.ItemCreated}, "bot")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !subscription.Events.Has(events.ItemCreated) || subscription.Secret != "whsec_x" {
+		t.Errorf("unexpected subscription %+v", subscription)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDeleteSubscription_NotFound(t *testing.T) {
+	store, mock := setupMockWebhookStore(t)
+
+	mock.ExpectExec(`DELETE FROM webhook_subscriptions WHERE id`).WithArgs(9).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := store.DeleteSubscription(context.Background(), 9)
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got: %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestEnqueue_QueuesForSubscribers(t *testing.T) {
+	store, mock := setupMockWebhookStore(t)
+
+	event := &events.Event{ID: "evt_1", Type: events.CharacterDeleted, Data: []byte(`{"id":4}`)}
+	mock.ExpectExec(`INSERT INTO webhook_deliveries (.+) FROM webhook_subscriptions`).
+		WithArgs("evt_1", events.CharacterDeleted, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	queued, err := store.Enqueue(context.Background(), event)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if queued != 2 {
+		t.Errorf("expected 2 deliveries, got %d", queued)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestClaimDeliveries_ReturnsURLAndSecret(t *testing.T) {
+	store, mock := setupMockWebhookStore(t)
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "subscription_id", "event_id", "event_type", "payload", "status", "attempts", "next_attempt_at", "last_status_code", "last_error", "created_at", "delivered_at", "url", "secret"}).
+		AddRow(5, 1, "evt_1", "item.created", []byte(`{"id":"evt_1"}`), "pending", 0, now, nil, "", now, nil, "https://example.com/hook", "whsec_x")
+	mock.ExpectQuery(`UPDATE webhook_deliveries d (.+) FOR UPDATE SKIP LOCKED`).WithArgs(20, 60.0).WillReturnRows(rows)
+
+	due, err := store.ClaimDeliveries(context.Background(), 20, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(due) != 1 || due[0].URL != "https://example.com/hook" || string(due[0].Payload) != `{"id":"evt_1"}` {
+		t.Errorf("unexpected deliveries %+v", due)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRecordAttempt_LogsAndReschedules(t *testing.T) {
+	store, mock := setupMockWebhookStore(t)
+
+	status := 503
+	attempt := &webhooks.Attempt{DeliveryID: 5, StatusCode: &status, Error: "unexpected status 503 Service Unavailable", DurationMS: 12}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO webhook_delivery_attempts`).
+		WithArgs(webhooks.DeliveryID(5), &status, attempt.Error, int64(12)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "attempted_at"}).AddRow(1, time.Now()))
+	mock.ExpectExec(`UPDATE webhook_deliveries`).
+		WithArgs(webhooks.DeliveryID(5), webhooks.DeliveryPending, 30.0, &status, attempt.Error).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := store.RecordAttempt(context.Background(), attempt, webhooks.Outcome{Status: webhooks.DeliveryPending, RetryIn: 30 * time.Second})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if attempt.ID != 1 {
+		t.Errorf("expected the attempt ID to be set, got %d", attempt.ID)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRetryDelivery_NotFound(t *testing.T) {
+	store, mock := setupMockWebhookStore(t)
+
+	mock.ExpectQuery(`UPDATE webhook_deliveries SET status = 'pending'`).WithArgs(webhooks.DeliveryID(8), webhooks.SubscriptionID(1)).WillReturnRows(deliveryRows())
+
+	_, err := store.RetryDelivery(context.Background(), 1, 8)
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got: %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package graphql
+
+import (
+	"context"
+	"strconv"
+
+	"dZev1/character-gallery/handlers"
+	"dZev1/character-gallery/models/auth"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+)
+
+// queryError is a failed field. Its extensions carry the code and details the REST API answers
+// the same failure with.
+type queryError struct {
+	er *handlers.Error
+}
+
+func (e queryError) Error() string {
+	return e.er.Error
+}
+
+func (e queryError) Extensions() map[string]any {
+	extensions := map[string]any{"code": e.er.Code}
+	if e.er.Details != nil {
+		extensions["details"] = e.er.Details
+	}
+	return extensions
+}
+
+func badRequest(message string, details any) error {
+	return queryError{&handlers.Error{Error: message, Code: "BAD_REQUEST", Details: details}}
+}
+
+// requireScope fails a field the API key of the request lacks scope for, like the REST API
+// refuses the route.
+func requireScope(ctx context.Context, scope auth.Scope) error {
+	if key, ok := auth.APIKeyFromContext(ctx); ok && key.Scopes.Has(scope) {
+		return nil
+	}
+	return queryError{&handlers.Error{
+		Error: "API key lacks the required scope",
+		Code:  "INSUFFICIENT_SCOPE",
+		Details: struct {
+			Scope auth.Scope `json:"scope"`
+		}{
+			Scope: scope,
+		},
+	}}
+}
+
+// storeError reports a failed gallery call. message describes the failed operation for errors
+// the client can't act on.
+func storeError(ctx context.Context, err error, message string, details any) error {
+	er, _ := handlers.StoreError(ctx, err, message, details)
+	return queryError{er}
+}
+
+func idDetails(id graphqlgo.ID) any {
+	return struct {
+		ID string `json:"id"`
+	}{
+		ID: string(id),
+	}
+}
+
+// parseID reads the ID of a character or item, answering like the REST API when it isn't one.
+func parseID(id graphqlgo.ID, message string) (uint64, error) {
+	n, err := strconv.ParseUint(string(id), 10, 64)
+	if err != nil {
+		return 0, badRequest(message, idDetails(id))
+	}
+	return n, nil
+}
+
+func formatID[T ~uint64](id T) graphqlgo.ID {
+	return graphqlgo.ID(strconv.FormatUint(uint64(id), 10))
+}
@@ -0,0 +1,269 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dZev1/character-gallery/models"
+	"dZev1/character-gallery/models/auth"
+	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/inventory"
+)
+
+// fakeGallery implements the methods these tests call; anything else panics through the nil
+// embedded interface.
+type fakeGallery struct {
+	models.CharacterGallery
+	characters  []characters.Character
+	inventories map[characters.CharacterID][]inventory.InventoryItem
+	filter      characters.CharacterFilter
+	fetches     [][]characters.CharacterID
+	created     []*characters.Character
+}
+
+func (f *fakeGallery) GetAll(ctx context.Context, filter characters.CharacterFilter, offset, limit int) ([]characters.Character, uint64, error) {
+	f.filter = filter
+	return f.characters, uint64(len(f.characters)), nil
+}
+
+func (f *fakeGallery) Get(ctx context.Context, id characters.CharacterID) (*characters.Character, error) {
+	for i := range f.characters {
+		if f.characters[i].ID == id {
+			return &f.characters[i], nil
+		}
+	}
+	return nil, models.NotFound("character not found", nil)
+}
+
+func (f *fakeGallery) GetCharacterInventories(ctx context.Context, ids []characters.CharacterID) (map[characters.CharacterID][]inventory.InventoryItem, error) {
+	f.fetches = append(f.fetches, ids)
+	return f.inventories, nil
+}
+
+func (f *fakeGallery) Create(ctx context.Context, character *characters.Character) error {
+	character.ID = 10
+	f.created = append(f.created, character)
+	return nil
+}
+
+func newCharacter(id characters.CharacterID, name string) characters.Character {
+	return characters.Character{
+		ID:            id,
+		Name:          name,
+		BodyType:      characters.TypeA,
+		Species:       characters.Elf,
+		Class:         characters.Wizard,
+		Stats:         &characters.Stats{ID: id, Strength: 8, Dexterity: 14, Constitution: 12, Intelligence: 16, Wisdom: 10, Charisma: 10},
+		Customization: &characters.Customization{ID: id, Hair: 1, Face: 2, Shirt: 3, Pants: 4, Shoes: 5},
+	}
+}
+
+type response struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string `json:"message"`
+		Extensions struct {
+			Code    string          `json:"code"`
+			Details json.RawMessage `json:"details"`
+		} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func do(t *testing.T, gallery models.CharacterGallery, query string, variables map[string]any) response {
+	t.Helper()
+	return doAs(t, gallery, auth.DefaultScopes, query, variables)
+}
+
+// doAs runs the query with an API key holding scopes.
+func doAs(t *testing.T, gallery models.CharacterGallery, scopes auth.Scopes, query string, variables map[string]any) response {
+	t.Helper()
+
+	body, _ := json.Marshal(Request{Query: query, Variables: variables})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/graphql", strings.NewReader(string(body)))
+	req = req.WithContext(auth.WithAPIKey(req.Context(), &auth.APIKey{Scopes: scopes}))
+	rec := httptest.NewRecorder()
+	Handler(gallery, 20).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var res response
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("could not decode response %s: %v", rec.Body, err)
+	}
+	return res
+}
+
+func TestCharacters_FetchesInventoriesTogether(t *testing.T) {
+	damage := uint64(6)
+	gallery := &fakeGallery{
+		characters: []characters.Character{newCharacter(1, "Aria"), newCharacter(2, "Brom")},
+		inventories: map[characters.CharacterID][]inventory.InventoryItem{
+			2: {{Item: &inventory.Item{ID: 7, Name: "Dagger", Type: inventory.Weapon, Rarity: 1, Damage: &damage}, Quantity: 2}},
+		},
+	}
+
+	res := do(t, gallery, `{
+		characters(filter: {species: elf, name: "r"}, limit: 5) {
+			data { id name species inventory { quantity item { name type damage defense } } }
+			pagination { page limit total hasNext }
+		}
+	}`, nil)
+	if len(res.Errors) > 0 {
+		t.Fatalf("unexpected errors %+v", res.Errors)
+	}
+
+	var page struct {
+		Data []struct {
+			ID        string `json:"id"`
+			Name      string `json:"name"`
+			Species   string `json:"species"`
+			Inventory []struct {
+				Quantity int `json:"quantity"`
+				Item     struct {
+					Name    string `json:"name"`
+					Type    string `json:"type"`
+					Damage  *int   `json:"damage"`
+					Defense *int   `json:"defense"`
+				} `json:"item"`
+			} `json:"inventory"`
+		} `json:"data"`
+		Pagination struct {
+			Page    int  `json:"page"`
+			Limit   int  `json:"limit"`
+			Total   int  `json:"total"`
+			HasNext bool `json:"hasNext"`
+		} `json:"pagination"`
+	}
+	if err := json.Unmarshal(res.Data["characters"], &page); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(gallery.fetches) != 1 || len(gallery.fetches[0]) != 2 {
+		t.Errorf("expected both inventories in one fetch, got %v", gallery.fetches)
+	}
+	if gallery.filter != (characters.CharacterFilter{Name: "r", Species: characters.Elf}) {
+		t.Errorf("unexpected filter %+v", gallery.filter)
+	}
+	if len(page.Data) != 2 || page.Data[0].ID != "1" || page.Data[0].Species != "elf" || len(page.Data[0].Inventory) != 0 {
+		t.Fatalf("unexpected characters %+v", page.Data)
+	}
+	inv := page.Data[1].Inventory
+	if len(inv) != 1 || inv[0].Quantity != 2 || inv[0].Item.Type != "weapon" || inv[0].Item.Damage == nil || *inv[0].Item.Damage != 6 || inv[0].Item.Defense != nil {
+		t.Errorf("unexpected inventory %+v", inv)
+	}
+	if page.Pagination.Limit != 5 || page.Pagination.Total != 2 || page.Pagination.HasNext {
+		t.Errorf("unexpected pagination %+v", page.Pagination)
+	}
+}
+
+func TestCharacter_MissingIsNull(t *testing.T) {
+	res := do(t, &fakeGallery{}, `{ character(id: "3") { name } }`, nil)
+	if len(res.Errors) > 0 || string(res.Data["character"]) != "null" {
+		t.Errorf("expected a null character, got %s %+v", res.Data["character"], res.Errors)
+	}
+}
+
+func TestCharacters_InvalidLimit(t *testing.T) {
+	res := do(t, &fakeGallery{}, `{ characters(limit: 500) { data { id } } }`, nil)
+	if len(res.Errors) != 1 || res.Errors[0].Extensions.Code != "BAD_REQUEST" {
+		t.Errorf("expected a bad request, got %+v", res.Errors)
+	}
+}
+
+const createCharacter = `mutation($input: CharacterInput!) {
+	createCharacter(input: $input) { id name }
+}`
+
+func characterInputVars(name string, strength int) map[string]any {
+	return map[string]any{"input": map[string]any{
+		"name":          name,
+		"bodyType":      "type_a",
+		"species":       "elf",
+		"class":         "wizard",
+		"stats":         map[string]any{"strength": strength, "dexterity": 10, "constitution": 10, "intelligence": 10, "wisdom": 10, "charisma": 10},
+		"customization": map[string]any{"hair": 1, "face": 1, "shirt": 1, "pants": 1, "shoes": 1},
+	}}
+}
+
+func TestCreateCharacter(t *testing.T) {
+	gallery := &fakeGallery{}
+
+	res := do(t, gallery, createCharacter, characterInputVars("Aria", 12))
+	if len(res.Errors) > 0 {
+		t.Fatalf("unexpected errors %+v", res.Errors)
+	}
+	if string(res.Data["createCharacter"]) != `{"id":"10","name":"Aria"}` {
+		t.Errorf("unexpected character %s", res.Data["createCharacter"])
+	}
+	if len(gallery.created) != 1 || gallery.created[0].Stats.Strength != 12 || gallery.created[0].Class != characters.Wizard {
+		t.Errorf("unexpected stored characters %+v", gallery.created)
+	}
+}
+
+func TestCreateCharacter_ReportsValidationErrors(t *testing.T) {
+	gallery := &fakeGallery{}
+
+	res := do(t, gallery, createCharacter, characterInputVars("A", 300))
+	if len(res.Errors) != 1 || res.Errors[0].Extensions.Code != "VALIDATION_FAILED" {
+		t.Fatalf("expected a validation error, got %+v", res.Errors)
+	}
+	if details := string(res.Errors[0].Extensions.Details); !strings.Contains(details, `"stats.strength"`) {
+		t.Errorf("expected the out of range stat in the details, got %s", details)
+	}
+	if len(gallery.created) != 0 {
+		t.Errorf("expected nothing stored, got %+v", gallery.created)
+	}
+}
+
+func TestScopes_AreCheckedPerField(t *testing.T) {
+	gallery := &fakeGallery{characters: []characters.Character{newCharacter(1, "Aria")}}
+
+	res := doAs(t, gallery, auth.Scopes{auth.ScopeRead}, createCharacter, characterInputVars("Brom", 12))
+	if len(res.Errors) != 1 || res.Errors[0].Extensions.Code != "INSUFFICIENT_SCOPE" || string(res.Errors[0].Extensions.Details) != `{"scope":"write"}` {
+		t.Fatalf("expected the mutation to need the write scope, got %+v", res.Errors)
+	}
+	if len(gallery.created) != 0 {
+		t.Errorf("expected nothing stored, got %+v", gallery.created)
+	}
+
+	res = doAs(t, gallery, auth.Scopes{auth.ScopeWrite}, `{ character(id: "1") { name } }`, nil)
+	if len(res.Errors) != 1 || res.Errors[0].Extensions.Code != "INSUFFICIENT_SCOPE" || string(res.Errors[0].Extensions.Details) != `{"scope":"read"}` {
+		t.Errorf("expected the query to need the read scope, got %+v", res.Errors)
+	}
+}
+
+func TestSchema_EnumsMatchModels(t *testing.T) {
+	res := do(t, &fakeGallery{}, `{ __type(name: "Class") { enumValues { name } } }`, nil)
+
+	var class struct {
+		EnumValues []struct {
+			Name string `json:"name"`
+		} `json:"enumValues"`
+	}
+	if err := json.Unmarshal(res.Data["__type"], &class); err != nil {
+		t.Fatal(err)
+	}
+	if len(class.EnumValues) != len(characters.AllClasses) {
+		t.Fatalf("expected %d classes, got %+v", len(characters.AllClasses), class.EnumValues)
+	}
+	for i, value := range class.EnumValues {
+		if value.Name != string(characters.AllClasses[i]) {
+			t.Errorf("expected class %q, got %q", characters.AllClasses[i], value.Name)
+		}
+	}
+}
+
+func TestHandler_RejectsMissingQuery(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/graphql", strings.NewReader(`{"variables": {}}`))
+	rec := httptest.NewRecorder()
+	Handler(&fakeGallery{}, 20).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
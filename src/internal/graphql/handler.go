@@ -0,0 +1,79 @@
+// Package graphql serves the gallery over GraphQL, so that clients fetch a character, its
+// inventory and the details of its items in one round trip.
+package graphql
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"dZev1/character-gallery/handlers"
+	"dZev1/character-gallery/models"
+	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/inventory"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// maxDepth bounds how deeply queries nest. The schema has no cycles, so it only stops abuse.
+const maxDepth = 10
+
+// Request is the body of a GraphQL request.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Handler answers GraphQL requests made against gallery. Characters are listed pageSize at a
+// time unless the query asks otherwise, like they are by the REST API.
+func Handler(gallery models.CharacterGallery, pageSize int) http.Handler {
+	if pageSize == 0 {
+		pageSize = 20
+	}
+	schema := graphqlgo.MustParseSchema(schemaString(), &resolver{gallery: gallery, pageSize: pageSize}, graphqlgo.MaxDepth(maxDepth))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Query == "" {
+			er := &handlers.Error{
+				Error: "Invalid request body",
+				Code:  "BAD_REQUEST",
+			}
+			handlers.ThrowError(er, w, r, http.StatusBadRequest)
+			return
+		}
+
+		// Failed fields are reported in the errors of the response, which is sent with 200.
+		response := schema.Exec(withLoader(r.Context(), gallery), req.Query, req.OperationName, req.Variables)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	})
+}
+
+// schemaString returns the schema served, in the GraphQL schema language. Enums list the values
+// the models accept, so they never drift apart.
+func schemaString() string {
+	return schemaSDL +
+		enum("BodyType", characters.AllBodyTypes) +
+		enum("Species", characters.AllSpecies) +
+		enum("Class", characters.AllClasses) +
+		enum("ItemType", inventory.AllTypes)
+}
+
+func enum[T ~string](name string, values []T) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\nenum %s {\n", name)
+	for _, value := range values {
+		fmt.Fprintf(&b, "  %s\n", value)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,76 @@
+package graphql
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"dZev1/character-gallery/models"
+	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/inventory"
+)
+
+type loaderKey struct{}
+
+// inventoryLoader fetches the inventories of the characters in a response with one gallery call
+// instead of one per character. Every character resolved is registered, and the first inventory
+// asked for fetches those of all the characters registered since the last fetch. A loader lives
+// for one request, so inventories are never served from an earlier one.
+type inventoryLoader struct {
+	gallery models.CharacterGallery
+
+	mu      sync.Mutex
+	pending []characters.CharacterID
+	loaded  map[characters.CharacterID]loadedInventory
+}
+
+type loadedInventory struct {
+	items []inventory.InventoryItem
+	err   error
+}
+
+func withLoader(ctx context.Context, gallery models.CharacterGallery) context.Context {
+	return context.WithValue(ctx, loaderKey{}, &inventoryLoader{
+		gallery: gallery,
+		loaded:  make(map[characters.CharacterID]loadedInventory),
+	})
+}
+
+func loaderFrom(ctx context.Context) *inventoryLoader {
+	return ctx.Value(loaderKey{}).(*inventoryLoader)
+}
+
+// register queues the inventory of id for the next fetch.
+func (l *inventoryLoader) register(id characters.CharacterID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.loaded[id]; !ok && !slices.Contains(l.pending, id) {
+		l.pending = append(l.pending, id)
+	}
+}
+
+// load returns the inventory of id, fetching it along with every registered one when it hasn't
+// been yet. Concurrent calls wait for the fetch instead of making their own.
+func (l *inventoryLoader) load(ctx context.Context, id characters.CharacterID) ([]inventory.InventoryItem, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if loaded, ok := l.loaded[id]; ok {
+		return loaded.items, loaded.err
+	}
+
+	ids := l.pending
+	if !slices.Contains(ids, id) {
+		ids = append(ids, id)
+	}
+	l.pending = nil
+
+	inventories, err := l.gallery.GetCharacterInventories(ctx, ids)
+	for _, id := range ids {
+		l.loaded[id] = loadedInventory{items: inventories[id], err: err}
+	}
+
+	loaded := l.loaded[id]
+	return loaded.items, loaded.err
+}
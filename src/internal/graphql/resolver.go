@@ -0,0 +1,442 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"dZev1/character-gallery/models"
+	"dZev1/character-gallery/models/auth"
+	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/inventory"
+	"dZev1/character-gallery/models/validation"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+)
+
+// maxLimit is the most characters a page can hold.
+const maxLimit = 100
+
+// resolver resolves the fields of Query and Mutation. Mutations make the same gallery calls,
+// and answer the same failures, as the REST handlers they mirror.
+type resolver struct {
+	gallery  models.CharacterGallery
+	pageSize int
+}
+
+func (r *resolver) Character(ctx context.Context, args struct{ ID graphqlgo.ID }) (*characterResolver, error) {
+	if err := requireScope(ctx, auth.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	id, err := parseID(args.ID, "Invalid ID")
+	if err != nil {
+		return nil, err
+	}
+
+	character, err := r.gallery.Get(ctx, characters.CharacterID(id))
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, storeError(ctx, err, "Could not retrieve character", idDetails(args.ID))
+	}
+
+	return newCharacterResolver(ctx, character), nil
+}
+
+type characterFilterInput struct {
+	Name     *string
+	BodyType *string
+	Species  *string
+	Class    *string
+}
+
+func (in *characterFilterInput) filter() characters.CharacterFilter {
+	var filter characters.CharacterFilter
+	if in == nil {
+		return filter
+	}
+	if in.Name != nil {
+		filter.Name = *in.Name
+	}
+	if in.BodyType != nil {
+		filter.BodyType = characters.BodyType(*in.BodyType)
+	}
+	if in.Species != nil {
+		filter.Species = characters.Species(*in.Species)
+	}
+	if in.Class != nil {
+		filter.Class = characters.Class(*in.Class)
+	}
+	return filter
+}
+
+func (r *resolver) Characters(ctx context.Context, args struct {
+	Filter *characterFilterInput
+	Page   int32
+	Limit  *int32
+}) (*characterPageResolver, error) {
+	if err := requireScope(ctx, auth.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	if args.Page < 0 {
+		return nil, badRequest("Invalid page number", struct {
+			Page int32 `json:"page"`
+		}{
+			Page: args.Page,
+		})
+	}
+
+	limit := r.pageSize
+	if args.Limit != nil {
+		if *args.Limit < 1 || *args.Limit > maxLimit {
+			return nil, badRequest("Invalid limit", struct {
+				Limit int32 `json:"limit"`
+				Max   int   `json:"max"`
+			}{
+				Limit: *args.Limit,
+				Max:   maxLimit,
+			})
+		}
+		limit = int(*args.Limit)
+	}
+	page := int(args.Page)
+
+	chars, total, err := r.gallery.GetAll(ctx, args.Filter.filter(), page*limit, limit)
+	if err != nil {
+		return nil, storeError(ctx, err, "Could not list characters", nil)
+	}
+
+	data := make([]*characterResolver, len(chars))
+	for i := range chars {
+		data[i] = newCharacterResolver(ctx, &chars[i])
+	}
+	return &characterPageResolver{
+		data:       data,
+		pagination: &paginationResolver{page: page, limit: limit, total: total},
+	}, nil
+}
+
+func (r *resolver) Item(ctx context.Context, args struct{ ID graphqlgo.ID }) (*itemResolver, error) {
+	if err := requireScope(ctx, auth.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	id, err := parseID(args.ID, "Invalid item ID")
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := r.gallery.DisplayItem(ctx, inventory.ItemID(id))
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, storeError(ctx, err, "Could not retrieve item from item pool", idDetails(args.ID))
+	}
+
+	return &itemResolver{item}, nil
+}
+
+func (r *resolver) Items(ctx context.Context, args struct{ Pack *string }) ([]*itemResolver, error) {
+	if err := requireScope(ctx, auth.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	var filter inventory.ItemFilter
+	if args.Pack != nil {
+		filter.Pack = *args.Pack
+	}
+
+	items, err := r.gallery.DisplayPoolItems(ctx, filter)
+	if err != nil {
+		return nil, storeError(ctx, err, "Could not retrieve pool items", nil)
+	}
+
+	resolvers := make([]*itemResolver, len(items))
+	for i := range items {
+		resolvers[i] = &itemResolver{&items[i]}
+	}
+	return resolvers, nil
+}
+
+type statsInput struct {
+	Strength     int32
+	Dexterity    int32
+	Constitution int32
+	Intelligence int32
+	Wisdom       int32
+	Charisma     int32
+}
+
+type customizationInput struct {
+	Hair  int32
+	Face  int32
+	Shirt int32
+	Pants int32
+	Shoes int32
+}
+
+type characterInput struct {
+	Name          string
+	BodyType      string
+	Species       string
+	Class         string
+	Stats         statsInput
+	Customization customizationInput
+}
+
+// character returns the character described by the input, or the validation errors the REST
+// API answers the same character with.
+func (in characterInput) character() (*characters.Character, error) {
+	var errs validation.Errors
+	character := &characters.Character{
+		Name:     in.Name,
+		BodyType: characters.BodyType(in.BodyType),
+		Species:  characters.Species(in.Species),
+		Class:    characters.Class(in.Class),
+		Stats: &characters.Stats{
+			Strength:     toUint8(&errs, "stats.strength", in.Stats.Strength),
+			Dexterity:    toUint8(&errs, "stats.dexterity", in.Stats.Dexterity),
+			Constitution: toUint8(&errs, "stats.constitution", in.Stats.Constitution),
+			Intelligence: toUint8(&errs, "stats.intelligence", in.Stats.Intelligence),
+			Wisdom:       toUint8(&errs, "stats.wisdom", in.Stats.Wisdom),
+			Charisma:     toUint8(&errs, "stats.charisma", in.Stats.Charisma),
+		},
+		Customization: &characters.Customization{
+			Hair:  toUint8(&errs, "customization.hair", in.Customization.Hair),
+			Face:  toUint8(&errs, "customization.face", in.Customization.Face),
+			Shirt: toUint8(&errs, "customization.shirt", in.Customization.Shirt),
+			Pants: toUint8(&errs, "customization.pants", in.Customization.Pants),
+			Shoes: toUint8(&errs, "customization.shoes", in.Customization.Shoes),
+		},
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	return character, character.Validate()
+}
+
+type itemInput struct {
+	Name        string
+	Type        string
+	Description string
+	Equippable  bool
+	Rarity      int32
+	Damage      *int32
+	Defense     *int32
+	HealAmount  *int32
+	ManaCost    *int32
+	Duration    *int32
+	Cooldown    *int32
+	Capacity    *int32
+}
+
+func (in itemInput) item() (*inventory.Item, error) {
+	var errs validation.Errors
+	item := &inventory.Item{
+		Name:        in.Name,
+		Type:        inventory.Type(in.Type),
+		Description: in.Description,
+		Equippable:  in.Equippable,
+		Rarity:      toUint8(&errs, "rarity", in.Rarity),
+		Damage:      toUint64(&errs, "damage", in.Damage),
+		Defense:     toUint64(&errs, "defense", in.Defense),
+		HealAmount:  toUint64(&errs, "heal_amount", in.HealAmount),
+		ManaCost:    toUint64(&errs, "mana_cost", in.ManaCost),
+		Duration:    toUint64(&errs, "duration", in.Duration),
+		Cooldown:    toUint64(&errs, "cooldown", in.Cooldown),
+		Capacity:    toUint64(&errs, "capacity", in.Capacity),
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	return item, item.Validate()
+}
+
+// toUint8 converts a GraphQL Int to a model field, reporting values that don't fit at path.
+func toUint8(errs *validation.Errors, path string, v int32) uint8 {
+	if v < 0 || v > math.MaxUint8 {
+		errs.Add(path, validation.CodeOutOfRange, "must be between 0 and 255")
+		return 0
+	}
+	return uint8(v)
+}
+
+func toUint64(errs *validation.Errors, path string, v *int32) *uint64 {
+	if v == nil {
+		return nil
+	}
+	if *v < 0 {
+		errs.Add(path, validation.CodeOutOfRange, "cannot be negative")
+		return nil
+	}
+	n := uint64(*v)
+	return &n
+}
+
+// quantity checks the quantity of an inventory mutation, which the gallery stores in a byte.
+func quantity(v int32) (uint8, error) {
+	if v < 1 || v > math.MaxUint8 {
+		return 0, badRequest("Invalid item quantity", struct {
+			Quantity int32 `json:"quantity"`
+		}{
+			Quantity: v,
+		})
+	}
+	return uint8(v), nil
+}
+
+func (r *resolver) CreateCharacter(ctx context.Context, args struct{ Input characterInput }) (*characterResolver, error) {
+	if err := requireScope(ctx, auth.ScopeWrite); err != nil {
+		return nil, err
+	}
+
+	character, err := args.Input.character()
+	if err != nil {
+		return nil, storeError(ctx, err, "Invalid character", nil)
+	}
+
+	if err := r.gallery.Create(ctx, character); err != nil {
+		return nil, storeError(ctx, err, "Could not create character", nil)
+	}
+
+	return newCharacterResolver(ctx, character), nil
+}
+
+func (r *resolver) EditCharacter(ctx context.Context, args struct {
+	ID    graphqlgo.ID
+	Input characterInput
+}) (*characterResolver, error) {
+	if err := requireScope(ctx, auth.ScopeWrite); err != nil {
+		return nil, err
+	}
+
+	id, err := parseID(args.ID, "Invalid ID")
+	if err != nil {
+		return nil, err
+	}
+
+	character, err := args.Input.character()
+	if err != nil {
+		return nil, storeError(ctx, err, "Invalid character", nil)
+	}
+	character.ID = characters.CharacterID(id)
+	character.Stats.ID = character.ID
+	character.Customization.ID = character.ID
+
+	if err := r.gallery.Edit(ctx, character); err != nil {
+		return nil, storeError(ctx, err, "Could not edit character", idDetails(args.ID))
+	}
+
+	return newCharacterResolver(ctx, character), nil
+}
+
+func (r *resolver) DeleteCharacter(ctx context.Context, args struct{ ID graphqlgo.ID }) (graphqlgo.ID, error) {
+	if err := requireScope(ctx, auth.ScopeWrite); err != nil {
+		return "", err
+	}
+
+	id, err := parseID(args.ID, "Invalid ID")
+	if err != nil {
+		return "", err
+	}
+
+	if err := r.gallery.Remove(ctx, characters.CharacterID(id)); err != nil {
+		return "", storeError(ctx, err, "Could not delete character", idDetails(args.ID))
+	}
+
+	return args.ID, nil
+}
+
+type inventoryArgs struct {
+	CharacterID graphqlgo.ID
+	ItemID      graphqlgo.ID
+	Quantity    int32
+}
+
+// parse returns the character, item and quantity of an inventory mutation.
+func (args inventoryArgs) parse() (characters.CharacterID, inventory.ItemID, uint8, error) {
+	characterID, err := parseID(args.CharacterID, "Invalid character ID")
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	itemID, err := parseID(args.ItemID, "Invalid item ID")
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	quantity, err := quantity(args.Quantity)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return characters.CharacterID(characterID), inventory.ItemID(itemID), quantity, nil
+}
+
+func (args inventoryArgs) details() any {
+	return struct {
+		CharacterID string `json:"character_id"`
+		ItemID      string `json:"item_id"`
+	}{
+		CharacterID: string(args.CharacterID),
+		ItemID:      string(args.ItemID),
+	}
+}
+
+func (r *resolver) AddItemToCharacter(ctx context.Context, args inventoryArgs) (*inventoryItemResolver, error) {
+	if err := requireScope(ctx, auth.ScopeWrite); err != nil {
+		return nil, err
+	}
+
+	characterID, itemID, quantity, err := args.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := r.gallery.AddItemToCharacter(ctx, characterID, itemID, quantity)
+	if err != nil {
+		return nil, storeError(ctx, err, "Could not add item to character", args.details())
+	}
+
+	return &inventoryItemResolver{entry}, nil
+}
+
+func (r *resolver) RemoveItemFromCharacter(ctx context.Context, args inventoryArgs) (*itemResolver, error) {
+	if err := requireScope(ctx, auth.ScopeWrite); err != nil {
+		return nil, err
+	}
+
+	characterID, itemID, quantity, err := args.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.gallery.RemoveItemFromCharacter(ctx, characterID, itemID, quantity); err != nil {
+		return nil, storeError(ctx, err, "Could not remove item from character", args.details())
+	}
+
+	item, err := r.gallery.DisplayItem(ctx, itemID)
+	if err != nil {
+		return nil, storeError(ctx, err, "Could not retrieve item from item pool", args.details())
+	}
+
+	return &itemResolver{item}, nil
+}
+
+func (r *resolver) CreateItem(ctx context.Context, args struct{ Input itemInput }) (*itemResolver, error) {
+	if err := requireScope(ctx, auth.ScopeWrite); err != nil {
+		return nil, err
+	}
+
+	item, err := args.Input.item()
+	if err != nil {
+		return nil, storeError(ctx, err, "Invalid item", nil)
+	}
+
+	if err := r.gallery.CreateItem(ctx, item); err != nil {
+		return nil, storeError(ctx, err, "Could not create item", nil)
+	}
+
+	return &itemResolver{item}, nil
+}
@@ -0,0 +1,203 @@
+package graphql
+
+import (
+	"context"
+	"math"
+
+	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/inventory"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+)
+
+type characterResolver struct {
+	character *characters.Character
+}
+
+// newCharacterResolver registers the character with the request's inventory loader, so that
+// its inventory is fetched along with those of the other characters in the response.
+func newCharacterResolver(ctx context.Context, character *characters.Character) *characterResolver {
+	loaderFrom(ctx).register(character.ID)
+	return &characterResolver{character: character}
+}
+
+func (r *characterResolver) ID() graphqlgo.ID {
+	return formatID(r.character.ID)
+}
+
+func (r *characterResolver) Name() string {
+	return r.character.Name
+}
+
+func (r *characterResolver) BodyType() string {
+	return string(r.character.BodyType)
+}
+
+func (r *characterResolver) Species() string {
+	return string(r.character.Species)
+}
+
+func (r *characterResolver) Class() string {
+	return string(r.character.Class)
+}
+
+func (r *characterResolver) Stats() *statsResolver {
+	if r.character.Stats == nil {
+		return &statsResolver{&characters.Stats{}}
+	}
+	return &statsResolver{r.character.Stats}
+}
+
+func (r *characterResolver) Customization() *customizationResolver {
+	if r.character.Customization == nil {
+		return &customizationResolver{&characters.Customization{}}
+	}
+	return &customizationResolver{r.character.Customization}
+}
+
+func (r *characterResolver) Inventory(ctx context.Context) ([]*inventoryItemResolver, error) {
+	entries, err := loaderFrom(ctx).load(ctx, r.character.ID)
+	if err != nil {
+		return nil, storeError(ctx, err, "Could not retrieve inventory", idDetails(r.ID()))
+	}
+
+	resolvers := make([]*inventoryItemResolver, len(entries))
+	for i := range entries {
+		resolvers[i] = &inventoryItemResolver{&entries[i]}
+	}
+	return resolvers, nil
+}
+
+type statsResolver struct {
+	stats *characters.Stats
+}
+
+func (r *statsResolver) Strength() int32     { return int32(r.stats.Strength) }
+func (r *statsResolver) Dexterity() int32    { return int32(r.stats.Dexterity) }
+func (r *statsResolver) Constitution() int32 { return int32(r.stats.Constitution) }
+func (r *statsResolver) Intelligence() int32 { return int32(r.stats.Intelligence) }
+func (r *statsResolver) Wisdom() int32       { return int32(r.stats.Wisdom) }
+func (r *statsResolver) Charisma() int32     { return int32(r.stats.Charisma) }
+
+type customizationResolver struct {
+	customization *characters.Customization
+}
+
+func (r *customizationResolver) Hair() int32  { return int32(r.customization.Hair) }
+func (r *customizationResolver) Face() int32  { return int32(r.customization.Face) }
+func (r *customizationResolver) Shirt() int32 { return int32(r.customization.Shirt) }
+func (r *customizationResolver) Pants() int32 { return int32(r.customization.Pants) }
+func (r *customizationResolver) Shoes() int32 { return int32(r.customization.Shoes) }
+
+type itemResolver struct {
+	item *inventory.Item
+}
+
+func (r *itemResolver) ID() graphqlgo.ID {
+	return formatID(r.item.ID)
+}
+
+func (r *itemResolver) Name() string {
+	return r.item.Name
+}
+
+func (r *itemResolver) Type() string {
+	return string(r.item.Type)
+}
+
+func (r *itemResolver) Description() string {
+	return r.item.Description
+}
+
+func (r *itemResolver) Equippable() bool {
+	return r.item.Equippable
+}
+
+func (r *itemResolver) Rarity() int32 {
+	return int32(r.item.Rarity)
+}
+
+func (r *itemResolver) Damage() *int32     { return optionalInt(r.item.Damage) }
+func (r *itemResolver) Defense() *int32    { return optionalInt(r.item.Defense) }
+func (r *itemResolver) HealAmount() *int32 { return optionalInt(r.item.HealAmount) }
+func (r *itemResolver) ManaCost() *int32   { return optionalInt(r.item.ManaCost) }
+func (r *itemResolver) Duration() *int32   { return optionalInt(r.item.Duration) }
+func (r *itemResolver) Cooldown() *int32   { return optionalInt(r.item.Cooldown) }
+func (r *itemResolver) Capacity() *int32   { return optionalInt(r.item.Capacity) }
+
+func (r *itemResolver) Pack() *string {
+	return optionalString(r.item.Pack)
+}
+
+func (r *itemResolver) Slug() *string {
+	return optionalString(r.item.Slug)
+}
+
+// optionalInt returns a stat as a GraphQL Int, which only has 32 bits. Larger stats are capped.
+func optionalInt(v *uint64) *int32 {
+	if v == nil {
+		return nil
+	}
+	n := int32(min(*v, math.MaxInt32))
+	return &n
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+type inventoryItemResolver struct {
+	entry *inventory.InventoryItem
+}
+
+func (r *inventoryItemResolver) Item() *itemResolver {
+	if r.entry.Item == nil {
+		return &itemResolver{&inventory.Item{}}
+	}
+	return &itemResolver{r.entry.Item}
+}
+
+func (r *inventoryItemResolver) Quantity() int32 {
+	return int32(r.entry.Quantity)
+}
+
+func (r *inventoryItemResolver) IsEquipped() bool {
+	return r.entry.IsEquipped
+}
+
+type characterPageResolver struct {
+	data       []*characterResolver
+	pagination *paginationResolver
+}
+
+func (r *characterPageResolver) Data() []*characterResolver {
+	return r.data
+}
+
+func (r *characterPageResolver) Pagination() *paginationResolver {
+	return r.pagination
+}
+
+type paginationResolver struct {
+	page, limit int
+	total       uint64
+}
+
+func (r *paginationResolver) Page() int32 {
+	return int32(r.page)
+}
+
+func (r *paginationResolver) Limit() int32 {
+	return int32(r.limit)
+}
+
+func (r *paginationResolver) Total() int32 {
+	return int32(min(r.total, math.MaxInt32))
+}
+
+func (r *paginationResolver) HasNext() bool {
+	return uint64((r.page+1)*r.limit) < r.total
+}
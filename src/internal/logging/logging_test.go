@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNew_AddsRequestIDFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "json", "info")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := WithRequestID(context.Background(), "req-123")
+	logger.InfoContext(ctx, "hello", "character_id", 7)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected a JSON record, got %q: %v", buf.String(), err)
+	}
+
+	if record["request_id"] != "req-123" {
+		t.Errorf("expected request_id req-123, got %v", record["request_id"])
+	}
+	if record["msg"] != "hello" {
+		t.Errorf("expected msg hello, got %v", record["msg"])
+	}
+}
+
+func TestNew_Level(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "text", "warn")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	logger.Info("dropped")
+	logger.Warn("kept")
+
+	if strings.Contains(buf.String(), "dropped") || !strings.Contains(buf.String(), "kept") {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+}
+
+func TestNew_WithAttrsKeepsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := New(&buf, "text", "")
+
+	logger.With(slog.String("component", "test")).InfoContext(WithRequestID(context.Background(), "abc"), "hi")
+
+	if !strings.Contains(buf.String(), "request_id=abc") {
+		t.Errorf("expected request_id in %q", buf.String())
+	}
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "xml", "info"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	if _, err := New(&bytes.Buffer{}, "json", "loud"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"dZev1/character-gallery/models"
+	"dZev1/character-gallery/models/auth"
+	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/events"
+	"dZev1/character-gallery/models/inventory"
+	"dZev1/character-gallery/models/webhooks"
+)
+
+// instrumentedGallery records the latency of every call made to the wrapped gallery.
+type instrumentedGallery struct {
+	gallery models.CharacterGallery
+	metrics *Metrics
+}
+
+func InstrumentGallery(gallery models.CharacterGallery, m *Metrics) models.CharacterGallery {
+	return &instrumentedGallery{gallery: gallery, metrics: m}
+}
+
+func (g *instrumentedGallery) observe(method string, start time.Time, err error) {
+	g.metrics.observeGalleryCall(method, err, time.Since(start))
+}
+
+func (g *instrumentedGallery) Create(ctx context.Context, character *characters.Character) error {
+	start := time.Now()
+	err := g.gallery.Create(ctx, character)
+	g.observe("Create", start, err)
+	return err
+}
+
+func (g *instrumentedGallery) Close() error {
+	start := time.Now()
+	err := g.gallery.Close()
+	g.observe("Close", start, err)
+	return err
+}
+
+func (g *instrumentedGallery) Get(ctx context.Context, id characters.CharacterID) (*characters.Character, error) {
+	start := time.Now()
+	character, err := g.gallery.Get(ctx, id)
+	g.observe("Get", start, err)
+	return character, err
+}
+
+func (g *instrumentedGallery) GetAll(ctx context.Context, filter characters.CharacterFilter, offset, limit int) ([]characters.Character, uint64, error) {
+	start := time.Now()
+	chars, total, err := g.gallery.GetAll(ctx, filter, offset, limit)
+	g.observe("GetAll", start, err)
+	return chars, total, err
+}
+
+func (g *instrumentedGallery) Edit(ctx context.Context, character *characters.Character) error {
+	start := time.Now()
+	err := g.gallery.Edit(ctx, character)
+	g.observe("Edit", start, err)
+	return err
+}
+
+func (g *instrumentedGallery) Remove(ctx context.Context, id characters.CharacterID) error {
+	start := time.Now()
+	err := g.gallery.Remove(ctx, id)
+	g.observe("Remove", start, err)
+	return err
+}
+
+func (g *instrumentedGallery) CountCharacters(ctx context.Context) (uint64, error) {
+	start := time.Now()
+	total, err := g.gallery.CountCharacters(ctx)
+	g.observe("CountCharacters", start, err)
+	return total, err
+}
+
+func (g *instrumentedGallery) CreateItem(ctx context.Context, item *inventory.Item) error {
+	start := time.Now()
+	err := g.gallery.CreateItem(ctx, item)
+	g.observe("CreateItem", start, err)
+	return err
+}
+
+func (g *instrumentedGallery) SeedItems(ctx context.Context, items []inventory.Item) error {
+	start := time.Now()
+	err := g.gallery.SeedItems(ctx, items)
+	g.observe("SeedItems", start, err)
+	return err
+}
+
+func (g *instrumentedGallery) DisplayPoolItems(ctx context.Context, filter inventory.ItemFilter) ([]inventory.Item, error) {
+	start := time.Now()
+	items, err := g.gallery.DisplayPoolItems(ctx, filter)
+	g.observe("DisplayPoolItems", start, err)
+	return items, err
+}
+
+func (g *instrumentedGallery) DisplayItem(ctx context.Context, itemID inventory.ItemID) (*inventory.Item, error) {
+	start := time.Now()
+	item, err := g.gallery.DisplayItem(ctx, itemID)
+	g.observe("DisplayItem", start, err)
+	return item, err
+}
+
+func (g *instrumentedGallery) AddItemToCharacter(ctx context.Context, characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) (*inventory.InventoryItem, error) {
+	start := time.Now()
+	item, err := g.gallery.AddItemToCharacter(ctx, characterID, itemID, quantity)
+	g.observe("AddItemToCharacter", start, err)
+	return item, err
+}
+
+func (g *instrumentedGallery) RemoveItemFromCharacter(ctx context.Context, characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) error {
+	start := time.Now()
+	err := g.gallery.RemoveItemFromCharacter(ctx, characterID, itemID, quantity)
+	g.observe("RemoveItemFromCharacter", start, err)
+	return err
+}
+
+func (g *instrumentedGallery) GetCharacterInventory(ctx context.Context, characterID characters.CharacterID) ([]inventory.InventoryItem, error) {
+	start := time.Now()
+	items, err := g.gallery.GetCharacterInventory(ctx, characterID)
+	g.observe("GetCharacterInventory", start, err)
+	return items, err
+}
+
+func (g *instrumentedGallery) GetCharacterInventories(ctx context.Context, characterIDs []characters.CharacterID) (map[characters.CharacterID][]inventory.InventoryItem, error) {
+	start := time.Now()
+	inventories, err := g.gallery.GetCharacterInventories(ctx, characterIDs)
+	g.observe("GetCharacterInventories", start, err)
+	return inventories, err
+}
+
+func (g *instrumentedGallery) CountItems(ctx context.Context) (uint64, error) {
+	start := time.Now()
+	total, err := g.gallery.CountItems(ctx)
+	g.observe("CountItems", start, err)
+	return total, err
+}
+
+func (g *instrumentedGallery) GetAuthStore() auth.AuthStore {
+	return g.gallery.GetAuthStore()
+}
+
+func (g *instrumentedGallery) GetWebhookStore() webhooks.WebhookStore {
+	return g.gallery.GetWebhookStore()
+}
+
+func (g *instrumentedGallery) GetOutboxStore() events.OutboxStore {
+	return g.gallery.GetOutboxStore()
+}
+
+func (g *instrumentedGallery) Batch(ctx context.Context, fn func(ctx context.Context, tx models.BatchTx) error) error {
+	start := time.Now()
+	err := g.gallery.Batch(ctx, fn)
+	g.observe("Batch", start, err)
+	return err
+}
@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"dZev1/character-gallery/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// countTimeout bounds the queries run on every scrape to count characters and items.
+const countTimeout = 5 * time.Second
+
+type Metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	galleryCalls    *prometheus.HistogramVec
+	authFailures    *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests served, by route pattern and status code.",
+		}, []string{"route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time taken to serve HTTP requests, by route pattern and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "status"}),
+		galleryCalls: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "gallery_call_duration_seconds",
+			Help:    "Time taken by CharacterGallery calls, by method and result.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "result"}),
+		authFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "auth_failures_total",
+			Help: "Requests rejected by API key authentication, by reason.",
+		}, []string{"reason"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.requestDuration,
+		m.galleryCalls,
+		m.authFailures,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+func (m *Metrics) ObserveRequest(route string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	m.requests.WithLabelValues(route, code).Inc()
+	m.requestDuration.WithLabelValues(route, code).Observe(duration.Seconds())
+}
+
+func (m *Metrics) ObserveAuthFailure(reason string) {
+	m.authFailures.WithLabelValues(reason).Inc()
+}
+
+func (m *Metrics) observeGalleryCall(method string, err error, duration time.Duration) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	m.galleryCalls.WithLabelValues(method, result).Observe(duration.Seconds())
+}
+
+// RegisterDBStats exports the connection pool statistics returned by stats on every scrape.
+func (m *Metrics) RegisterDBStats(stats func() sql.DBStats) {
+	gauge := func(name, help string, value func(sql.DBStats) float64) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{Name: name, Help: help}, func() float64 {
+			return value(stats())
+		})
+	}
+	counter := func(name, help string, value func(sql.DBStats) float64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{Name: name, Help: help}, func() float64 {
+			return value(stats())
+		})
+	}
+
+	m.registry.MustRegister(
+		gauge("db_max_open_connections", "Maximum number of open connections to the database.",
+			func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }),
+		gauge("db_open_connections", "Established connections, both in use and idle.",
+			func(s sql.DBStats) float64 { return float64(s.OpenConnections) }),
+		gauge("db_in_use_connections", "Connections currently in use.",
+			func(s sql.DBStats) float64 { return float64(s.InUse) }),
+		gauge("db_idle_connections", "Idle connections.",
+			func(s sql.DBStats) float64 { return float64(s.Idle) }),
+		counter("db_wait_count_total", "Connections waited for.",
+			func(s sql.DBStats) float64 { return float64(s.WaitCount) }),
+		counter("db_wait_duration_seconds_total", "Time spent waiting for a connection.",
+			func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }),
+		counter("db_max_idle_closed_total", "Connections closed due to SetMaxIdleConns.",
+			func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }),
+		counter("db_max_idle_time_closed_total", "Connections closed due to SetConnMaxIdleTime.",
+			func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }),
+		counter("db_max_lifetime_closed_total", "Connections closed due to SetConnMaxLifetime.",
+			func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }),
+	)
+}
+
+// RegisterGalleryCounts exports the number of characters and items, counted on every scrape.
+// A count that fails is left out of the scrape rather than reported as 0.
+func (m *Metrics) RegisterGalleryCounts(gallery models.CharacterGallery) {
+	m.registry.MustRegister(
+		newCountCollector("gallery_characters", "Characters stored in the gallery.", gallery.CountCharacters),
+		newCountCollector("gallery_items", "Items in the item pool.", gallery.CountItems),
+	)
+}
+
+// countCollector is a gauge set by a query on every scrape, which is not sent when it fails.
+type countCollector struct {
+	name  string
+	desc  *prometheus.Desc
+	query func(context.Context) (uint64, error)
+}
+
+func newCountCollector(name, help string, query func(context.Context) (uint64, error)) *countCollector {
+	return &countCollector{name: name, desc: prometheus.NewDesc(name, help, nil, nil), query: query}
+}
+
+func (c *countCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *countCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), countTimeout)
+	defer cancel()
+
+	total, err := c.query(ctx)
+	if err != nil {
+		slog.Error("could not collect metric", "metric", c.name, "error", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(total))
+}
+
+// Handler serves the metrics in the Prometheus text format. When token is not empty, scrapes
+// must send it as a bearer token.
+func (m *Metrics) Handler(token string) http.Handler {
+	handler := promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+	if token == "" {
+		return handler
+	}
+
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"dZev1/character-gallery/models"
+	"dZev1/character-gallery/models/characters"
+)
+
+// fakeGallery implements the methods these tests call; anything else panics through the nil
+// embedded interface.
+type fakeGallery struct {
+	models.CharacterGallery
+	countErr error
+}
+
+func (f *fakeGallery) Get(ctx context.Context, id characters.CharacterID) (*characters.Character, error) {
+	if id == 0 {
+		return nil, errors.New("not found")
+	}
+	return &characters.Character{ID: id}, nil
+}
+
+func (f *fakeGallery) CountCharacters(ctx context.Context) (uint64, error) {
+	return 3, nil
+}
+
+func (f *fakeGallery) CountItems(ctx context.Context) (uint64, error) {
+	if f.countErr != nil {
+		return 0, f.countErr
+	}
+	return 40, nil
+}
+
+func scrape(t *testing.T, handler http.Handler, header string) (int, string) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if header != "" {
+		req.Header.Set("Authorization", header)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	body, _ := io.ReadAll(rec.Body)
+	return rec.Code, string(body)
+}
+
+func assertContains(t *testing.T, body string, lines ...string) {
+	t.Helper()
+
+	for _, line := range lines {
+		if !strings.Contains(body, line) {
+			t.Errorf("expected metrics to contain %q", line)
+		}
+	}
+}
+
+func TestObserveRequest(t *testing.T) {
+	m := New()
+
+	m.ObserveRequest("GET /characters", http.StatusOK, 20*time.Millisecond)
+	m.ObserveRequest("GET /characters", http.StatusOK, 30*time.Millisecond)
+	m.ObserveAuthFailure("revoked")
+
+	_, body := scrape(t, m.Handler(""), "")
+
+	assertContains(t, body,
+		`http_requests_total{route="GET /characters",status="200"} 2`,
+		`http_request_duration_seconds_count{route="GET /characters",status="200"} 2`,
+		`auth_failures_total{reason="revoked"} 1`,
+	)
+}
+
+func TestInstrumentGallery(t *testing.T) {
+	m := New()
+	gallery := InstrumentGallery(&fakeGallery{}, m)
+
+	gallery.Get(context.Background(), 1)
+	gallery.Get(context.Background(), 0)
+
+	_, body := scrape(t, m.Handler(""), "")
+
+	assertContains(t, body,
+		`gallery_call_duration_seconds_count{method="Get",result="ok"} 1`,
+		`gallery_call_duration_seconds_count{method="Get",result="error"} 1`,
+	)
+}
+
+func TestRegisterGaugesAndDBStats(t *testing.T) {
+	m := New()
+	m.RegisterGalleryCounts(&fakeGallery{})
+	m.RegisterDBStats(func() sql.DBStats {
+		return sql.DBStats{OpenConnections: 4, InUse: 1, Idle: 3, WaitCount: 9}
+	})
+
+	_, body := scrape(t, m.Handler(""), "")
+
+	assertContains(t, body,
+		"gallery_characters 3",
+		"gallery_items 40",
+		"db_open_connections 4",
+		"db_in_use_connections 1",
+		"db_idle_connections 3",
+		"db_wait_count_total 9",
+	)
+}
+
+func TestRegisterGalleryCounts_LeavesOutFailedCounts(t *testing.T) {
+	m := New()
+	m.RegisterGalleryCounts(&fakeGallery{countErr: errors.New("database down")})
+
+	code, body := scrape(t, m.Handler(""), "")
+
+	if code != http.StatusOK {
+		t.Fatalf("expected the other metrics to be scraped, got %d", code)
+	}
+	assertContains(t, body, "gallery_characters 3")
+	if strings.Contains(body, "gallery_items") {
+		t.Errorf("expected the failed count to be left out, got %s", body)
+	}
+}
+
+func TestHandler_Token(t *testing.T) {
+	handler := New().Handler("secret")
+
+	if code, _ := scrape(t, handler, ""); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", code)
+	}
+	if code, _ := scrape(t, handler, "Bearer wrong"); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 with a wrong token, got %d", code)
+	}
+	if code, _ := scrape(t, handler, "Bearer secret"); code != http.StatusOK {
+		t.Errorf("expected 200 with the token, got %d", code)
+	}
+}
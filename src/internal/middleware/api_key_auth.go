@@ -1,8 +1,12 @@
 package middleware
 
 import (
-	"dZev1/character-gallery/models/auth"
+	"errors"
+	"log/slog"
 	"net/http"
+
+	"dZev1/character-gallery/handlers"
+	"dZev1/character-gallery/models/auth"
 )
 
 func RequireAPIKey(authStore auth.AuthStore) func(http.Handler) http.Handler {
@@ -10,25 +14,84 @@ func RequireAPIKey(authStore auth.AuthStore) func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			apiKey := r.Header.Get("X-API-Key")
 			if apiKey == "" {
-				http.Error(w, "Missing API key", http.StatusUnauthorized)
+				noteAuthFailure(r.Context(), "missing")
+				er := &handlers.Error{
+					Error: "Missing API key",
+					Code:  "MISSING_API_KEY",
+				}
+				handlers.ThrowError(er, w, r, http.StatusUnauthorized)
 				return
 			}
 
 			keyHash := auth.HashAPIKey(apiKey)
-			valid, err := authStore.ValidateAPIKey(keyHash)
-			if err != nil {
-				http.Error(w, "Error validating API key", http.StatusInternalServerError)
+			key, err := authStore.ValidateAPIKey(keyHash)
+			switch {
+			case errors.Is(err, auth.ErrAPIKeyNotFound):
+				noteAuthFailure(r.Context(), "invalid")
+				er := &handlers.Error{
+					Error: "Invalid API key",
+					Code:  "INVALID_API_KEY",
+				}
+				handlers.ThrowError(er, w, r, http.StatusUnauthorized)
+				return
+			case errors.Is(err, auth.ErrAPIKeyRevoked):
+				noteAuthFailure(r.Context(), "revoked")
+				er := &handlers.Error{
+					Error: "API key has been revoked",
+					Code:  "API_KEY_REVOKED",
+				}
+				handlers.ThrowError(er, w, r, http.StatusUnauthorized)
+				return
+			case errors.Is(err, auth.ErrAPIKeyExpired):
+				noteAuthFailure(r.Context(), "expired")
+				er := &handlers.Error{
+					Error: "API key has expired",
+					Code:  "API_KEY_EXPIRED",
+				}
+				handlers.ThrowError(er, w, r, http.StatusUnauthorized)
+				return
+			case err != nil:
+				noteAuthFailure(r.Context(), "error")
+				slog.ErrorContext(r.Context(), "could not validate API key", "error", err)
+				er := &handlers.Error{
+					Error: "Error validating API key",
+					Code:  "INTERNAL_SERVER_ERROR",
+				}
+				handlers.ThrowError(er, w, r, http.StatusInternalServerError)
 				return
 			}
 
-			if !valid {
-				http.Error(w, "Invalid API key", http.StatusUnauthorized)
-				return
+			noteAPIKey(r.Context(), key.ID)
+
+			if err := authStore.UpdateLastUsed(keyHash); err != nil {
+				slog.ErrorContext(r.Context(), "could not update last used time of API key", "api_key_id", key.ID, "error", err)
 			}
 
-			authStore.UpdateLastUsed(keyHash)
+			next.ServeHTTP(w, r.WithContext(auth.WithAPIKey(r.Context(), key)))
+		})
+	}
+}
+
+// RequireScope rejects requests whose API key lacks scope. It must run after RequireAPIKey.
+func RequireScope(scope auth.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := auth.APIKeyFromContext(r.Context())
+			if !ok || !key.Scopes.Has(scope) {
+				er := &handlers.Error{
+					Error: "API key lacks the required scope",
+					Code:  "INSUFFICIENT_SCOPE",
+					Details: struct {
+						Scope auth.Scope `json:"scope"`
+					}{
+						Scope: scope,
+					},
+				}
+				handlers.ThrowError(er, w, r, http.StatusForbidden)
+				return
+			}
 
 			next.ServeHTTP(w, r)
 		})
 	}
-}
\ No newline at end of file
+}
@@ -1,34 +1,90 @@
 package middleware
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+
+	"dZev1/character-gallery/handlers"
+	"dZev1/character-gallery/internal/authcache"
+	"dZev1/character-gallery/models/auth"
 )
 
 // MockAuthStore implements auth.AuthStore for testing
 type MockAuthStore struct {
-	ValidateFunc       func(keyHash string) (bool, error)
-	UpdateLastUsedFunc func(keyHash string) error
+	ValidateFunc       func(keyHash string) (*auth.APIKey, error)
+	UpdateLastUsedFunc func(keyHashes ...string) error
 }
 
-func (m *MockAuthStore) ValidateAPIKey(keyHash string) (bool, error) {
+func (m *MockAuthStore) ValidateAPIKey(keyHash string) (*auth.APIKey, error) {
 	if m.ValidateFunc != nil {
 		return m.ValidateFunc(keyHash)
 	}
-	return false, nil
+	return nil, auth.ErrAPIKeyNotFound
 }
 
-func (m *MockAuthStore) UpdateLastUsed(keyHash string) error {
+func (m *MockAuthStore) UpdateLastUsed(keyHashes ...string) error {
 	if m.UpdateLastUsedFunc != nil {
-		return m.UpdateLastUsedFunc(keyHash)
+		return m.UpdateLastUsedFunc(keyHashes...)
 	}
 	return nil
 }
 
-func (m *MockAuthStore) CreateAPIKey(name string) (string, error) {
-	return "", nil
+func (m *MockAuthStore) CreateAPIKey(actor auth.Actor, name string, scopes auth.Scopes, expiresIn time.Duration) (*auth.APIKey, string, error) {
+	return nil, "", nil
+}
+
+func (m *MockAuthStore) RevokeAPIKey(actor auth.Actor, id auth.APIKeyID) error {
+	return nil
+}
+
+func (m *MockAuthStore) ReactivateAPIKey(actor auth.Actor, id auth.APIKeyID) error {
+	return nil
+}
+
+func (m *MockAuthStore) ListAPIKeys() ([]auth.APIKey, error) {
+	return nil, nil
+}
+
+func (m *MockAuthStore) RotateAPIKey(actor auth.Actor, id auth.APIKeyID, gracePeriod time.Duration) (*auth.APIKey, string, error) {
+	return nil, "", nil
+}
+
+func (m *MockAuthStore) PruneAPIKeys(actor auth.Actor, unusedFor time.Duration) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockAuthStore) GetAPIKey(id auth.APIKeyID) (*auth.APIKey, error) {
+	return nil, auth.ErrAPIKeyNotFound
+}
+
+func (m *MockAuthStore) SetMonthlyQuota(actor auth.Actor, id auth.APIKeyID, quota uint64) error {
+	return nil
+}
+
+func (m *MockAuthStore) RecordUsage(records []auth.UsageRecord) error {
+	return nil
+}
+
+func (m *MockAuthStore) GetUsage(id auth.APIKeyID, from time.Time, to time.Time) ([]auth.UsageRecord, error) {
+	return nil, nil
+}
+
+func (m *MockAuthStore) MonthlyUsage(id auth.APIKeyID, month time.Time) (uint64, error) {
+	return 0, nil
+}
+
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) handlers.Error {
+	t.Helper()
+
+	var er handlers.Error
+	if err := json.NewDecoder(rec.Body).Decode(&er); err != nil {
+		t.Fatalf("could not decode error body: %v", err)
+	}
+	return er
 }
 
 func TestRequireAPIKey_MissingHeader(t *testing.T) {
@@ -47,15 +103,16 @@ func TestRequireAPIKey_MissingHeader(t *testing.T) {
 		t.Fatalf("expected status 401, got %d", rec.Code)
 	}
 
-	if rec.Body.String() != "Missing API key\n" {
-		t.Fatalf("unexpected body: %s", rec.Body.String())
+	er := decodeError(t, rec)
+	if er.Code != "MISSING_API_KEY" || er.Error != "Missing API key" {
+		t.Fatalf("unexpected error body: %+v", er)
 	}
 }
 
 func TestRequireAPIKey_InvalidKey(t *testing.T) {
 	mockStore := &MockAuthStore{
-		ValidateFunc: func(keyHash string) (bool, error) {
-			return false, nil
+		ValidateFunc: func(keyHash string) (*auth.APIKey, error) {
+			return nil, auth.ErrAPIKeyNotFound
 		},
 	}
 
@@ -73,8 +130,9 @@ func TestRequireAPIKey_InvalidKey(t *testing.T) {
 		t.Fatalf("expected status 401, got %d", rec.Code)
 	}
 
-	if rec.Body.String() != "Invalid API key\n" {
-		t.Fatalf("unexpected body: %s", rec.Body.String())
+	er := decodeError(t, rec)
+	if er.Code != "INVALID_API_KEY" || er.Error != "Invalid API key" {
+		t.Fatalf("unexpected error body: %+v", er)
 	}
 }
 
@@ -82,10 +140,10 @@ func TestRequireAPIKey_ValidKey(t *testing.T) {
 	lastUsedCalled := false
 
 	mockStore := &MockAuthStore{
-		ValidateFunc: func(keyHash string) (bool, error) {
-			return true, nil
+		ValidateFunc: func(keyHash string) (*auth.APIKey, error) {
+			return &auth.APIKey{ID: 1, Scopes: auth.DefaultScopes}, nil
 		},
-		UpdateLastUsedFunc: func(keyHash string) error {
+		UpdateLastUsedFunc: func(keyHashes ...string) error {
 			lastUsedCalled = true
 			return nil
 		},
@@ -118,8 +176,8 @@ func TestRequireAPIKey_ValidKey(t *testing.T) {
 
 func TestRequireAPIKey_DBError(t *testing.T) {
 	mockStore := &MockAuthStore{
-		ValidateFunc: func(keyHash string) (bool, error) {
-			return false, errors.New("database error")
+		ValidateFunc: func(keyHash string) (*auth.APIKey, error) {
+			return nil, errors.New("database error")
 		},
 	}
 
@@ -137,8 +195,61 @@ func TestRequireAPIKey_DBError(t *testing.T) {
 		t.Fatalf("expected status 500, got %d", rec.Code)
 	}
 
-	if rec.Body.String() != "Error validating API key\n" {
-		t.Fatalf("unexpected body: %s", rec.Body.String())
+	er := decodeError(t, rec)
+	if er.Code != "INTERNAL_SERVER_ERROR" || er.Error != "Error validating API key" {
+		t.Fatalf("unexpected error body: %+v", er)
+	}
+}
+
+func TestRequireAPIKey_RevokedKey(t *testing.T) {
+	mockStore := &MockAuthStore{
+		ValidateFunc: func(keyHash string) (*auth.APIKey, error) {
+			return nil, auth.ErrAPIKeyRevoked
+		},
+	}
+
+	handler := RequireAPIKey(mockStore)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("X-API-Key", "revoked_key")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401, got %d", rec.Code)
+	}
+
+	if er := decodeError(t, rec); er.Code != "API_KEY_REVOKED" {
+		t.Fatalf("expected code API_KEY_REVOKED, got %s", er.Code)
+	}
+}
+
+func TestRequireAPIKey_ExpiredKey(t *testing.T) {
+	mockStore := &MockAuthStore{
+		ValidateFunc: func(keyHash string) (*auth.APIKey, error) {
+			return nil, auth.ErrAPIKeyExpired
+		},
+	}
+
+	handler := RequireAPIKey(mockStore)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("X-API-Key", "expired_key")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401, got %d", rec.Code)
+	}
+
+	if er := decodeError(t, rec); er.Code != "API_KEY_EXPIRED" {
+		t.Fatalf("expected code API_KEY_EXPIRED, got %s", er.Code)
 	}
 }
 
@@ -146,9 +257,9 @@ func TestRequireAPIKey_HashesKey(t *testing.T) {
 	var receivedHash string
 
 	mockStore := &MockAuthStore{
-		ValidateFunc: func(keyHash string) (bool, error) {
+		ValidateFunc: func(keyHash string) (*auth.APIKey, error) {
 			receivedHash = keyHash
-			return true, nil
+			return &auth.APIKey{ID: 1}, nil
 		},
 	}
 
@@ -171,3 +282,127 @@ func TestRequireAPIKey_HashesKey(t *testing.T) {
 		t.Fatal("key should be hashed, not passed as-is")
 	}
 }
+
+func TestRequireAPIKey_StoresKeyInContext(t *testing.T) {
+	mockStore := &MockAuthStore{
+		ValidateFunc: func(keyHash string) (*auth.APIKey, error) {
+			return &auth.APIKey{ID: 42, KeyHash: keyHash}, nil
+		},
+	}
+
+	var fromContext *auth.APIKey
+	handler := RequireAPIKey(mockStore)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fromContext, _ = auth.APIKeyFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("X-API-Key", "my_api_key")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if fromContext == nil || fromContext.ID != 42 {
+		t.Fatalf("expected key 42 in request context, got %+v", fromContext)
+	}
+}
+
+func TestRequireScope_Allowed(t *testing.T) {
+	nextHandlerCalled := false
+	handler := RequireScope(auth.ScopeAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nextHandlerCalled = true
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req = req.WithContext(auth.WithAPIKey(req.Context(), &auth.APIKey{Scopes: auth.Scopes{auth.ScopeAdmin}}))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	if !nextHandlerCalled {
+		t.Fatal("next handler was not called")
+	}
+}
+
+func TestRequireScope_MissingScope(t *testing.T) {
+	handler := RequireScope(auth.ScopeAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("next handler should not be called")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req = req.WithContext(auth.WithAPIKey(req.Context(), &auth.APIKey{Scopes: auth.DefaultScopes}))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", rec.Code)
+	}
+
+	if er := decodeError(t, rec); er.Code != "INSUFFICIENT_SCOPE" {
+		t.Fatalf("expected code INSUFFICIENT_SCOPE, got %s", er.Code)
+	}
+}
+
+func TestRequireScope_NoKeyInContext(t *testing.T) {
+	handler := RequireScope(auth.ScopeAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("next handler should not be called")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", rec.Code)
+	}
+}
+
+// slowAuthStore simulates the round trip of a database hosted on the same network.
+type slowAuthStore struct {
+	MockAuthStore
+	latency time.Duration
+}
+
+func (s *slowAuthStore) ValidateAPIKey(keyHash string) (*auth.APIKey, error) {
+	time.Sleep(s.latency)
+	return &auth.APIKey{ID: 1, KeyHash: keyHash}, nil
+}
+
+func (s *slowAuthStore) UpdateLastUsed(keyHashes ...string) error {
+	time.Sleep(s.latency)
+	return nil
+}
+
+func benchmarkRequireAPIKey(b *testing.B, store auth.AuthStore) {
+	handler := RequireAPIKey(store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("X-API-Key", "bench_key")
+
+	b.ResetTimer()
+	for b.Loop() {
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
+
+// BenchmarkRequireAPIKey_Direct pays two round trips per request: validate and last-used.
+func BenchmarkRequireAPIKey_Direct(b *testing.B) {
+	benchmarkRequireAPIKey(b, &slowAuthStore{latency: 200 * time.Microsecond})
+}
+
+// BenchmarkRequireAPIKey_Cached serves validation from memory and batches last-used updates.
+func BenchmarkRequireAPIKey_Cached(b *testing.B) {
+	store := authcache.New(&slowAuthStore{latency: 200 * time.Microsecond}, time.Minute, time.Second)
+	defer store.Close()
+
+	benchmarkRequireAPIKey(b, store)
+}
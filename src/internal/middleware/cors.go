@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CORSPolicy decides which browser origins may call the API. Origins are written as
+// "https://app.example.com", "https://*.example.com" to allow every subdomain, or "*".
+type CORSPolicy struct {
+	AllowedOrigins   []string `json:"allowed_origins"`
+	AllowedMethods   []string `json:"allowed_methods"`
+	AllowedHeaders   []string `json:"allowed_headers"`
+	ExposedHeaders   []string `json:"exposed_headers"`
+	AllowCredentials bool     `json:"allow_credentials"`
+	// MaxAge is how many seconds browsers may cache a preflight response.
+	MaxAge int `json:"max_age"`
+}
+
+var DefaultCORSPolicy = CORSPolicy{
+	AllowedOrigins: []string{"*"},
+	AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+	AllowedHeaders: []string{"Content-Type", "Authorization", "X-API-Key", "X-Request-ID", "traceparent", "tracestate"},
+	ExposedHeaders: []string{"X-Request-ID", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"},
+	MaxAge:         600,
+}
+
+// LoadCORSPolicy reads a policy from a JSON file, falling back to DefaultCORSPolicy when the
+// file does not exist. Fields missing from the file keep their default.
+func LoadCORSPolicy(path string) (CORSPolicy, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return DefaultCORSPolicy, nil
+	}
+	if err != nil {
+		return CORSPolicy{}, fmt.Errorf("could not open CORS file: %w", err)
+	}
+	defer file.Close()
+
+	policy := DefaultCORSPolicy
+	if err := json.NewDecoder(file).Decode(&policy); err != nil {
+		return CORSPolicy{}, fmt.Errorf("could not decode CORS file: %w", err)
+	}
+	return policy, policy.Validate()
+}
+
+func (p CORSPolicy) Validate() error {
+	for _, origin := range p.AllowedOrigins {
+		if origin == "*" {
+			if p.AllowCredentials {
+				return errors.New("invalid CORS policy: credentials can't be allowed for every origin")
+			}
+			continue
+		}
+		if _, err := parseOriginPattern(origin); err != nil {
+			return fmt.Errorf("invalid CORS policy: %w", err)
+		}
+	}
+	if p.MaxAge < 0 {
+		return errors.New("invalid CORS policy: max_age can't be negative")
+	}
+	return nil
+}
+
+// originPattern is an allowed origin split so that a wildcard host can match subdomains.
+type originPattern struct {
+	scheme string
+	host   string
+	port   string
+	// wildcard matches any subdomain of host, but not host itself.
+	wildcard bool
+}
+
+func parseOriginPattern(origin string) (originPattern, error) {
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+		return originPattern{}, fmt.Errorf("origin %q must look like scheme://host[:port]", origin)
+	}
+
+	pattern := originPattern{scheme: u.Scheme, host: u.Hostname(), port: u.Port()}
+	if rest, ok := strings.CutPrefix(pattern.host, "*."); ok {
+		pattern.host, pattern.wildcard = rest, true
+	}
+	if strings.Contains(pattern.host, "*") {
+		return originPattern{}, fmt.Errorf("origin %q may only use a wildcard as its first label", origin)
+	}
+	return pattern, nil
+}
+
+func (p originPattern) matches(origin *url.URL) bool {
+	if origin.Scheme != p.scheme || origin.Port() != p.port {
+		return false
+	}
+	if p.wildcard {
+		return strings.HasSuffix(origin.Hostname(), "."+p.host)
+	}
+	return origin.Hostname() == p.host
+}
+
+// CORS answers preflight requests itself, so they never reach the API key check, and adds the
+// CORS headers to actual requests from allowed origins. policy must be valid.
+func CORS(policy CORSPolicy) func(http.Handler) http.Handler {
+	anyOrigin := slices.Contains(policy.AllowedOrigins, "*")
+	var patterns []originPattern
+	for _, origin := range policy.AllowedOrigins {
+		if pattern, err := parseOriginPattern(origin); err == nil {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	allowed := func(origin string) bool {
+		if anyOrigin {
+			return true
+		}
+		u, err := url.Parse(strings.ToLower(origin))
+		if err != nil {
+			return false
+		}
+		return slices.ContainsFunc(patterns, func(p originPattern) bool { return p.matches(u) })
+	}
+
+	methods := strings.Join(policy.AllowedMethods, ", ")
+	headers := strings.Join(policy.AllowedHeaders, ", ")
+	exposed := strings.Join(policy.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(policy.MaxAge)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && origin != "" && r.Header.Get("Access-Control-Request-Method") != ""
+
+			header := w.Header()
+			if preflight {
+				header.Add("Vary", "Origin")
+				header.Add("Vary", "Access-Control-Request-Method")
+				header.Add("Vary", "Access-Control-Request-Headers")
+			} else if !anyOrigin || policy.AllowCredentials {
+				header.Add("Vary", "Origin")
+			}
+
+			if origin != "" && allowed(origin) {
+				if anyOrigin && !policy.AllowCredentials {
+					header.Set("Access-Control-Allow-Origin", "*")
+				} else {
+					header.Set("Access-Control-Allow-Origin", origin)
+				}
+				if policy.AllowCredentials {
+					header.Set("Access-Control-Allow-Credentials", "true")
+				}
+
+				if preflight {
+					header.Set("Access-Control-Allow-Methods", methods)
+					header.Set("Access-Control-Allow-Headers", headers)
+					header.Set("Access-Control-Max-Age", maxAge)
+				} else if exposed != "" {
+					header.Set("Access-Control-Expose-Headers", exposed)
+				}
+			}
+
+			// A disallowed preflight gets no CORS headers, which is how browsers learn the
+			// request is refused.
+			if preflight {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func corsRequest(method, origin string) *http.Request {
+	r := httptest.NewRequest(method, "/api/v1/characters", nil)
+	if origin != "" {
+		r.Header.Set("Origin", origin)
+	}
+	return r
+}
+
+func preflight(origin string) *http.Request {
+	r := corsRequest(http.MethodOptions, origin)
+	r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	r.Header.Set("Access-Control-Request-Headers", "x-api-key, content-type")
+	return r
+}
+
+func TestCORS_PreflightBypassesAPIKey(t *testing.T) {
+	store := &MockAuthStore{}
+	handler := CORS(DefaultCORSPolicy)(RequireAPIKey(store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("preflight reached the handler")
+	})))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, preflight("https://app.example.com"))
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("expected Access-Control-Allow-Origin *, got %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Headers"); !slices.Contains(strings.Split(got, ", "), "X-API-Key") {
+		t.Errorf("expected X-API-Key to be allowed, got %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("expected Access-Control-Max-Age 600, got %q", got)
+	}
+}
+
+func TestCORS_AllowList(t *testing.T) {
+	policy := DefaultCORSPolicy
+	policy.AllowedOrigins = []string{"https://app.example.com", "https://*.example.org"}
+	policy.AllowCredentials = true
+	if err := policy.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://app.example.com", true},
+		{"https://APP.example.com", true},
+		{"http://app.example.com", false},
+		{"https://app.example.com:8443", false},
+		{"https://evil.com", false},
+		{"https://a.example.org", true},
+		{"https://a.b.example.org", true},
+		{"https://example.org", false},
+		{"https://badexample.org", false},
+	}
+
+	handler := CORS(policy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, corsRequest(http.MethodGet, tt.origin))
+
+			got := rec.Header().Get("Access-Control-Allow-Origin")
+			if tt.allowed && got != tt.origin {
+				t.Errorf("expected the origin to be echoed, got %q", got)
+			}
+			if !tt.allowed && got != "" {
+				t.Errorf("expected no Access-Control-Allow-Origin, got %q", got)
+			}
+			if tt.allowed && rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+				t.Error("expected credentials to be allowed")
+			}
+			exposed := strings.Split(rec.Header().Get("Access-Control-Expose-Headers"), ", ")
+			if tt.allowed && !slices.Contains(exposed, "RateLimit-Reset") {
+				t.Errorf("expected the rate limit headers to be exposed, got %q", exposed)
+			}
+		})
+	}
+}
+
+func TestCORS_DisallowedPreflight(t *testing.T) {
+	policy := DefaultCORSPolicy
+	policy.AllowedOrigins = []string{"https://app.example.com"}
+
+	rec := httptest.NewRecorder()
+	CORS(policy)(http.NotFoundHandler()).ServeHTTP(rec, preflight("https://evil.com"))
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("expected no Access-Control-Allow-Origin, got %q", got)
+	}
+}
+
+func TestCORSPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy CORSPolicy
+	}{
+		{"wildcard with credentials", CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true}},
+		{"missing scheme", CORSPolicy{AllowedOrigins: []string{"app.example.com"}}},
+		{"path", CORSPolicy{AllowedOrigins: []string{"https://app.example.com/api"}}},
+		{"inner wildcard", CORSPolicy{AllowedOrigins: []string{"https://app.*.example.com"}}},
+		{"negative max age", CORSPolicy{MaxAge: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestLoadCORSPolicy(t *testing.T) {
+	policy, err := LoadCORSPolicy(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(policy.AllowedOrigins) != 1 || policy.AllowedOrigins[0] != "*" {
+		t.Errorf("expected the default policy, got %+v", policy)
+	}
+
+	path := filepath.Join(t.TempDir(), "cors.json")
+	os.WriteFile(path, []byte(`{"allowed_origins": ["https://*.example.com"], "allow_credentials": true}`), 0o600)
+
+	policy, err = LoadCORSPolicy(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !policy.AllowCredentials || policy.MaxAge != DefaultCORSPolicy.MaxAge {
+		t.Errorf("expected file values over defaults, got %+v", policy)
+	}
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"dZev1/character-gallery/internal/metrics"
+)
+
+// InstrumentRequests counts requests and their latency by route pattern and status, and counts
+// authentication failures by reason. It must run outside RequireAPIKey to see rejected requests.
+func InstrumentRequests(m *metrics.Metrics, routes *http.ServeMux) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			r, info := withRequestInfo(r)
+
+			recorder := newResponseRecorder(w)
+			next.ServeHTTP(recorder, r)
+
+			m.ObserveRequest(matchedRoute(routes, r), recorder.status, time.Since(start))
+			if info.authFailure != "" {
+				m.ObserveAuthFailure(info.authFailure)
+			}
+		})
+	}
+}
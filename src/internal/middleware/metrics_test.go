@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dZev1/character-gallery/internal/metrics"
+	"dZev1/character-gallery/models/auth"
+)
+
+func TestInstrumentRequests(t *testing.T) {
+	m := metrics.New()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /characters/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	mockStore := &MockAuthStore{
+		ValidateFunc: func(keyHash string) (*auth.APIKey, error) {
+			if keyHash == auth.HashAPIKey("revoked_key") {
+				return nil, auth.ErrAPIKeyRevoked
+			}
+			return &auth.APIKey{ID: 1, KeyHash: keyHash}, nil
+		},
+	}
+	handler := InstrumentRequests(m, mux)(RequireAPIKey(mockStore)(mux))
+
+	for _, key := range []string{"valid_key", "revoked_key", ""} {
+		req := httptest.NewRequest(http.MethodGet, "/characters/1", nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	rec := httptest.NewRecorder()
+	m.Handler("").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+
+	for _, line := range []string{
+		`http_requests_total{route="GET /characters/{id}",status="200"} 1`,
+		`http_requests_total{route="GET /characters/{id}",status="401"} 2`,
+		`auth_failures_total{reason="revoked"} 1`,
+		`auth_failures_total{reason="missing"} 1`,
+	} {
+		if !strings.Contains(string(body), line) {
+			t.Errorf("expected metrics to contain %q", line)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dZev1/character-gallery/handlers"
+	"dZev1/character-gallery/models/auth"
+)
+
+// RateLimit allows Requests requests every Per, refilling the bucket continuously.
+// It is written as "<requests>/<duration>", e.g. "120/1m".
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+func ParseRateLimit(s string) (RateLimit, error) {
+	requestsStr, perStr, found := strings.Cut(s, "/")
+	if !found {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: expected <requests>/<duration>", s)
+	}
+
+	requests, err := strconv.Atoi(requestsStr)
+	if err != nil || requests < 1 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: requests must be a positive integer", s)
+	}
+
+	per, err := time.ParseDuration(perStr)
+	if err != nil || per <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: duration must be positive", s)
+	}
+
+	return RateLimit{Requests: requests, Per: per}, nil
+}
+
+func (l RateLimit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+func (l RateLimit) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+func (l *RateLimit) UnmarshalText(text []byte) error {
+	limit, err := ParseRateLimit(string(text))
+	if err != nil {
+		return err
+	}
+	*l = limit
+	return nil
+}
+
+func (l RateLimit) ratePerSecond() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// RateLimits holds separate limits for reads (GET, HEAD, OPTIONS), writes and GraphQL requests,
+// which can both read and write.
+type RateLimits struct {
+	Read    RateLimit `json:"read"`
+	Write   RateLimit `json:"write"`
+	GraphQL RateLimit `json:"graphql"`
+}
+
+// RateLimitPolicy resolves the limits of a key: a per-key override wins, then the most
+// generous limit among the key's scopes, then the default.
+type RateLimitPolicy struct {
+	Default RateLimits                   `json:"default"`
+	Scopes  map[auth.Scope]RateLimits    `json:"scopes,omitempty"`
+	Keys    map[auth.APIKeyID]RateLimits `json:"keys,omitempty"`
+}
+
+var DefaultRateLimitPolicy = RateLimitPolicy{
+	Default: RateLimits{
+		Read:    RateLimit{Requests: 120, Per: time.Minute},
+		Write:   RateLimit{Requests: 30, Per: time.Minute},
+		GraphQL: RateLimit{Requests: 60, Per: time.Minute},
+	},
+}
+
+// LoadRateLimitPolicy reads a policy from a JSON file, falling back to DefaultRateLimitPolicy
+// when the file does not exist. Unknown scopes are refused rather than left to the default.
+func LoadRateLimitPolicy(path string) (RateLimitPolicy, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return DefaultRateLimitPolicy, nil
+	}
+	if err != nil {
+		return RateLimitPolicy{}, fmt.Errorf("could not open rate limit file: %w", err)
+	}
+	defer file.Close()
+
+	policy := DefaultRateLimitPolicy
+	if err := json.NewDecoder(file).Decode(&policy); err != nil {
+		return RateLimitPolicy{}, fmt.Errorf("could not decode rate limit file: %w", err)
+	}
+	for scope := range policy.Scopes {
+		if !scope.Validate() {
+			return RateLimitPolicy{}, fmt.Errorf("invalid rate limit file: unknown scope %q", scope)
+		}
+	}
+	policy.fillDefaults()
+	return policy, nil
+}
+
+// fillDefaults lets scope and key overrides set only some of their limits.
+func (p *RateLimitPolicy) fillDefaults() {
+	fill := func(limits RateLimits) RateLimits {
+		if limits.Read.Requests == 0 {
+			limits.Read = p.Default.Read
+		}
+		if limits.Write.Requests == 0 {
+			limits.Write = p.Default.Write
+		}
+		if limits.GraphQL.Requests == 0 {
+			limits.GraphQL = p.Default.GraphQL
+		}
+		return limits
+	}
+
+	for scope, limits := range p.Scopes {
+		p.Scopes[scope] = fill(limits)
+	}
+	for id, limits := range p.Keys {
+		p.Keys[id] = fill(limits)
+	}
+}
+
+func (p RateLimitPolicy) limitsFor(key *auth.APIKey) RateLimits {
+	if limits, ok := p.Keys[key.ID]; ok {
+		return limits
+	}
+
+	limits, found := p.Default, false
+	for _, scope := range key.Scopes {
+		scopeLimits, ok := p.Scopes[scope]
+		if !ok {
+			continue
+		}
+		if !found {
+			limits, found = scopeLimits, true
+			continue
+		}
+		if scopeLimits.Read.ratePerSecond() > limits.Read.ratePerSecond() {
+			limits.Read = scopeLimits.Read
+		}
+		if scopeLimits.Write.ratePerSecond() > limits.Write.ratePerSecond() {
+			limits.Write = scopeLimits.Write
+		}
+		if scopeLimits.GraphQL.ratePerSecond() > limits.GraphQL.ratePerSecond() {
+			limits.GraphQL = scopeLimits.GraphQL
+		}
+	}
+	return limits
+}
+
+// BucketStore keeps token buckets. Take removes a token from the bucket named key, creating
+// it full if needed, and reports how many tokens are left or how long until one is available.
+type BucketStore interface {
+	Take(key string, limit RateLimit) (remaining int, retryAfter time.Duration, allowed bool)
+}
+
+type bucket struct {
+	tokens   float64
+	limit    RateLimit
+	lastSeen time.Time
+}
+
+// MemoryBucketStore is a BucketStore local to the process.
+type MemoryBucketStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryBucketStore() *MemoryBucketStore {
+	return &MemoryBucketStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryBucketStore) Take(key string, limit RateLimit) (int, time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{tokens: float64(limit.Requests), limit: limit, lastSeen: now}
+		s.buckets[key] = b
+	}
+
+	rate := limit.ratePerSecond()
+	b.tokens = math.Min(float64(limit.Requests), b.tokens+now.Sub(b.lastSeen).Seconds()*rate)
+	b.lastSeen = now
+
+	if b.tokens < 1 {
+		retryAfter := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+		return 0, retryAfter, false
+	}
+
+	b.tokens--
+	return int(b.tokens), 0, true
+}
+
+// sweep drops buckets that have been idle long enough to be full again, at most once a minute.
+func (s *MemoryBucketStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if now.Sub(b.lastSeen) >= b.limit.Per {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// RateLimitByKey applies the policy per API key hash, with separate read, write and GraphQL
+// buckets. Requests to graphqlPath are charged to the GraphQL one, whatever they do. It must run
+// after RequireAPIKey; requests without a key in their context are not limited.
+func RateLimitByKey(policy RateLimitPolicy, buckets BucketStore, graphqlPath string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := auth.APIKeyFromContext(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			limits := policy.limitsFor(key)
+			limit, kind := limits.Write, "write"
+			switch {
+			case r.URL.Path == graphqlPath:
+				limit, kind = limits.GraphQL, "graphql"
+			case r.Method == http.MethodGet, r.Method == http.MethodHead, r.Method == http.MethodOptions:
+				limit, kind = limits.Read, "read"
+			}
+
+			remaining, retryAfter, allowed := buckets.Take(key.KeyHash+":"+kind, limit)
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+			// The bucket refills continuously, so the reset is when it is full again.
+			reset := math.Ceil(float64(limit.Requests-remaining) / limit.ratePerSecond())
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(int(reset)))
+
+			if !allowed {
+				seconds := int(math.Ceil(retryAfter.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+				er := &handlers.Error{
+					Error: "Rate limit exceeded",
+					Code:  "RATE_LIMITED",
+					Details: struct {
+						Limit      string `json:"limit"`
+						RetryAfter int    `json:"retry_after"`
+					}{
+						Limit:      limit.String(),
+						RetryAfter: seconds,
+					},
+				}
+				handlers.ThrowError(er, w, r, http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dZev1/character-gallery/models/auth"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestBucketStore() (*MemoryBucketStore, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := NewMemoryBucketStore()
+	store.now = clock.Now
+	return store, clock
+}
+
+func testPolicy() RateLimitPolicy {
+	return RateLimitPolicy{
+		Default: RateLimits{
+			Read:    RateLimit{Requests: 2, Per: time.Minute},
+			Write:   RateLimit{Requests: 1, Per: time.Minute},
+			GraphQL: RateLimit{Requests: 1, Per: time.Minute},
+		},
+	}
+}
+
+func doRateLimited(handler http.Handler, method string, key *auth.APIKey) *httptest.ResponseRecorder {
+	return doRateLimitedAt(handler, method, "/test", key)
+}
+
+func doRateLimitedAt(handler http.Handler, method, path string, key *auth.APIKey) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req = req.WithContext(auth.WithAPIKey(req.Context(), key))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestRateLimitByKey_BlocksAfterLimit(t *testing.T) {
+	store, _ := newTestBucketStore()
+	handler := RateLimitByKey(testPolicy(), store, "/graphql")(okHandler())
+	key := &auth.APIKey{ID: 1, KeyHash: "hash1"}
+
+	rec := doRateLimited(handler, http.MethodGet, key)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if rec.Header().Get("RateLimit-Limit") != "2" || rec.Header().Get("RateLimit-Remaining") != "1" {
+		t.Fatalf("unexpected rate limit headers: %v", rec.Header())
+	}
+
+	doRateLimited(handler, http.MethodGet, key)
+
+	rec = doRateLimited(handler, http.MethodGet, key)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "30" {
+		t.Fatalf("expected Retry-After 30, got %q", rec.Header().Get("Retry-After"))
+	}
+	if rec.Header().Get("RateLimit-Reset") != "60" {
+		t.Fatalf("expected RateLimit-Reset 60, got %q", rec.Header().Get("RateLimit-Reset"))
+	}
+	if er := decodeError(t, rec); er.Code != "RATE_LIMITED" {
+		t.Fatalf("expected code RATE_LIMITED, got %s", er.Code)
+	}
+}
+
+func TestRateLimitByKey_RefillsOverTime(t *testing.T) {
+	store, clock := newTestBucketStore()
+	handler := RateLimitByKey(testPolicy(), store, "/graphql")(okHandler())
+	key := &auth.APIKey{ID: 1, KeyHash: "hash1"}
+
+	doRateLimited(handler, http.MethodPost, key)
+	if rec := doRateLimited(handler, http.MethodPost, key); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d", rec.Code)
+	}
+
+	clock.now = clock.now.Add(time.Minute)
+
+	if rec := doRateLimited(handler, http.MethodPost, key); rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 after refill, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitByKey_SeparateReadAndWriteBuckets(t *testing.T) {
+	store, _ := newTestBucketStore()
+	handler := RateLimitByKey(testPolicy(), store, "/graphql")(okHandler())
+	key := &auth.APIKey{ID: 1, KeyHash: "hash1"}
+
+	doRateLimited(handler, http.MethodDelete, key)
+	if rec := doRateLimited(handler, http.MethodPut, key); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected write to be limited, got %d", rec.Code)
+	}
+
+	if rec := doRateLimited(handler, http.MethodGet, key); rec.Code != http.StatusOK {
+		t.Fatalf("expected read to use its own bucket, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitByKey_GraphQLBucket(t *testing.T) {
+	store, _ := newTestBucketStore()
+	handler := RateLimitByKey(testPolicy(), store, "/graphql")(okHandler())
+	key := &auth.APIKey{ID: 1, KeyHash: "hash1"}
+
+	if rec := doRateLimitedAt(handler, http.MethodPost, "/graphql", key); rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if rec := doRateLimitedAt(handler, http.MethodPost, "/graphql", key); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected GraphQL to be limited, got %d", rec.Code)
+	}
+	if rec := doRateLimited(handler, http.MethodPost, key); rec.Code != http.StatusOK {
+		t.Fatalf("expected writes to use their own bucket, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitByKey_SeparateKeys(t *testing.T) {
+	store, _ := newTestBucketStore()
+	handler := RateLimitByKey(testPolicy(), store, "/graphql")(okHandler())
+
+	doRateLimited(handler, http.MethodPost, &auth.APIKey{ID: 1, KeyHash: "hash1"})
+
+	if rec := doRateLimited(handler, http.MethodPost, &auth.APIKey{ID: 2, KeyHash: "hash2"}); rec.Code != http.StatusOK {
+		t.Fatalf("expected other key to have its own bucket, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitPolicy_Resolution(t *testing.T) {
+	policy := testPolicy()
+	policy.Scopes = map[auth.Scope]RateLimits{
+		auth.ScopeRead:  {Read: RateLimit{Requests: 10, Per: time.Minute}, Write: policy.Default.Write},
+		auth.ScopeAdmin: {Read: RateLimit{Requests: 5, Per: time.Second}, Write: RateLimit{Requests: 50, Per: time.Minute}},
+	}
+	policy.Keys = map[auth.APIKeyID]RateLimits{
+		7: {Read: RateLimit{Requests: 1, Per: time.Hour}, Write: RateLimit{Requests: 1, Per: time.Hour}},
+	}
+
+	limits := policy.limitsFor(&auth.APIKey{ID: 1, Scopes: auth.Scopes{auth.ScopeRead, auth.ScopeAdmin}})
+	if limits.Read.Requests != 5 || limits.Write.Requests != 50 {
+		t.Fatalf("expected most generous scope limits, got %+v", limits)
+	}
+
+	limits = policy.limitsFor(&auth.APIKey{ID: 7, Scopes: auth.Scopes{auth.ScopeAdmin}})
+	if limits.Read.Per != time.Hour {
+		t.Fatalf("expected key override to win, got %+v", limits)
+	}
+
+	limits = policy.limitsFor(&auth.APIKey{ID: 2, Scopes: auth.Scopes{auth.ScopeWrite}})
+	if limits != policy.Default {
+		t.Fatalf("expected default limits, got %+v", limits)
+	}
+}
+
+func TestLoadRateLimitPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rate_limits.json")
+	content := `{
+		"default": {"read": "100/1m", "write": "10/1m"},
+		"scopes": {"admin": {"read": "1000/1m"}},
+		"keys": {"3": {"write": "1/1h"}}
+	}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("could not write policy file: %v", err)
+	}
+
+	policy, err := LoadRateLimitPolicy(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if policy.Scopes[auth.ScopeAdmin].Write.Requests != 10 {
+		t.Fatalf("expected admin write limit to fall back to default, got %+v", policy.Scopes[auth.ScopeAdmin])
+	}
+
+	if policy.Keys[3].Write != (RateLimit{Requests: 1, Per: time.Hour}) {
+		t.Fatalf("unexpected key override: %+v", policy.Keys[3])
+	}
+	if policy.Keys[3].GraphQL != DefaultRateLimitPolicy.Default.GraphQL {
+		t.Fatalf("expected the GraphQL limit left unset to be the default, got %+v", policy.Keys[3])
+	}
+
+	policy, err = LoadRateLimitPolicy(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("unexpected error for missing file: %v", err)
+	}
+	if policy.Default != DefaultRateLimitPolicy.Default {
+		t.Fatalf("expected default policy, got %+v", policy)
+	}
+}
+
+func TestLoadRateLimitPolicy_UnknownScope(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rate_limits.json")
+	if err := os.WriteFile(path, []byte(`{"scopes": {"amdin": {"read": "1000/1m"}}}`), 0o600); err != nil {
+		t.Fatalf("could not write policy file: %v", err)
+	}
+
+	if _, err := LoadRateLimitPolicy(path); err == nil || !strings.Contains(err.Error(), `"amdin"`) {
+		t.Fatalf("expected the unknown scope to be refused, got %v", err)
+	}
+}
+
+func TestParseRateLimit_Invalid(t *testing.T) {
+	for _, s := range []string{"", "10", "0/1m", "10/abc", "10/-1s"} {
+		if _, err := ParseRateLimit(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"dZev1/character-gallery/models/auth"
+)
+
+type requestInfoKey struct{}
+
+// requestInfo collects what inner middlewares learn about a request, since the requests they
+// pass on are copies the outer ones never see.
+type requestInfo struct {
+	keyID       *auth.APIKeyID
+	authFailure string
+}
+
+// withRequestInfo returns r carrying a requestInfo, reusing the one an outer middleware already
+// attached.
+func withRequestInfo(r *http.Request) (*http.Request, *requestInfo) {
+	if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+		return r, info
+	}
+
+	info := &requestInfo{}
+	return r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, info)), info
+}
+
+func noteAPIKey(ctx context.Context, id auth.APIKeyID) {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		info.keyID = &id
+	}
+}
+
+func noteAuthFailure(ctx context.Context, reason string) {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		info.authFailure = reason
+	}
+}
+
+// matchedRoute looks up the pattern r matches in routes. Outer middlewares can't rely on
+// r.Pattern, which the ServeMux only sets on the request it receives.
+func matchedRoute(routes *http.ServeMux, r *http.Request) string {
+	_, pattern := routes.Handler(r)
+	if pattern == "" {
+		return "unmatched"
+	}
+	return pattern
+}
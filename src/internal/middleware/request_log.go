@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"dZev1/character-gallery/internal/logging"
+)
+
+const maxRequestIDLength = 128
+
+// LogRequests assigns every request an ID, or keeps the one sent in X-Request-ID, and logs one
+// line per request once it has been served. It should be the outermost middleware. routes is
+// only used to look up the pattern a request matches.
+func LogRequests(logger *slog.Logger, routes *http.ServeMux) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			requestID := r.Header.Get("X-Request-ID")
+			if !validRequestID(requestID) {
+				requestID = rand.Text()
+			}
+			w.Header().Set("X-Request-ID", requestID)
+
+			r = r.WithContext(logging.WithRequestID(r.Context(), requestID))
+			r, info := withRequestInfo(r)
+
+			recorder := newResponseRecorder(w)
+			next.ServeHTTP(recorder, r)
+
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("route", matchedRoute(routes, r)),
+				slog.Int("status", recorder.status),
+				slog.Duration("latency", time.Since(start)),
+				slog.Int("bytes", recorder.bytes),
+			}
+			if info.keyID != nil {
+				attrs = append(attrs, slog.Uint64("api_key_id", uint64(*info.keyID)))
+			}
+
+			level := slog.LevelInfo
+			if recorder.status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			logger.LogAttrs(r.Context(), level, "request", attrs...)
+		})
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dZev1/character-gallery/internal/logging"
+	"dZev1/character-gallery/models/auth"
+)
+
+func newTestLogger(t *testing.T) (*bytes.Buffer, func(*http.ServeMux) func(http.Handler) http.Handler) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, "json", "debug")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return &buf, func(mux *http.ServeMux) func(http.Handler) http.Handler {
+		return LogRequests(logger, mux)
+	}
+}
+
+func decodeLogLine(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &record); err != nil {
+		t.Fatalf("could not decode log line %q: %v", buf.String(), err)
+	}
+	return record
+}
+
+func TestLogRequests_LogsRequest(t *testing.T) {
+	buf, logRequests := newTestLogger(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /characters/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("hello"))
+	})
+
+	mockStore := &MockAuthStore{
+		ValidateFunc: func(keyHash string) (*auth.APIKey, error) {
+			return &auth.APIKey{ID: 42, KeyHash: keyHash}, nil
+		},
+	}
+	handler := logRequests(mux)(RequireAPIKey(mockStore)(mux))
+
+	req := httptest.NewRequest(http.MethodGet, "/characters/7", nil)
+	req.Header.Set("X-API-Key", "test_key")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	requestID := rec.Header().Get("X-Request-ID")
+	if requestID == "" {
+		t.Fatal("expected an X-Request-ID header")
+	}
+
+	record := decodeLogLine(t, buf)
+	expected := map[string]any{
+		"method":     "GET",
+		"route":      "GET /characters/{id}",
+		"status":     float64(http.StatusTeapot),
+		"bytes":      float64(5),
+		"api_key_id": float64(42),
+		"request_id": requestID,
+	}
+	for field, want := range expected {
+		if record[field] != want {
+			t.Errorf("expected %s %v, got %v", field, want, record[field])
+		}
+	}
+	if _, ok := record["latency"]; !ok {
+		t.Error("expected a latency field")
+	}
+}
+
+func TestLogRequests_PropagatesRequestID(t *testing.T) {
+	_, logRequests := newTestLogger(t)
+
+	var seen string
+	handler := logRequests(http.NewServeMux())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = logging.RequestIDFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("X-Request-ID", "upstream-id_1.2")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if seen != "upstream-id_1.2" {
+		t.Errorf("expected the incoming request ID in the context, got %q", seen)
+	}
+	if got := rec.Header().Get("X-Request-ID"); got != "upstream-id_1.2" {
+		t.Errorf("expected the incoming request ID to be echoed, got %q", got)
+	}
+}
+
+func TestLogRequests_ReplacesInvalidRequestID(t *testing.T) {
+	_, logRequests := newTestLogger(t)
+
+	handler := logRequests(http.NewServeMux())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("X-Request-ID", "bad id\nwith newline")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	got := rec.Header().Get("X-Request-ID")
+	if got == "" || got == req.Header.Get("X-Request-ID") {
+		t.Errorf("expected a generated request ID, got %q", got)
+	}
+}
+
+func TestLogRequests_UnauthenticatedRequest(t *testing.T) {
+	buf, logRequests := newTestLogger(t)
+
+	mux := http.NewServeMux()
+	handler := logRequests(mux)(RequireAPIKey(&MockAuthStore{})(mux))
+
+	req := httptest.NewRequest(http.MethodGet, "/nowhere", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	record := decodeLogLine(t, buf)
+	if record["route"] != "unmatched" {
+		t.Errorf("expected route unmatched, got %v", record["route"])
+	}
+	if record["status"] != float64(http.StatusUnauthorized) {
+		t.Errorf("expected status 401, got %v", record["status"])
+	}
+	if _, ok := record["api_key_id"]; ok {
+		t.Error("expected no api_key_id for an unauthenticated request")
+	}
+}
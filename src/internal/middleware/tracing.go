@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+
+	"dZev1/character-gallery/internal/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceRequests starts a server span for every request, continuing the trace sent in the W3C
+// traceparent header when there is one. It should be the outermost middleware so request logs
+// carry the trace ID.
+func TraceRequests(routes *http.ServeMux) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			route := matchedRoute(routes, r)
+
+			name := route
+			if route == "unmatched" {
+				name = r.Method
+			}
+
+			ctx, span := tracing.Tracer().Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.HTTPRoute(route),
+					semconv.URLPath(r.URL.Path),
+				),
+			)
+			defer span.End()
+
+			recorder := newResponseRecorder(w)
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+
+			span.SetAttributes(semconv.HTTPResponseStatusCode(recorder.status))
+			if recorder.status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(recorder.status))
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"dZev1/character-gallery/handlers"
+	"dZev1/character-gallery/internal/usage"
+	"dZev1/character-gallery/models/auth"
+)
+
+// TrackUsage counts requests per key, route pattern and status class, and rejects keys that
+// have used up their monthly quota. It must wrap the ServeMux directly so the matched pattern
+// is visible once the request has been served.
+func TrackUsage(recorder *usage.Recorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := auth.APIKeyFromContext(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			allowed, err := recorder.Allow(key)
+			if err != nil {
+				// Failing open keeps a usage store outage from taking the API down with it.
+				slog.WarnContext(r.Context(), "could not check monthly quota, allowing request", "api_key_id", key.ID, "error", err)
+				allowed = true
+			}
+
+			if !allowed {
+				er := &handlers.Error{
+					Error: "Monthly quota exceeded",
+					Code:  "QUOTA_EXCEEDED",
+					Details: struct {
+						MonthlyQuota uint64 `json:"monthly_quota"`
+					}{
+						MonthlyQuota: *key.MonthlyQuota,
+					},
+				}
+				handlers.ThrowError(er, w, r, http.StatusTooManyRequests)
+				return
+			}
+
+			rec := newResponseRecorder(w)
+			next.ServeHTTP(rec, r)
+
+			recorder.Record(key.ID, routePattern(r), rec.status)
+		})
+	}
+}
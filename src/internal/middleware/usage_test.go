@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"dZev1/character-gallery/internal/usage"
+	"dZev1/character-gallery/models/auth"
+)
+
+type recordingUsageStore struct {
+	mu      sync.Mutex
+	records []auth.UsageRecord
+	monthly uint64
+}
+
+func (s *recordingUsageStore) RecordUsage(records []auth.UsageRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func (s *recordingUsageStore) MonthlyUsage(id auth.APIKeyID, month time.Time) (uint64, error) {
+	return s.monthly, nil
+}
+
+func TestTrackUsage_RecordsRoutePatternAndStatus(t *testing.T) {
+	store := &recordingUsageStore{}
+	recorder := usage.NewRecorder(store, time.Hour)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /characters/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	handler := TrackUsage(recorder)(mux)
+
+	req := httptest.NewRequest(http.MethodGet, "/characters/7", nil)
+	req = req.WithContext(auth.WithAPIKey(req.Context(), &auth.APIKey{ID: 3}))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	recorder.Close()
+
+	if len(store.records) != 1 {
+		t.Fatalf("expected one usage record, got %d", len(store.records))
+	}
+
+	record := store.records[0]
+	if record.KeyID != 3 || record.Route != "GET /characters/{id}" || record.StatusClass != "4xx" || record.Count != 1 {
+		t.Fatalf("unexpected usage record: %+v", record)
+	}
+}
+
+func TestTrackUsage_QuotaExceeded(t *testing.T) {
+	store := &recordingUsageStore{monthly: 100}
+	recorder := usage.NewRecorder(store, time.Hour)
+	defer recorder.Close()
+
+	handler := TrackUsage(recorder)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("next handler should not be called")
+	}))
+
+	quota := uint64(100)
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req = req.WithContext(auth.WithAPIKey(req.Context(), &auth.APIKey{ID: 3, MonthlyQuota: &quota}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d", rec.Code)
+	}
+
+	if er := decodeError(t, rec); er.Code != "QUOTA_EXCEEDED" {
+		t.Fatalf("expected code QUOTA_EXCEEDED, got %s", er.Code)
+	}
+}
@@ -0,0 +1,33 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+// Handler serves doc as JSON. The document is encoded once, since it never changes at runtime.
+func Handler(doc *Document) http.Handler {
+	body, err := json.Marshal(doc)
+	if err != nil {
+		panic(err)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	})
+}
+
+// DocsHandler serves a page rendering the document served at /openapi.json.
+func DocsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write(docsPage)
+	})
+}
@@ -0,0 +1,110 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+
+	"dZev1/character-gallery/models/characters"
+)
+
+func TestBuild_Schemas(t *testing.T) {
+	doc := Build("/api/v1")
+	schemas := doc.Components.Schemas
+
+	character, ok := schemas["Character"]
+	if !ok {
+		t.Fatal("expected a Character schema")
+	}
+	if got := character.Properties["class"].Ref; got != "#/components/schemas/Class" {
+		t.Errorf("expected class to reference the Class enum, got %q", got)
+	}
+	if !slices.Contains(character.Required, "name") || slices.Contains(character.Required, "stats") {
+		t.Errorf("unexpected required fields: %v", character.Required)
+	}
+	if _, ok := character.Properties["id"]; !ok {
+		t.Error("expected an id property")
+	}
+
+	class := schemas["Class"]
+	if len(class.Enum) != len(characters.AllClasses) || class.Enum[0] != string(characters.Barbarian) {
+		t.Errorf("expected the Class enum to list every class, got %v", class.Enum)
+	}
+
+	item := schemas["Item"]
+	if slices.Contains(item.Required, "damage") || !slices.Contains(item.Required, "name") {
+		t.Errorf("unexpected required fields for Item: %v", item.Required)
+	}
+	if got := item.Properties["type"].Ref; got != "#/components/schemas/ItemType" {
+		t.Errorf("expected type to reference ItemType, got %q", got)
+	}
+
+	inventoryItem := schemas["InventoryItem"].Properties["item"]
+	if len(inventoryItem.AnyOf) != 2 || inventoryItem.AnyOf[0].Ref != "#/components/schemas/Item" {
+		t.Errorf("expected InventoryItem to reference a nullable Item, got %+v", inventoryItem)
+	}
+
+	details := schemas["ValidationError"].Properties["details"]
+	if details.Type != "array" || details.Items.Ref != "#/components/schemas/FieldError" {
+		t.Errorf("expected validation details to list FieldErrors, got %+v", details)
+	}
+
+	for _, name := range []string{"Error", "Pagination", "CreatedAPIKey", "Scope", "FieldError"} {
+		if _, ok := schemas[name]; !ok {
+			t.Errorf("expected a %s schema", name)
+		}
+	}
+}
+
+func TestBuild_PathParameters(t *testing.T) {
+	doc := Build("/api/v1")
+
+	op := doc.Paths["/characters/{character_id}/inventory/{item_id}"].Post
+	var names []string
+	for _, p := range op.Parameters {
+		names = append(names, p.Name)
+	}
+
+	if !slices.Equal(names, []string{"character_id", "item_id", "quantity"}) {
+		t.Errorf("unexpected parameters: %v", names)
+	}
+}
+
+func TestSchema_EmbeddedStructsAreFlattened(t *testing.T) {
+	type inner struct {
+		A string `json:"a"`
+	}
+	type outer struct {
+		*inner
+		B int `json:"b,omitempty"`
+	}
+
+	s := newSchemaGenerator().schema(reflect.TypeFor[struct{ outer }]())
+
+	if _, ok := s.Properties["a"]; !ok {
+		t.Errorf("expected embedded fields to be flattened, got %v", s.Properties)
+	}
+	if !slices.Equal(s.Required, []string{"a"}) {
+		t.Errorf("unexpected required fields: %v", s.Required)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Handler(Build("/api/v1")).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("expected valid JSON: %v", err)
+	}
+	if doc["openapi"] != "3.1.0" {
+		t.Errorf("unexpected openapi version %v", doc["openapi"])
+	}
+}
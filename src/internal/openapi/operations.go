@@ -0,0 +1,633 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+
+	"dZev1/character-gallery/handlers"
+	"dZev1/character-gallery/internal/graphql"
+	"dZev1/character-gallery/internal/seeding"
+	"dZev1/character-gallery/models/auth"
+	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/events"
+	"dZev1/character-gallery/models/inventory"
+	"dZev1/character-gallery/models/validation"
+	"dZev1/character-gallery/models/webhooks"
+)
+
+const (
+	apiKeySecurity  = "apiKey"
+	metricsSecurity = "metricsToken"
+)
+
+// builder keeps the schema generator around while operations are added to a document.
+type builder struct {
+	doc *Document
+	gen *schemaGenerator
+}
+
+// Build describes every route the server registers. baseRoute is the prefix API routes are
+// mounted under, such as /api/v1.
+func Build(baseRoute string) *Document {
+	gen := newSchemaGenerator()
+	registerEnum(gen, "BodyType", characters.AllBodyTypes)
+	registerEnum(gen, "Class", characters.AllClasses)
+	registerEnum(gen, "Species", characters.AllSpecies)
+	registerEnum(gen, "ItemType", inventory.AllTypes)
+	registerEnum(gen, "Scope", auth.AllScopes)
+	registerEnum(gen, "EventType", events.AllEventTypes)
+	registerEnum(gen, "DeliveryStatus", webhooks.AllDeliveryStatuses)
+	registerEnum(gen, "BatchOp", handlers.AllBatchOps)
+
+	b := &builder{
+		doc: &Document{
+			OpenAPI: "3.1.0",
+			Info: Info{
+				Title:       "Character Gallery API",
+				Version:     strings.TrimPrefix(baseRoute, "/api/"),
+				Description: "Create and manage characters, their inventories and the item pool.",
+			},
+			Servers:  []Server{{URL: baseRoute}},
+			Security: []map[string][]string{{apiKeySecurity: {}}},
+			Paths:    map[string]*PathItem{},
+			Components: Components{
+				Schemas: gen.components,
+				SecuritySchemes: map[string]*SecurityScheme{
+					apiKeySecurity: {
+						Type:        "apiKey",
+						In:          "header",
+						Name:        "X-API-Key",
+						Description: "Keys are issued with apikey_gen or the admin endpoints. Admin endpoints need the admin scope.",
+					},
+					metricsSecurity: {
+						Type:        "http",
+						Scheme:      "bearer",
+						Description: "Only required when the server sets METRICS_TOKEN.",
+					},
+				},
+			},
+		},
+		gen: gen,
+	}
+
+	b.characterOperations()
+	b.inventoryOperations()
+	b.itemOperations()
+	b.batchOperations()
+	b.adminOperations()
+	b.webhookOperations()
+	b.eventOperations()
+	b.graphqlOperations()
+	b.rootOperations()
+
+	return b.doc
+}
+
+func (b *builder) add(method, path string, op *Operation) {
+	item, ok := b.doc.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		b.doc.Paths[path] = item
+	}
+
+	for _, name := range pathParameters(path) {
+		op.Parameters = append([]Parameter{{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "integer", Format: "int64", Minimum: float(0)},
+		}}, op.Parameters...)
+	}
+
+	if op.Security == nil {
+		op.Responses["401"] = b.errorResponse("Missing, invalid, revoked or expired API key")
+		op.Responses["429"] = b.errorResponse("Rate limit or monthly quota exceeded")
+		op.Responses["503"] = b.errorResponse("The database is unavailable, try again later")
+	}
+
+	switch method {
+	case http.MethodGet:
+		item.Get = op
+	case http.MethodPut:
+		item.Put = op
+	case http.MethodPost:
+		item.Post = op
+	case http.MethodDelete:
+		item.Delete = op
+	}
+}
+
+// pathParameters returns the wildcards of a ServeMux path, in reverse order so prepending them
+// one by one keeps them in path order.
+func pathParameters(path string) []string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			names = append([]string{strings.Trim(segment, "{}")}, names...)
+		}
+	}
+	return names
+}
+
+func (b *builder) schemaFor(t reflect.Type) *Schema {
+	return b.gen.schema(t)
+}
+
+func (b *builder) jsonBody(t reflect.Type) *RequestBody {
+	return &RequestBody{
+		Required: true,
+		Content:  map[string]*MediaType{"application/json": {Schema: b.schemaFor(t)}},
+	}
+}
+
+func (b *builder) jsonResponse(description string, t reflect.Type) *Response {
+	return &Response{
+		Description: description,
+		Content:     map[string]*MediaType{"application/json": {Schema: b.schemaFor(t)}},
+	}
+}
+
+// errorResponse describes an error written by handlers.ThrowError, in either of its formats.
+func (b *builder) errorResponse(description string) *Response {
+	return b.errorResponseOf(description, reflect.TypeFor[handlers.Error]())
+}
+
+func (b *builder) errorResponseOf(description string, t reflect.Type) *Response {
+	response := b.jsonResponse(description, t)
+	response.Content["application/problem+json"] = &MediaType{Schema: b.schemaFor(reflect.TypeFor[handlers.Problem]())}
+	return response
+}
+
+// validationErrorResponse describes the handlers.Error written with status 422, whose details
+// list every invalid field.
+func (b *builder) validationErrorResponse(description string) *Response {
+	type ValidationError struct {
+		Error   string                  `json:"error"`
+		Code    string                  `json:"code"`
+		Details []validation.FieldError `json:"details"`
+	}
+	return b.errorResponseOf(description, reflect.TypeFor[ValidationError]())
+}
+
+func queryParameter(name, description string, required bool, schema *Schema) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Required: required, Schema: schema}
+}
+
+func (b *builder) characterOperations() {
+	character := reflect.TypeFor[characters.Character]()
+
+	b.add(http.MethodPost, "/characters", b.requires(auth.ScopeWrite, &Operation{
+		OperationID: "createCharacter",
+		Summary:     "Create a character",
+		Tags:        []string{"Characters"},
+		RequestBody: b.jsonBody(character),
+		Responses: map[string]*Response{
+			"201": b.jsonResponse("The created character", character),
+			"400": b.errorResponse("Invalid request body"),
+			"422": b.validationErrorResponse("Invalid character fields"),
+			"500": b.errorResponse("The character could not be stored"),
+		},
+	}))
+
+	b.add(http.MethodGet, "/characters", b.requires(auth.ScopeRead, &Operation{
+		OperationID: "listCharacters",
+		Summary:     "List characters, a page at a time (20 per page unless configured)",
+		Tags:        []string{"Characters"},
+		Parameters: []Parameter{
+			queryParameter("page", "Zero based page number", false, &Schema{Type: "integer", Minimum: float(0)}),
+		},
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("A page of characters", reflect.TypeFor[struct {
+				Data       []characters.Character `json:"data"`
+				Pagination handlers.Pagination    `json:"pagination"`
+			}]()),
+			"400": b.errorResponse("Invalid page number"),
+			"500": b.errorResponse("The characters could not be retrieved"),
+		},
+	}))
+
+	b.add(http.MethodGet, "/characters/{id}", b.requires(auth.ScopeRead, &Operation{
+		OperationID: "getCharacter",
+		Summary:     "Get a character",
+		Tags:        []string{"Characters"},
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("The character", character),
+			"400": b.errorResponse("Invalid ID"),
+			"404": b.errorResponse("Character not found"),
+		},
+	}))
+
+	b.add(http.MethodPut, "/characters/{id}", b.requires(auth.ScopeWrite, &Operation{
+		OperationID: "editCharacter",
+		Summary:     "Replace a character",
+		Tags:        []string{"Characters"},
+		RequestBody: b.jsonBody(character),
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("The updated character", character),
+			"400": b.errorResponse("Invalid ID or request body"),
+			"422": b.validationErrorResponse("Invalid character fields"),
+			"404": b.errorResponse("Character not found"),
+		},
+	}))
+
+	b.add(http.MethodDelete, "/characters/{id}", b.requires(auth.ScopeWrite, &Operation{
+		OperationID: "deleteCharacter",
+		Summary:     "Delete a character",
+		Tags:        []string{"Characters"},
+		Responses: map[string]*Response{
+			"200": {Description: "The character was deleted"},
+			"400": b.errorResponse("Invalid ID"),
+			"404": b.errorResponse("Character not found"),
+		},
+	}))
+}
+
+func (b *builder) inventoryOperations() {
+	quantity := queryParameter("quantity", "How many units to add or remove", true, &Schema{Type: "integer", Minimum: float(1), Maximum: float(255)})
+
+	b.add(http.MethodPost, "/characters/{character_id}/inventory/{item_id}", b.requires(auth.ScopeWrite, &Operation{
+		OperationID: "addItemToCharacter",
+		Summary:     "Add an item to a character's inventory",
+		Tags:        []string{"Inventory"},
+		Parameters:  []Parameter{quantity},
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("The inventory entry after adding the item", reflect.TypeFor[inventory.InventoryItem]()),
+			"400": b.errorResponse("Invalid character ID, item ID or quantity"),
+			"404": b.errorResponse("Character or item not found"),
+			"500": b.errorResponse("The item could not be added"),
+		},
+	}))
+
+	b.add(http.MethodDelete, "/characters/{character_id}/inventory/{item_id}", b.requires(auth.ScopeWrite, &Operation{
+		OperationID: "removeItemFromCharacter",
+		Summary:     "Remove an item from a character's inventory",
+		Tags:        []string{"Inventory"},
+		Parameters:  []Parameter{quantity},
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("The removed item", reflect.TypeFor[inventory.Item]()),
+			"400": b.errorResponse("Invalid character ID, item ID or quantity"),
+			"404": b.errorResponse("The character doesn't hold the item"),
+			"500": b.errorResponse("The item could not be removed"),
+		},
+	}))
+
+	b.add(http.MethodGet, "/characters/{character_id}/inventory", b.requires(auth.ScopeRead, &Operation{
+		OperationID: "getCharacterInventory",
+		Summary:     "Get a character's inventory",
+		Tags:        []string{"Inventory"},
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("The items the character holds", reflect.TypeFor[[]inventory.InventoryItem]()),
+			"400": b.errorResponse("Invalid character ID"),
+			"500": b.errorResponse("The inventory could not be retrieved"),
+		},
+	}))
+}
+
+func (b *builder) itemOperations() {
+	item := reflect.TypeFor[inventory.Item]()
+
+	b.add(http.MethodGet, "/items", b.requires(auth.ScopeRead, &Operation{
+		OperationID: "listItems",
+		Summary:     "Get the item pool",
+		Tags:        []string{"Items"},
+		Parameters: []Parameter{
+			queryParameter("pack", "Only return the items of this content pack namespace", false, &Schema{Type: "string"}),
+		},
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("The items in the pool", reflect.TypeFor[[]inventory.Item]()),
+			"500": b.errorResponse("The item pool could not be retrieved"),
+		},
+	}))
+
+	b.add(http.MethodPost, "/items", b.requires(auth.ScopeWrite, &Operation{
+		OperationID: "createItem",
+		Summary:     "Add an item to the pool",
+		Tags:        []string{"Items"},
+		RequestBody: b.jsonBody(item),
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("The created item", item),
+			"400": b.errorResponse("Invalid request body"),
+			"409": b.errorResponse("An item with the same name and rarity exists"),
+			"422": b.validationErrorResponse("Invalid item fields"),
+			"500": b.errorResponse("The item could not be stored"),
+		},
+	}))
+
+	b.add(http.MethodGet, "/items/{item_id}", b.requires(auth.ScopeRead, &Operation{
+		OperationID: "getItem",
+		Summary:     "Get an item from the pool",
+		Tags:        []string{"Items"},
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("The item", item),
+			"400": b.errorResponse("Invalid item ID"),
+			"404": b.errorResponse("Item not found"),
+		},
+	}))
+}
+
+func (b *builder) batchOperations() {
+	type BatchFailureError struct {
+		Error   string                `json:"error"`
+		Code    string                `json:"code"`
+		Details handlers.BatchFailure `json:"details"`
+	}
+	failure := reflect.TypeFor[BatchFailureError]()
+
+	b.add(http.MethodPost, "/batch", b.requires(auth.ScopeWrite, &Operation{
+		OperationID: "runBatch",
+		Summary:     "Make several writes in order, in one transaction",
+		Description: "Operations create, edit and add items to characters, remove their items, and create items. " +
+			"An operation creating a character or an item can name it with a ref, which later operations give as character_ref or item_ref instead of an ID. " +
+			"Every operation is validated before anything is written. When one fails, none of the writes of the batch is kept, and no event is sent for them.",
+		Tags:        []string{"Batch"},
+		RequestBody: b.jsonBody(reflect.TypeFor[handlers.BatchRequest]()),
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("The result of every operation, in order", reflect.TypeFor[handlers.BatchResponse]()),
+			"400": b.errorResponse("Invalid request body"),
+			"404": b.errorResponseOf("A character or item the failed operation refers to was not found", failure),
+			"409": b.errorResponseOf("The failed operation conflicts with a stored record", failure),
+			"422": b.validationErrorResponse("Invalid operations, with paths such as operations[2].character.name"),
+			"500": b.errorResponseOf("The batch could not be stored", failure),
+		},
+	}))
+}
+
+// requires documents that op needs an API key with scope.
+func (b *builder) requires(scope auth.Scope, op *Operation) *Operation {
+	op.Responses["403"] = b.errorResponse("The API key lacks the " + scope.String() + " scope")
+	return op
+}
+
+// adminOnly tags op and documents that it needs the admin scope.
+func (b *builder) adminOnly(tag string, op *Operation) *Operation {
+	op.Tags = []string{tag}
+	op.Description = "Requires an API key with the admin scope."
+	return b.requires(auth.ScopeAdmin, op)
+}
+
+func (b *builder) adminOperations() {
+	created := reflect.TypeFor[handlers.CreatedAPIKey]()
+
+	admin := func(op *Operation) *Operation {
+		return b.adminOnly("API Keys", op)
+	}
+
+	b.add(http.MethodGet, "/admin/api-keys", admin(&Operation{
+		OperationID: "listAPIKeys",
+		Summary:     "List API keys",
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("Every API key", reflect.TypeFor[[]auth.APIKey]()),
+			"500": b.errorResponse("The keys could not be retrieved"),
+		},
+	}))
+
+	b.add(http.MethodPost, "/admin/api-keys", admin(&Operation{
+		OperationID: "createAPIKey",
+		Summary:     "Create an API key",
+		RequestBody: b.jsonBody(reflect.TypeFor[handlers.CreateAPIKeyRequest]()),
+		Responses: map[string]*Response{
+			"201": b.jsonResponse("The new key. The raw key is only ever returned here.", created),
+			"400": b.errorResponse("Invalid name, scopes or expiry"),
+			"500": b.errorResponse("The key could not be created"),
+		},
+	}))
+
+	b.add(http.MethodPost, "/admin/api-keys/{id}/revoke", admin(&Operation{
+		OperationID: "revokeAPIKey",
+		Summary:     "Revoke an API key",
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("The key was revoked", reflect.TypeFor[struct {
+				ID       auth.APIKeyID `json:"id"`
+				IsActive bool          `json:"is_active"`
+			}]()),
+			"400": b.errorResponse("Invalid ID"),
+			"404": b.errorResponse("API key not found"),
+		},
+	}))
+
+	b.add(http.MethodPost, "/admin/api-keys/{id}/rotate", admin(&Operation{
+		OperationID: "rotateAPIKey",
+		Summary:     "Replace an API key, expiring the old one after a grace period",
+		Parameters: []Parameter{
+			queryParameter("grace", "How long the old key keeps working, as a Go duration. Defaults to 24h.", false, &Schema{Type: "string"}),
+		},
+		Responses: map[string]*Response{
+			"201": b.jsonResponse("The replacement key", reflect.TypeFor[struct {
+				handlers.CreatedAPIKey
+				ReplacedID auth.APIKeyID `json:"replaced_id"`
+			}]()),
+			"400": b.errorResponse("Invalid ID or grace period"),
+			"404": b.errorResponse("API key not found"),
+			"409": b.errorResponse("API key is revoked or expired"),
+		},
+	}))
+
+	b.add(http.MethodGet, "/admin/api-keys/{id}/usage", admin(&Operation{
+		OperationID: "getAPIKeyUsage",
+		Summary:     "Get the daily usage of an API key",
+		Parameters: []Parameter{
+			queryParameter("from", "First day, as YYYY-MM-DD. Defaults to the start of the month.", false, &Schema{Type: "string", Format: "date"}),
+			queryParameter("to", "Last day, as YYYY-MM-DD. Defaults to today.", false, &Schema{Type: "string", Format: "date"}),
+		},
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("Request counts per day, route and status class", reflect.TypeFor[struct {
+				KeyID        auth.APIKeyID      `json:"key_id"`
+				From         string             `json:"from"`
+				To           string             `json:"to"`
+				Total        uint64             `json:"total"`
+				MonthlyQuota *uint64            `json:"monthly_quota,omitempty"`
+				Usage        []auth.UsageRecord `json:"usage"`
+			}]()),
+			"400": b.errorResponse("Invalid ID or dates"),
+			"404": b.errorResponse("API key not found"),
+		},
+	}))
+
+	b.add(http.MethodPut, "/admin/api-keys/{id}/quota", admin(&Operation{
+		OperationID: "setAPIKeyQuota",
+		Summary:     "Set the monthly request quota of an API key",
+		RequestBody: b.jsonBody(reflect.TypeFor[struct {
+			MonthlyQuota uint64 `json:"monthly_quota"`
+		}]()),
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("The quota was set. 0 means unlimited.", reflect.TypeFor[struct {
+				ID           auth.APIKeyID `json:"id"`
+				MonthlyQuota uint64        `json:"monthly_quota"`
+			}]()),
+			"400": b.errorResponse("Invalid ID or quota"),
+			"404": b.errorResponse("API key not found"),
+		},
+	}))
+
+	b.add(http.MethodGet, "/admin/item-pool/status", b.adminOnly("Items", &Operation{
+		OperationID: "getItemPoolStatus",
+		Summary:     "Get the outcome of the last item pool reload",
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("The last reload. A failed one kept the pool applied at applied_at.", reflect.TypeFor[seeding.Status]()),
+			"404": b.errorResponse("The item pool has not been seeded yet"),
+		},
+	}))
+}
+
+func (b *builder) webhookOperations() {
+	admin := func(op *Operation) *Operation {
+		return b.adminOnly("Webhooks", op)
+	}
+
+	b.add(http.MethodGet, "/admin/webhooks", admin(&Operation{
+		OperationID: "listWebhooks",
+		Summary:     "List webhook subscriptions",
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("Every subscription. Secrets are never returned.", reflect.TypeFor[[]webhooks.Subscription]()),
+			"500": b.errorResponse("The subscriptions could not be retrieved"),
+		},
+	}))
+
+	b.add(http.MethodPost, "/admin/webhooks", admin(&Operation{
+		OperationID: "createWebhook",
+		Summary:     "Subscribe a URL to gallery events",
+		RequestBody: b.jsonBody(reflect.TypeFor[handlers.CreateWebhookRequest]()),
+		Responses: map[string]*Response{
+			"201": b.jsonResponse("The new subscription. The signing secret is only ever returned here.", reflect.TypeFor[handlers.CreatedWebhook]()),
+			"400": b.errorResponse("Malformed request body"),
+			"422": b.validationErrorResponse("Invalid URL or event types"),
+		},
+	}))
+
+	b.add(http.MethodDelete, "/admin/webhooks/{id}", admin(&Operation{
+		OperationID: "deleteWebhook",
+		Summary:     "Delete a webhook subscription and its deliveries",
+		Responses: map[string]*Response{
+			"204": {Description: "The subscription was deleted"},
+			"400": b.errorResponse("Invalid ID"),
+			"404": b.errorResponse("Webhook not found"),
+		},
+	}))
+
+	b.add(http.MethodGet, "/admin/webhooks/{id}/deliveries", admin(&Operation{
+		OperationID: "listWebhookDeliveries",
+		Summary:     "List the latest deliveries of a subscription",
+		Parameters: []Parameter{
+			queryParameter("status", "Only deliveries in this status.", false, b.schemaFor(reflect.TypeFor[webhooks.DeliveryStatus]())),
+			queryParameter("limit", "How many deliveries to return, newest first. Defaults to 50.", false, &Schema{Type: "integer", Minimum: float(1), Maximum: float(500)}),
+		},
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("The deliveries, newest first", reflect.TypeFor[[]webhooks.Delivery]()),
+			"400": b.errorResponse("Invalid ID, status or limit"),
+			"404": b.errorResponse("Webhook not found"),
+		},
+	}))
+
+	b.add(http.MethodGet, "/admin/webhooks/{id}/deliveries/{delivery}", admin(&Operation{
+		OperationID: "getWebhookDelivery",
+		Summary:     "Get a delivery and the log of its attempts",
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("The delivery and every attempt made", reflect.TypeFor[struct {
+				Delivery *webhooks.Delivery `json:"delivery"`
+				Attempts []webhooks.Attempt `json:"attempts"`
+			}]()),
+			"400": b.errorResponse("Invalid ID"),
+			"404": b.errorResponse("Delivery not found"),
+		},
+	}))
+
+	b.add(http.MethodPost, "/admin/webhooks/{id}/deliveries/{delivery}/retry", admin(&Operation{
+		OperationID: "retryWebhookDelivery",
+		Summary:     "Queue a delivery again, such as a dead-lettered one",
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("The delivery, pending again with its attempts reset", reflect.TypeFor[webhooks.Delivery]()),
+			"400": b.errorResponse("Invalid ID"),
+			"404": b.errorResponse("Delivery not found"),
+		},
+	}))
+}
+
+func (b *builder) eventOperations() {
+	b.add(http.MethodGet, "/events", b.requires(auth.ScopeRead, &Operation{
+		OperationID: "streamEvents",
+		Summary:     "Stream gallery changes as server-sent events",
+		Description: "Every event carries its ID, type and the JSON of the event as data, the same one webhooks receive. " +
+			"A client reconnecting with Last-Event-ID is first sent the events it missed, or a reset event when they are no longer buffered. " +
+			"Idle streams receive a heartbeat comment.",
+		Tags: []string{"Events"},
+		Parameters: []Parameter{
+			queryParameter("types", "Comma separated event types to receive. Defaults to every type.", false, &Schema{Type: "string"}),
+			queryParameter("character_id", "Only the events about this character.", false, &Schema{Type: "integer", Format: "int64", Minimum: float(1)}),
+			{Name: "Last-Event-ID", In: "header", Description: "The ID of the last event received, to resume from.", Schema: &Schema{Type: "string"}},
+		},
+		Responses: map[string]*Response{
+			"200": {Description: "An endless text/event-stream", Content: map[string]*MediaType{"text/event-stream": {Schema: &Schema{Type: "string"}}}},
+			"400": b.errorResponse("Invalid event types or character ID"),
+		},
+	}))
+}
+
+func (b *builder) graphqlOperations() {
+	type GraphQLRequest graphql.Request
+	type GraphQLError struct {
+		Message    string         `json:"message"`
+		Path       []any          `json:"path,omitempty"`
+		Extensions map[string]any `json:"extensions,omitempty"`
+	}
+	type GraphQLResponse struct {
+		Data   map[string]any `json:"data"`
+		Errors []GraphQLError `json:"errors,omitempty"`
+	}
+
+	b.add(http.MethodPost, "/graphql", &Operation{
+		OperationID: "graphql",
+		Summary:     "Run a GraphQL query or mutation",
+		Description: "Characters, their inventories and the item pool can be read, and written, in one request. " +
+			"Failed fields are listed in errors, with the code and details the REST API would answer in their extensions, " +
+			"and the response is still sent with 200. Queries need the read scope and mutations the write scope, " +
+			"which fields lacking them fail with INSUFFICIENT_SCOPE.",
+		Tags:        []string{"GraphQL"},
+		RequestBody: b.jsonBody(reflect.TypeFor[GraphQLRequest]()),
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("The data resolved and the errors of the fields that failed", reflect.TypeFor[GraphQLResponse]()),
+			"400": b.errorResponse("Invalid request body or missing query"),
+		},
+	})
+}
+
+// rootOperations describes the routes served outside the API prefix and its API key check.
+func (b *builder) rootOperations() {
+	root := []Server{{URL: "/"}}
+	public := &[]map[string][]string{}
+
+	b.add(http.MethodGet, "/metrics", &Operation{
+		OperationID: "getMetrics",
+		Summary:     "Prometheus metrics",
+		Tags:        []string{"Operations"},
+		Security:    &[]map[string][]string{{metricsSecurity: {}}, {}},
+		Responses: map[string]*Response{
+			"200": {Description: "Metrics in the Prometheus text format", Content: map[string]*MediaType{"text/plain": {Schema: &Schema{Type: "string"}}}},
+			"401": {Description: "Missing or wrong metrics token"},
+		},
+	})
+
+	b.add(http.MethodGet, "/openapi.json", &Operation{
+		OperationID: "getOpenAPI",
+		Summary:     "This document",
+		Tags:        []string{"Operations"},
+		Security:    public,
+		Responses: map[string]*Response{
+			"200": {Description: "The OpenAPI document", Content: map[string]*MediaType{"application/json": {Schema: &Schema{Type: "object"}}}},
+		},
+	})
+
+	b.add(http.MethodGet, "/docs", &Operation{
+		OperationID: "getDocs",
+		Summary:     "Browsable API reference",
+		Tags:        []string{"Operations"},
+		Security:    public,
+		Responses: map[string]*Response{
+			"200": {Description: "An HTML page rendering this document", Content: map[string]*MediaType{"text/html": {Schema: &Schema{Type: "string"}}}},
+		},
+	})
+
+	for _, path := range []string{"/metrics", "/openapi.json", "/docs"} {
+		b.doc.Paths[path].Servers = root
+	}
+}
@@ -0,0 +1,149 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+type enum struct {
+	name   string
+	values []string
+}
+
+// schemaGenerator derives JSON schemas from Go types through their json tags. Named structs and
+// registered enums become components referenced by name.
+type schemaGenerator struct {
+	components map[string]*Schema
+	enums      map[reflect.Type]enum
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{
+		components: map[string]*Schema{},
+		enums:      map[reflect.Type]enum{},
+	}
+}
+
+// registerEnum makes every field of type T a reference to a string enum named name.
+func registerEnum[T ~string](g *schemaGenerator, name string, values []T) {
+	strs := make([]string, len(values))
+	for i, v := range values {
+		strs[i] = string(v)
+	}
+	g.enums[reflect.TypeFor[T]()] = enum{name: name, values: strs}
+}
+
+func (g *schemaGenerator) ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) *Schema {
+	if e, ok := g.enums[t]; ok {
+		if _, done := g.components[e.name]; !done {
+			g.components[e.name] = &Schema{Type: "string", Enum: e.values}
+		}
+		return g.ref(e.name)
+	}
+
+	if t == reflect.TypeFor[time.Time]() {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	// Raw JSON, such as json.RawMessage, can hold any value.
+	if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 && t.Implements(reflect.TypeFor[json.Marshaler]()) {
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint8:
+		return &Schema{Type: "integer", Minimum: float(0), Maximum: float(255)}
+	case reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64", Minimum: float(0)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		if _, done := g.components[t.Name()]; !done {
+			// Reserve the name first so recursive types terminate.
+			g.components[t.Name()] = &Schema{}
+			*g.components[t.Name()] = *g.object(t)
+		}
+		return g.ref(t.Name())
+	}
+
+	panic(fmt.Sprintf("openapi: no schema for %s", t))
+}
+
+func (g *schemaGenerator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.addFields(s, t)
+	return s
+}
+
+func (g *schemaGenerator) addFields(s *Schema, t reflect.Type) {
+	for field := range t.Fields() {
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			g.addFields(s, embedded)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := g.schema(field.Type)
+		switch {
+		case strings.Contains(opts, "omitempty"):
+		case field.Type.Kind() == reflect.Pointer:
+			property = nullable(property)
+		default:
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = property
+	}
+}
+
+// nullable allows null on top of s, as pointers without omitempty are encoded.
+func nullable(s *Schema) *Schema {
+	if s.Ref != "" {
+		return &Schema{AnyOf: []*Schema{s, {Type: "null"}}}
+	}
+	s.Type = []string{s.Type.(string), "null"}
+	return s
+}
+
+func float(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,182 @@
+package outbound
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"dZev1/character-gallery/models/webhooks"
+)
+
+// Backoff spaces the attempts of a delivery: the first retry waits Base, and every later one
+// twice as long as the previous, up to Max. A delivery that failed MaxAttempts times is dead.
+type Backoff struct {
+	Base        time.Duration
+	Max         time.Duration
+	MaxAttempts int
+}
+
+var DefaultBackoff = Backoff{Base: 30 * time.Second, Max: time.Hour, MaxAttempts: 8}
+
+// Delay returns how long to wait after the given failed attempt, counted from 1.
+func (b Backoff) Delay(attempt int) time.Duration {
+	delay := b.Base
+	for i := 1; i < attempt && delay < b.Max; i++ {
+		delay *= 2
+	}
+	return min(delay, b.Max)
+}
+
+// batchSize is how many deliveries are claimed at once.
+const batchSize = 20
+
+// Dispatcher posts the queued deliveries every interval until Close is called. Deliveries are
+// claimed from the store, so any number of replicas can run one.
+type Dispatcher struct {
+	store    webhooks.WebhookStore
+	client   *http.Client
+	backoff  Backoff
+	interval time.Duration
+	now      func() time.Time
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewDispatcher starts a dispatcher that gives each post timeout to complete.
+func NewDispatcher(store webhooks.WebhookStore, interval, timeout time.Duration, backoff Backoff) *Dispatcher {
+	d := newDispatcher(store, &http.Client{
+		Timeout: timeout,
+		// A redirect is reported as a failure instead of posting the event somewhere else.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}, backoff)
+	d.interval = interval
+	go d.run()
+	return d
+}
+
+func newDispatcher(store webhooks.WebhookStore, client *http.Client, backoff Backoff) *Dispatcher {
+	return &Dispatcher{
+		store:   store,
+		client:  client,
+		backoff: backoff,
+		now:     time.Now,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.Dispatch(context.Background())
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// Dispatch posts every delivery that is due and returns how many were attempted.
+func (d *Dispatcher) Dispatch(ctx context.Context) int {
+	// A claim outlives the posts of its batch, so that a slow batch isn't claimed twice.
+	lease := time.Duration(batchSize)*d.client.Timeout + time.Minute
+
+	attempted := 0
+	for {
+		due, err := d.store.ClaimDeliveries(ctx, batchSize, lease)
+		if err != nil {
+			slog.ErrorContext(ctx, "could not claim webhook deliveries", "error", err)
+			return attempted
+		}
+
+		for _, delivery := range due {
+			d.deliver(ctx, delivery)
+		}
+		attempted += len(due)
+
+		if len(due) < batchSize {
+			return attempted
+		}
+	}
+}
+
+// deliver posts one delivery and records the attempt in the delivery log.
+func (d *Dispatcher) deliver(ctx context.Context, delivery webhooks.DueDelivery) {
+	start := d.now()
+	statusCode, err := d.post(ctx, delivery, start)
+
+	attempt := &webhooks.Attempt{
+		DeliveryID: delivery.ID,
+		DurationMS: d.now().Sub(start).Milliseconds(),
+	}
+	if statusCode != 0 {
+		attempt.StatusCode = &statusCode
+	}
+
+	outcome := webhooks.Outcome{Status: webhooks.DeliveryDone}
+	if err != nil {
+		attempt.Error = err.Error()
+		attempts := delivery.Attempts + 1
+		if attempts >= d.backoff.MaxAttempts {
+			outcome.Status = webhooks.DeliveryDead
+			slog.WarnContext(ctx, "webhook delivery failed for the last time",
+				"delivery_id", delivery.ID, "subscription_id", delivery.SubscriptionID, "attempts", attempts, "error", err)
+		} else {
+			outcome = webhooks.Outcome{Status: webhooks.DeliveryPending, RetryIn: d.backoff.Delay(attempts)}
+		}
+	}
+
+	if err := d.store.RecordAttempt(ctx, attempt, outcome); err != nil {
+		slog.ErrorContext(ctx, "could not record webhook attempt", "delivery_id", delivery.ID, "error", err)
+	}
+}
+
+// post sends the payload signed with the subscription secret. Any status other than 2xx fails
+// the attempt.
+func (d *Dispatcher) post(ctx context.Context, delivery webhooks.DueDelivery, sentAt time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "character-gallery-webhooks")
+	req.Header.Set(webhooks.HeaderEvent, delivery.EventType.String())
+	req.Header.Set(webhooks.HeaderDelivery, delivery.ID.String())
+	req.Header.Set(webhooks.HeaderTimestamp, strconv.FormatInt(sentAt.Unix(), 10))
+	req.Header.Set(webhooks.HeaderSignature, webhooks.Sign(delivery.Secret, sentAt, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Close stops the dispatcher. Deliveries being posted are finished first.
+func (d *Dispatcher) Close() {
+	d.closeOnce.Do(func() {
+		close(d.stop)
+	})
+	<-d.done
+}
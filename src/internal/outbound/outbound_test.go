@@ -0,0 +1,128 @@
+package outbound
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"dZev1/character-gallery/models/events"
+	"dZev1/character-gallery/models/webhooks"
+)
+
+// fakeStore implements the methods these tests call; anything else panics through the nil
+// embedded interface.
+type fakeStore struct {
+	webhooks.WebhookStore
+	due      []webhooks.DueDelivery
+	queued   []*events.Event
+	attempts []webhooks.Attempt
+	outcomes []webhooks.Outcome
+}
+
+func (s *fakeStore) Enqueue(ctx context.Context, event *events.Event) (int64, error) {
+	s.queued = append(s.queued, event)
+	return 1, nil
+}
+
+func (s *fakeStore) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]webhooks.DueDelivery, error) {
+	due := s.due
+	s.due = nil
+	return due, nil
+}
+
+func (s *fakeStore) RecordAttempt(ctx context.Context, attempt *webhooks.Attempt, outcome webhooks.Outcome) error {
+	s.attempts = append(s.attempts, *attempt)
+	s.outcomes = append(s.outcomes, outcome)
+	return nil
+}
+
+func dueDelivery(url string, attempts int) webhooks.DueDelivery {
+	return webhooks.DueDelivery{
+		Delivery: webhooks.Delivery{
+			ID:        7,
+			EventType: events.CharacterCreated,
+			Payload:   webhooks.Payload(`{"id":"evt_1","type":"character.created"}`),
+			Attempts:  attempts,
+		},
+		URL:    url,
+		Secret: "whsec_test",
+	}
+}
+
+func TestDispatch_SignsDeliveries(t *testing.T) {
+	var verifyErr error
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verifyErr = webhooks.Verify("whsec_test", r.Header.Get(webhooks.HeaderTimestamp), r.Header.Get(webhooks.HeaderSignature), body)
+		if r.Header.Get(webhooks.HeaderEvent) != "character.created" || r.Header.Get(webhooks.HeaderDelivery) != "7" {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	store := &fakeStore{due: []webhooks.DueDelivery{dueDelivery(receiver.URL, 0)}}
+	if n := newDispatcher(store, receiver.Client(), DefaultBackoff).Dispatch(context.Background()); n != 1 {
+		t.Fatalf("expected 1 delivery, got %d", n)
+	}
+
+	if verifyErr != nil {
+		t.Errorf("receiver could not verify the signature: %v", verifyErr)
+	}
+	if store.outcomes[0].Status != webhooks.DeliveryDone || *store.attempts[0].StatusCode != http.StatusNoContent {
+		t.Errorf("expected a successful attempt, got %+v %+v", store.attempts[0], store.outcomes[0])
+	}
+}
+
+func TestDispatch_RetriesWithBackoffThenDeadLetters(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	backoff := Backoff{Base: time.Second, Max: time.Minute, MaxAttempts: 3}
+	store := &fakeStore{due: []webhooks.DueDelivery{dueDelivery(receiver.URL, 1)}}
+	dispatcher := newDispatcher(store, receiver.Client(), backoff)
+	dispatcher.Dispatch(context.Background())
+
+	if outcome := store.outcomes[0]; outcome.Status != webhooks.DeliveryPending || outcome.RetryIn != 2*time.Second {
+		t.Errorf("expected a retry in 2s after the second attempt, got %+v", outcome)
+	}
+	if store.attempts[0].Error != "unexpected status 500 Internal Server Error" {
+		t.Errorf("unexpected attempt error %q", store.attempts[0].Error)
+	}
+
+	store.due = []webhooks.DueDelivery{dueDelivery(receiver.URL, 2)}
+	dispatcher.Dispatch(context.Background())
+	if outcome := store.outcomes[1]; outcome.Status != webhooks.DeliveryDead {
+		t.Errorf("expected the third failure to dead-letter the delivery, got %+v", outcome)
+	}
+}
+
+func TestBackoff_Delay(t *testing.T) {
+	backoff := Backoff{Base: 30 * time.Second, Max: 5 * time.Minute}
+	for attempt, want := range map[int]time.Duration{1: 30 * time.Second, 2: time.Minute, 4: 4 * time.Minute, 5: 5 * time.Minute, 40: 5 * time.Minute} {
+		if got := backoff.Delay(attempt); got != want {
+			t.Errorf("attempt %d: expected %s, got %s", attempt, want, got)
+		}
+	}
+}
+
+func TestQueue_EnqueuesEvents(t *testing.T) {
+	store := &fakeStore{}
+	event, err := events.NewEvent(events.CharacterDeleted, events.DeletedCharacter{ID: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (Queue{Store: store}).Deliver(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(store.queued) != 1 || store.queued[0] != event {
+		t.Errorf("expected the event to be queued, got %+v", store.queued)
+	}
+}
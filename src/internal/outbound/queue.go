@@ -0,0 +1,20 @@
+// Package outbound posts gallery events to webhook subscribers.
+package outbound
+
+import (
+	"context"
+
+	"dZev1/character-gallery/models/events"
+	"dZev1/character-gallery/models/webhooks"
+)
+
+// Queue is the outbox sink that queues every event for the webhook subscribers to its type. An
+// event handed over again is only queued once per subscription.
+type Queue struct {
+	Store webhooks.WebhookStore
+}
+
+func (q Queue) Deliver(ctx context.Context, event *events.Event) error {
+	_, err := q.Store.Enqueue(ctx, event)
+	return err
+}
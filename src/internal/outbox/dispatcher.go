@@ -0,0 +1,182 @@
+// Package outbox delivers the events the gallery writes to its outbox to the sinks that want
+// them: webhook subscribers, event streams and the log.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"dZev1/character-gallery/internal/outbound"
+	"dZev1/character-gallery/models/events"
+)
+
+// Sink receives the events read from the outbox. When some sinks fail an event, it is retried
+// with backoff for those sinks only. An event is still delivered at least once, since a
+// dispatcher that stops between delivering and recording it hands it over again.
+type Sink interface {
+	Deliver(ctx context.Context, event *events.Event) error
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(ctx context.Context, event *events.Event) error
+
+func (f SinkFunc) Deliver(ctx context.Context, event *events.Event) error {
+	return f(ctx, event)
+}
+
+// LogSink logs every event delivered.
+func LogSink(logger *slog.Logger) Sink {
+	return SinkFunc(func(ctx context.Context, event *events.Event) error {
+		logger.InfoContext(ctx, "event delivered", "event_id", event.ID, "event_type", event.Type, "character_id", event.CharacterID)
+		return nil
+	})
+}
+
+// batchSize is how many events are read from the outbox at once.
+const batchSize = 100
+
+// lease is how long the events of a batch are kept from other dispatchers while they are
+// delivered. Sinks only queue, stream or log them, so a batch takes far less.
+const lease = time.Minute
+
+// pruneInterval is how often delivered events older than the retention are deleted.
+const pruneInterval = time.Hour
+
+// Dispatcher reads the outbox every interval and delivers what it finds to its sinks, until
+// Close is called. Events are claimed while they are delivered, so any number of replicas can
+// run one. An event that failed backoff.MaxAttempts times is dead.
+type Dispatcher struct {
+	store     events.OutboxStore
+	sinks     map[string]Sink
+	backoff   outbound.Backoff
+	interval  time.Duration
+	retention time.Duration
+	now       func() time.Time
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewDispatcher starts a dispatcher delivering to the named sinks, which prunes the events
+// delivered longer than retention ago.
+func NewDispatcher(store events.OutboxStore, sinks map[string]Sink, interval, retention time.Duration, backoff outbound.Backoff) *Dispatcher {
+	d := newDispatcher(store, sinks, retention, backoff)
+	d.interval = interval
+	go d.run()
+	return d
+}
+
+func newDispatcher(store events.OutboxStore, sinks map[string]Sink, retention time.Duration, backoff outbound.Backoff) *Dispatcher {
+	return &Dispatcher{
+		store:     store,
+		sinks:     sinks,
+		backoff:   backoff,
+		retention: retention,
+		now:       time.Now,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	var pruned time.Time
+	for {
+		select {
+		case <-ticker.C:
+			d.Dispatch(context.Background())
+			if d.now().Sub(pruned) >= pruneInterval {
+				d.Prune(context.Background())
+				pruned = d.now()
+			}
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// Dispatch delivers every pending event that is due and returns how many were delivered.
+func (d *Dispatcher) Dispatch(ctx context.Context) int {
+	delivered := 0
+	for {
+		n, err := d.store.Dispatch(ctx, batchSize, lease, d.deliver)
+		if err != nil {
+			slog.ErrorContext(ctx, "could not dispatch outbox events", "error", err)
+			return delivered
+		}
+		delivered += n
+
+		// A short or failing batch means there is nothing left to deliver for now.
+		if n < batchSize {
+			return delivered
+		}
+	}
+}
+
+// deliver hands the event to every sink that has not accepted it yet, in the order of their
+// names, and fails it when any of them does.
+func (d *Dispatcher) deliver(ctx context.Context, event *events.OutboxEvent) events.OutboxOutcome {
+	outcome := events.OutboxOutcome{Status: events.OutboxDelivered, Sinks: slices.Clone(event.Sinks)}
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(d.sinks)) {
+		if slices.Contains(event.Sinks, name) {
+			continue
+		}
+		if err := d.sinks[name].Deliver(ctx, event.Event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		outcome.Sinks = append(outcome.Sinks, name)
+	}
+
+	err := errors.Join(errs...)
+	if err == nil {
+		return outcome
+	}
+	outcome.Error = err.Error()
+
+	attempts := event.Attempts + 1
+	if attempts >= d.backoff.MaxAttempts {
+		outcome.Status = events.OutboxDead
+		slog.ErrorContext(ctx, "could not deliver outbox event for the last time",
+			"event_id", event.ID, "event_type", event.Type, "attempts", attempts, "error", err)
+		return outcome
+	}
+
+	outcome.Status, outcome.RetryIn = events.OutboxPending, d.backoff.Delay(attempts)
+	slog.WarnContext(ctx, "could not deliver outbox event, it will be retried",
+		"event_id", event.ID, "event_type", event.Type, "retry_in", outcome.RetryIn, "error", err)
+	return outcome
+}
+
+// Prune deletes the events delivered longer than the retention ago, and the dead ones written
+// before then.
+func (d *Dispatcher) Prune(ctx context.Context) {
+	n, err := d.store.Prune(ctx, d.retention)
+	if err != nil {
+		slog.ErrorContext(ctx, "could not prune the outbox", "error", err)
+		return
+	}
+	if n > 0 {
+		slog.InfoContext(ctx, "pruned delivered outbox events", "count", n)
+	}
+}
+
+// Close stops the dispatcher. Events being delivered are finished first.
+func (d *Dispatcher) Close() {
+	d.closeOnce.Do(func() {
+		close(d.stop)
+	})
+	<-d.done
+}
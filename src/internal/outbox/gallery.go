@@ -0,0 +1,172 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"dZev1/character-gallery/models"
+	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/events"
+	"dZev1/character-gallery/models/inventory"
+)
+
+// publishingGallery hands the sinks an event after each write the wrapped gallery makes, for
+// backends without an outbox. Reads go straight to the wrapped gallery.
+type publishingGallery struct {
+	models.CharacterGallery
+	sinks map[string]Sink
+}
+
+// PublishGallery wraps a gallery that has no outbox so that its writes reach the sinks all the
+// same. Events are delivered once the write returns, without retries: one is lost when a sink
+// fails it, or when the process stops in between.
+func PublishGallery(gallery models.CharacterGallery, sinks map[string]Sink) models.CharacterGallery {
+	return &publishingGallery{CharacterGallery: gallery, sinks: sinks}
+}
+
+func (g *publishingGallery) publish(ctx context.Context, event *events.Event) {
+	if event == nil {
+		return
+	}
+	for _, name := range slices.Sorted(maps.Keys(g.sinks)) {
+		if err := g.sinks[name].Deliver(ctx, event); err != nil {
+			slog.ErrorContext(ctx, "could not deliver event", "sink", name, "event_id", event.ID, "event_type", event.Type, "error", err)
+		}
+	}
+}
+
+// newEvent builds the event a write is reported as, the same way the backends with an outbox
+// do. It returns nil when the data can't be encoded.
+func newEvent(ctx context.Context, eventType events.EventType, characterID characters.CharacterID, data any) *events.Event {
+	event, err := events.NewEvent(eventType, data)
+	if err != nil {
+		slog.ErrorContext(ctx, "could not build event", "event_type", eventType, "error", err)
+		return nil
+	}
+	event.CharacterID = characterID
+	return event
+}
+
+func (g *publishingGallery) Create(ctx context.Context, character *characters.Character) error {
+	if err := g.CharacterGallery.Create(ctx, character); err != nil {
+		return err
+	}
+	g.publish(ctx, newEvent(ctx, events.CharacterCreated, character.ID, character))
+	return nil
+}
+
+func (g *publishingGallery) Edit(ctx context.Context, character *characters.Character) error {
+	if err := g.CharacterGallery.Edit(ctx, character); err != nil {
+		return err
+	}
+	g.publish(ctx, newEvent(ctx, events.CharacterUpdated, character.ID, character))
+	return nil
+}
+
+func (g *publishingGallery) Remove(ctx context.Context, id characters.CharacterID) error {
+	if err := g.CharacterGallery.Remove(ctx, id); err != nil {
+		return err
+	}
+	g.publish(ctx, newEvent(ctx, events.CharacterDeleted, id, events.DeletedCharacter{ID: id}))
+	return nil
+}
+
+func (g *publishingGallery) CreateItem(ctx context.Context, item *inventory.Item) error {
+	if err := g.CharacterGallery.CreateItem(ctx, item); err != nil {
+		return err
+	}
+	g.publish(ctx, newEvent(ctx, events.ItemCreated, 0, item))
+	return nil
+}
+
+func (g *publishingGallery) SeedItems(ctx context.Context, items []inventory.Item) error {
+	if err := g.CharacterGallery.SeedItems(ctx, items); err != nil {
+		return err
+	}
+	g.publish(ctx, newEvent(ctx, events.ItemPoolSeeded, 0, events.SeededItems{Items: items}))
+	return nil
+}
+
+func (g *publishingGallery) AddItemToCharacter(ctx context.Context, characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) (*inventory.InventoryItem, error) {
+	entry, err := g.CharacterGallery.AddItemToCharacter(ctx, characterID, itemID, quantity)
+	if err != nil {
+		return nil, err
+	}
+	g.publish(ctx, newEvent(ctx, events.InventoryItemAdded, characterID, events.InventoryChange{CharacterID: characterID, ItemID: itemID, Quantity: quantity, Entry: entry}))
+	return entry, nil
+}
+
+func (g *publishingGallery) RemoveItemFromCharacter(ctx context.Context, characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) error {
+	if err := g.CharacterGallery.RemoveItemFromCharacter(ctx, characterID, itemID, quantity); err != nil {
+		return err
+	}
+	g.publish(ctx, newEvent(ctx, events.InventoryItemRemoved, characterID, events.InventoryChange{CharacterID: characterID, ItemID: itemID, Quantity: quantity}))
+	return nil
+}
+
+// Batch publishes the events of the writes of a batch once it is committed, and none when it
+// is rolled back.
+func (g *publishingGallery) Batch(ctx context.Context, fn func(ctx context.Context, tx models.BatchTx) error) error {
+	var batched []*events.Event
+	err := g.CharacterGallery.Batch(ctx, func(ctx context.Context, tx models.BatchTx) error {
+		batched = nil
+		return fn(ctx, &publishingBatchTx{BatchTx: tx, events: &batched})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, event := range batched {
+		g.publish(ctx, event)
+	}
+	return nil
+}
+
+// publishingBatchTx keeps the events of the writes of a batch until it is committed.
+type publishingBatchTx struct {
+	models.BatchTx
+	events *[]*events.Event
+}
+
+func (tx *publishingBatchTx) Create(ctx context.Context, character *characters.Character) error {
+	if err := tx.BatchTx.Create(ctx, character); err != nil {
+		return err
+	}
+	*tx.events = append(*tx.events, newEvent(ctx, events.CharacterCreated, character.ID, character))
+	return nil
+}
+
+func (tx *publishingBatchTx) Edit(ctx context.Context, character *characters.Character) error {
+	if err := tx.BatchTx.Edit(ctx, character); err != nil {
+		return err
+	}
+	*tx.events = append(*tx.events, newEvent(ctx, events.CharacterUpdated, character.ID, character))
+	return nil
+}
+
+func (tx *publishingBatchTx) AddItemToCharacter(ctx context.Context, characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) (*inventory.InventoryItem, error) {
+	entry, err := tx.BatchTx.AddItemToCharacter(ctx, characterID, itemID, quantity)
+	if err != nil {
+		return nil, err
+	}
+	*tx.events = append(*tx.events, newEvent(ctx, events.InventoryItemAdded, characterID, events.InventoryChange{CharacterID: characterID, ItemID: itemID, Quantity: quantity, Entry: entry}))
+	return entry, nil
+}
+
+func (tx *publishingBatchTx) RemoveItemFromCharacter(ctx context.Context, characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) error {
+	if err := tx.BatchTx.RemoveItemFromCharacter(ctx, characterID, itemID, quantity); err != nil {
+		return err
+	}
+	*tx.events = append(*tx.events, newEvent(ctx, events.InventoryItemRemoved, characterID, events.InventoryChange{CharacterID: characterID, ItemID: itemID, Quantity: quantity}))
+	return nil
+}
+
+func (tx *publishingBatchTx) CreateItem(ctx context.Context, item *inventory.Item) error {
+	if err := tx.BatchTx.CreateItem(ctx, item); err != nil {
+		return err
+	}
+	*tx.events = append(*tx.events, newEvent(ctx, events.ItemCreated, 0, item))
+	return nil
+}
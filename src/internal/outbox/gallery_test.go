@@ -0,0 +1,95 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"dZev1/character-gallery/models"
+	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/events"
+	"dZev1/character-gallery/models/inventory"
+)
+
+// fakeGallery has no outbox and fails every removal, to check that failed writes publish
+// nothing. Anything else it doesn't implement panics through the nil embedded interface.
+type fakeGallery struct {
+	models.CharacterGallery
+}
+
+func (g *fakeGallery) Create(ctx context.Context, character *characters.Character) error {
+	character.ID = 3
+	return nil
+}
+
+func (g *fakeGallery) Remove(ctx context.Context, id characters.CharacterID) error {
+	return models.NotFound("character not found", nil)
+}
+
+func (g *fakeGallery) AddItemToCharacter(ctx context.Context, characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) (*inventory.InventoryItem, error) {
+	return &inventory.InventoryItem{Quantity: quantity}, nil
+}
+
+// Batch runs fn on the gallery itself, and rolls nothing back since it writes nothing.
+func (g *fakeGallery) Batch(ctx context.Context, fn func(ctx context.Context, tx models.BatchTx) error) error {
+	return fn(ctx, g)
+}
+
+func TestPublishGallery_DeliversSuccessfulWrites(t *testing.T) {
+	first, second := &recorder{}, &recorder{err: errors.New("sink down")}
+	gallery := PublishGallery(&fakeGallery{}, map[string]Sink{"first": first, "second": second})
+
+	if err := gallery.Create(context.Background(), &characters.Character{Name: "Hero"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := gallery.Remove(context.Background(), 4); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("expected the gallery error to be returned, got %v", err)
+	}
+	if _, err := gallery.AddItemToCharacter(context.Background(), 3, 7, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(first.events) != 2 || len(second.events) != 2 || first.events[0] != second.events[0] {
+		t.Fatalf("expected both sinks to receive the 2 successful writes, got %+v and %+v", first.events, second.events)
+	}
+	if event := first.events[0]; event.Type != events.CharacterCreated || event.CharacterID != 3 {
+		t.Errorf("unexpected event %+v", event)
+	}
+	var data characters.Character
+	if err := json.Unmarshal(first.events[0].Data, &data); err != nil || data.ID != 3 {
+		t.Errorf("expected the created character as data, got %s", first.events[0].Data)
+	}
+	if event := first.events[1]; event.Type != events.InventoryItemAdded || event.CharacterID != 3 {
+		t.Errorf("unexpected event %+v", event)
+	}
+}
+
+func TestPublishGallery_DeliversBatchesOnceCommitted(t *testing.T) {
+	sink := &recorder{}
+	gallery := PublishGallery(&fakeGallery{}, map[string]Sink{"sink": sink})
+
+	err := gallery.Batch(context.Background(), func(ctx context.Context, tx models.BatchTx) error {
+		if err := tx.Create(ctx, &characters.Character{Name: "Hero"}); err != nil {
+			return err
+		}
+		return errors.New("rolled back")
+	})
+	if err == nil || len(sink.events) != 0 {
+		t.Fatalf("expected a rolled back batch to deliver nothing, got %v %+v", err, sink.events)
+	}
+
+	err = gallery.Batch(context.Background(), func(ctx context.Context, tx models.BatchTx) error {
+		if err := tx.Create(ctx, &characters.Character{Name: "Hero"}); err != nil {
+			return err
+		}
+		_, err := tx.AddItemToCharacter(ctx, 3, 7, 1)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.events) != 2 || sink.events[0].Type != events.CharacterCreated || sink.events[1].Type != events.InventoryItemAdded {
+		t.Errorf("expected the batch writes delivered in order, got %+v", sink.events)
+	}
+}
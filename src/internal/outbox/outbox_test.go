@@ -0,0 +1,148 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"dZev1/character-gallery/internal/outbound"
+	"dZev1/character-gallery/models/events"
+)
+
+// fakeStore hands its pending events over like the real store: delivered and dead ones are
+// removed, and pending ones are kept for the next call with the outcome applied. Retries are due
+// at once.
+type fakeStore struct {
+	pending   []*events.OutboxEvent
+	outcomes  []events.OutboxOutcome
+	olderThan time.Duration
+}
+
+func (s *fakeStore) Dispatch(ctx context.Context, limit int, lease time.Duration, deliver func(context.Context, *events.OutboxEvent) events.OutboxOutcome) (int, error) {
+	var kept []*events.OutboxEvent
+	delivered := 0
+	for i, event := range s.pending {
+		if i >= limit {
+			kept = append(kept, event)
+			continue
+		}
+		outcome := deliver(ctx, event)
+		s.outcomes = append(s.outcomes, outcome)
+		switch outcome.Status {
+		case events.OutboxDelivered:
+			delivered++
+		case events.OutboxPending:
+			kept = append(kept, &events.OutboxEvent{Event: event.Event, Attempts: event.Attempts + 1, Sinks: outcome.Sinks})
+		}
+	}
+	s.pending = kept
+	return delivered, nil
+}
+
+func (s *fakeStore) Prune(ctx context.Context, olderThan time.Duration) (int64, error) {
+	s.olderThan = olderThan
+	return 0, nil
+}
+
+func (s *fakeStore) Broadcast(ctx context.Context, event *events.Event) error {
+	return nil
+}
+
+type recorder struct {
+	events []*events.Event
+	err    error
+}
+
+func (r *recorder) Deliver(ctx context.Context, event *events.Event) error {
+	r.events = append(r.events, event)
+	return r.err
+}
+
+func pendingEvents(t *testing.T, n int) []*events.OutboxEvent {
+	t.Helper()
+	var pending []*events.OutboxEvent
+	for range n {
+		event, err := events.NewEvent(events.ItemCreated, struct{}{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pending = append(pending, &events.OutboxEvent{Event: event})
+	}
+	return pending
+}
+
+var testBackoff = outbound.Backoff{Base: time.Second, Max: time.Minute, MaxAttempts: 3}
+
+func TestDispatch_DeliversToEverySink(t *testing.T) {
+	store := &fakeStore{pending: pendingEvents(t, batchSize+1)}
+	var webhooks, stream recorder
+
+	d := newDispatcher(store, map[string]Sink{"webhooks": &webhooks, "stream": &stream}, time.Hour, testBackoff)
+	if n := d.Dispatch(context.Background()); n != batchSize+1 {
+		t.Fatalf("expected every batch to be delivered, got %d", n)
+	}
+
+	if len(webhooks.events) != batchSize+1 || len(stream.events) != batchSize+1 || len(store.pending) != 0 {
+		t.Errorf("expected both sinks to receive every event, got %d and %d with %d pending", len(webhooks.events), len(stream.events), len(store.pending))
+	}
+}
+
+func TestDispatch_RetriesOnlyTheSinksThatFailed(t *testing.T) {
+	store := &fakeStore{pending: pendingEvents(t, 1)}
+	webhooks := recorder{err: errors.New("database unavailable")}
+	var stream recorder
+
+	d := newDispatcher(store, map[string]Sink{"webhooks": &webhooks, "stream": &stream}, time.Hour, testBackoff)
+	if n := d.Dispatch(context.Background()); n != 0 {
+		t.Fatalf("expected nothing delivered, got %d", n)
+	}
+	outcome := store.outcomes[0]
+	if outcome.Status != events.OutboxPending || outcome.RetryIn != time.Second || !slices.Equal(outcome.Sinks, []string{"stream"}) {
+		t.Fatalf("expected the event to be retried for webhooks in 1s, got %+v", outcome)
+	}
+
+	// Once the failing sink recovers, only it is handed the event again.
+	webhooks.err = nil
+	if n := d.Dispatch(context.Background()); n != 1 {
+		t.Fatalf("expected the event to be delivered, got %d", n)
+	}
+	if len(webhooks.events) != 2 || len(stream.events) != 1 {
+		t.Errorf("expected the stream to receive the event once, got %d and %d", len(webhooks.events), len(stream.events))
+	}
+	if outcome := store.outcomes[1]; outcome.Status != events.OutboxDelivered || !slices.Equal(outcome.Sinks, []string{"stream", "webhooks"}) {
+		t.Errorf("expected the event delivered to both sinks, got %+v", outcome)
+	}
+}
+
+func TestDispatch_BacksOffThenDeadLetters(t *testing.T) {
+	store := &fakeStore{pending: pendingEvents(t, 1)}
+	webhooks := recorder{err: errors.New("database unavailable")}
+
+	d := newDispatcher(store, map[string]Sink{"webhooks": &webhooks}, time.Hour, testBackoff)
+	for range testBackoff.MaxAttempts {
+		d.Dispatch(context.Background())
+	}
+
+	if len(store.outcomes) != 3 || store.outcomes[1].RetryIn != 2*time.Second {
+		t.Fatalf("expected the retries to back off, got %+v", store.outcomes)
+	}
+	if outcome := store.outcomes[2]; outcome.Status != events.OutboxDead || outcome.Error != "webhooks: database unavailable" {
+		t.Errorf("expected the event dead after 3 attempts, got %+v", outcome)
+	}
+	if d.Dispatch(context.Background()); len(store.pending) != 0 || len(webhooks.events) != 3 {
+		t.Errorf("expected a dead event not to be handed over again, got %d attempts", len(webhooks.events))
+	}
+}
+
+func TestPrune_KeepsRetention(t *testing.T) {
+	store := &fakeStore{}
+
+	d := newDispatcher(store, nil, 7*24*time.Hour, testBackoff)
+	d.Prune(context.Background())
+
+	if store.olderThan != 7*24*time.Hour {
+		t.Errorf("expected events delivered over 7 days ago to be pruned, got %s", store.olderThan)
+	}
+}
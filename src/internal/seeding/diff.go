@@ -0,0 +1,158 @@
+package seeding
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"dZev1/character-gallery/models/inventory"
+)
+
+type Action string
+
+const (
+	Insert    Action = "insert"
+	Update    Action = "update"
+	Unchanged Action = "unchanged"
+)
+
+// FieldChange is a field an update rewrites, named by its JSON key. Old and New are nil for
+// stats the item doesn't have.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// Change is what seeding does to one item of the file.
+type Change struct {
+	Action Action         `json:"action"`
+	Item   inventory.Item `json:"item"`
+	Fields []FieldChange  `json:"fields,omitempty"`
+}
+
+// Plan lists the changes in file order. Items stored but missing from the source are left
+// alone, since characters may still hold them.
+type Plan struct {
+	Changes []Change `json:"changes"`
+	// Packs are the content packs the items came from, if any.
+	Packs []Pack `json:"packs,omitempty"`
+}
+
+// Diff compares the items to seed with the stored ones. Numbered items are matched by ID and
+// content pack items by pack and slug, taking the ID of the stored item they update.
+func Diff(stored, seed []inventory.Item) (Plan, error) {
+	byID := make(map[inventory.ItemID]inventory.Item, len(stored))
+	byKey := make(map[string]inventory.Item, len(stored))
+	for _, item := range stored {
+		byID[item.ID] = item
+		byKey[item.Key()] = item
+	}
+
+	plan := Plan{Changes: make([]Change, 0, len(seed))}
+	for _, item := range seed {
+		current, ok := byKey[item.Key()]
+		if owner, taken := byID[item.ID]; item.Slug == "" && taken && owner.Slug != "" {
+			return Plan{}, fmt.Errorf("item %d of the pool file would replace %s from a content pack", item.ID, owner.Key())
+		}
+		if ok {
+			item.ID = current.ID
+		}
+
+		switch fields := diffFields(current, item); {
+		case !ok:
+			plan.Changes = append(plan.Changes, Change{Action: Insert, Item: item})
+		case len(fields) > 0:
+			plan.Changes = append(plan.Changes, Change{Action: Update, Item: item, Fields: fields})
+		default:
+			plan.Changes = append(plan.Changes, Change{Action: Unchanged, Item: item})
+		}
+	}
+	return plan, nil
+}
+
+func diffFields(old, new inventory.Item) []FieldChange {
+	var fields []FieldChange
+	oldValue, newValue := reflect.ValueOf(old), reflect.ValueOf(new)
+	for field, value := range newValue.Fields() {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "id" {
+			continue
+		}
+
+		before := oldValue.FieldByIndex(field.Index)
+		if reflect.DeepEqual(before.Interface(), value.Interface()) {
+			continue
+		}
+		fields = append(fields, FieldChange{Field: name, Old: deref(before), New: deref(value)})
+	}
+	return fields
+}
+
+// deref unwraps the optional stats so that they print as numbers.
+func deref(v reflect.Value) any {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	return v.Interface()
+}
+
+// Count returns how many changes do action.
+func (p Plan) Count(action Action) int {
+	n := 0
+	for _, change := range p.Changes {
+		if change.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Pending returns the items that must be written, leaving out the unchanged ones.
+func (p Plan) Pending() []inventory.Item {
+	var items []inventory.Item
+	for _, change := range p.Changes {
+		if change.Action != Unchanged {
+			items = append(items, change.Item)
+		}
+	}
+	return items
+}
+
+// Write prints the plan as a diff: "+" for inserts, "~" for updates followed by each changed
+// field, and "=" for unchanged items.
+func (p Plan) Write(w io.Writer) error {
+	var b strings.Builder
+	for _, change := range p.Changes {
+		switch change.Action {
+		case Insert:
+			fmt.Fprintf(&b, "+ %s %s\n", change.Item.Key(), change.Item.Name)
+		case Update:
+			fmt.Fprintf(&b, "~ %s %s\n", change.Item.Key(), change.Item.Name)
+			for _, field := range change.Fields {
+				fmt.Fprintf(&b, "    %s: %s -> %s\n", field.Field, formatValue(field.Old), formatValue(field.New))
+			}
+		case Unchanged:
+			fmt.Fprintf(&b, "= %s %s\n", change.Item.Key(), change.Item.Name)
+		}
+	}
+	fmt.Fprintf(&b, "%d to insert, %d to update, %d unchanged\n", p.Count(Insert), p.Count(Update), p.Count(Unchanged))
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "(none)"
+	case string, inventory.Type:
+		return fmt.Sprintf("%q", v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
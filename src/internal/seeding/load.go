@@ -0,0 +1,230 @@
+package seeding
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"dZev1/character-gallery/models/inventory"
+	"dZev1/character-gallery/models/validation"
+)
+
+// Error is a problem with one item of a seed file, at the line and column it was found.
+type Error struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	// Path names the invalid value, such as [7].rarity, or is empty for syntax errors.
+	Path    string `json:"path,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e Error) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", e.File, e.Line, e.Column, e.Path, e.Message)
+}
+
+// Errors holds every problem found in a seed file, in file order.
+type Errors []Error
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, seedErr := range e {
+		messages[i] = seedErr.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+const (
+	CodeSyntax   = "syntax"
+	CodeConflict = "conflict"
+)
+
+// Load reads the JSON array of items in r, named name in errors. Every item is decoded and
+// validated even after a bad one, so all problems are reported at once as Errors. Items need an
+// id, which is what makes seeding the same file twice update instead of duplicate them.
+func Load(name string, r io.Reader) ([]inventory.Item, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", name, err)
+	}
+
+	found, errs := loadPool(name, data)
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return items(found), nil
+}
+
+// located is an item along with where it was read, so that conflicts between items of different
+// files can point at both.
+type located struct {
+	item inventory.Item
+	at   Error
+}
+
+func (l located) fail(field, code, message string) Error {
+	at := l.at
+	at.Path = joinPath(at.Path, field)
+	at.Code, at.Message = code, message
+	return at
+}
+
+func items(found []located) []inventory.Item {
+	items := make([]inventory.Item, len(found))
+	for i, l := range found {
+		items[i] = l.item
+	}
+	return items
+}
+
+func (e Errors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+func joinPath(prefix, field string) string {
+	if field == "" {
+		return prefix
+	}
+	return prefix + "." + field
+}
+
+func loadPool(name string, data []byte) ([]located, Errors) {
+	var errs Errors
+	fail := func(offset int64, path, code, message string) {
+		line, column := position(data, offset)
+		errs = append(errs, Error{File: name, Line: line, Column: column, Path: path, Code: code, Message: message})
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		fail(skipSpace(data, 0), "", CodeSyntax, "the file must hold a JSON array of items")
+		return nil, errs
+	}
+
+	var found []located
+	firstByID := map[inventory.ItemID]int64{}
+	for index := 0; decoder.More(); index++ {
+		start := skipSpace(data, decoder.InputOffset())
+		path := fmt.Sprintf("[%d]", index)
+
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			// The rest of the file can't be read past a syntax error.
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				fail(syntaxErr.Offset-1, "", CodeSyntax, syntaxErr.Error())
+			} else {
+				fail(start, "", CodeSyntax, err.Error())
+			}
+			return nil, errs
+		}
+		keys := keyOffsets(raw)
+		fieldOffset := func(field string) int64 {
+			top, _, _ := strings.Cut(field, ".")
+			if offset, ok := keys[top]; ok {
+				return start + offset
+			}
+			return start
+		}
+
+		var item inventory.Item
+		itemDecoder := json.NewDecoder(bytes.NewReader(raw))
+		itemDecoder.DisallowUnknownFields()
+		if err := itemDecoder.Decode(&item); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				message := fmt.Sprintf("must be %s, got %s", typeErr.Type, typeErr.Value)
+				fail(fieldOffset(typeErr.Field), path+"."+typeErr.Field, validation.CodeInvalidValue, message)
+			} else if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+				field = strings.Trim(field, `"`)
+				fail(fieldOffset(field), path+"."+field, validation.CodeInvalidValue, "is not an item field")
+			} else {
+				fail(start, path, validation.CodeInvalidValue, err.Error())
+			}
+			continue
+		}
+
+		var fieldErrs validation.Errors
+		if item.ID == 0 {
+			fieldErrs.Add("id", validation.CodeRequired, "is required to seed an item")
+		} else if first, ok := firstByID[item.ID]; ok {
+			line, _ := position(data, first)
+			fieldErrs.Add("id", validation.CodeInvalidValue, fmt.Sprintf("%d is already used by the item on line %d", item.ID, line))
+		} else {
+			firstByID[item.ID] = start
+		}
+		if item.Pack != "" {
+			fieldErrs.Add("pack", validation.CodeInvalidValue, "is only set for items of content packs")
+		}
+		if item.Slug != "" {
+			fieldErrs.Add("slug", validation.CodeInvalidValue, "is only set for items of content packs")
+		}
+		fieldErrs.Merge("", item.Validate())
+
+		for _, fieldErr := range fieldErrs {
+			fail(fieldOffset(fieldErr.Path), path+"."+fieldErr.Path, fieldErr.Code, fieldErr.Message)
+		}
+		line, column := position(data, start)
+		found = append(found, located{item: item, at: Error{File: name, Line: line, Column: column, Path: path}})
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		fail(skipSpace(data, decoder.InputOffset()), "", CodeSyntax, "the array of items is not closed")
+	}
+	return found, errs
+}
+
+// keyOffsets finds where each top-level key of a JSON object starts, so that an error can point
+// at the field it is about.
+func keyOffsets(object json.RawMessage) map[string]int64 {
+	offsets := map[string]int64{}
+	decoder := json.NewDecoder(bytes.NewReader(object))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return offsets
+	}
+
+	for decoder.More() {
+		offset := skipSpace(object, decoder.InputOffset())
+		token, err := decoder.Token()
+		if err != nil {
+			return offsets
+		}
+		if key, ok := token.(string); ok {
+			offsets[key] = offset
+		}
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return offsets
+		}
+	}
+	return offsets
+}
+
+// skipSpace returns the offset of the first byte at or after offset that isn't whitespace or
+// the comma separating values, which is where the next value starts.
+func skipSpace(data []byte, offset int64) int64 {
+	for offset < int64(len(data)) && strings.IndexByte(" \t\r\n,", data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// position converts a byte offset into a line and column, both starting at 1.
+func position(data []byte, offset int64) (line, column int) {
+	offset = min(max(offset, 0), int64(len(data)))
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	column = int(offset) - (bytes.LastIndexByte(before, '\n') + 1) + 1
+	return line, column
+}
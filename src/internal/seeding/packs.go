@@ -0,0 +1,262 @@
+package seeding
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"dZev1/character-gallery/models/inventory"
+	"dZev1/character-gallery/models/validation"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Pack is a content pack: a file of items that a team owns under its namespace. Items are keyed
+// by slugs instead of IDs, so packs never collide on numbers and the database numbers them.
+//
+//	namespace: core
+//	version: 1.0.0
+//	items:
+//	  light-armor:
+//	    name: Light Armor
+//	    type: armor
+//	    ...
+type Pack struct {
+	Namespace string `json:"namespace"`
+	Version   string `json:"version"`
+	File      string `json:"file"`
+	Items     int    `json:"items"`
+}
+
+// PackExtensions are the file extensions read from a packs directory. JSON is read as YAML, of
+// which it is a subset.
+var PackExtensions = []string{".json", ".yaml", ".yml"}
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// LoadPacks reads every pack file in dir, in name order. All problems are reported at once as
+// Errors, including namespaces declared by two files.
+func LoadPacks(dir string) ([]Pack, []inventory.Item, error) {
+	packs, found, errs, err := loadPacks(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := errs.err(); err != nil {
+		return nil, nil, err
+	}
+	return packs, items(found), nil
+}
+
+func loadPacks(dir string) ([]Pack, []located, Errors, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not read packs directory: %w", err)
+	}
+
+	var packs []Pack
+	var found []located
+	var errs Errors
+	declaredBy := map[string]string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !slices.Contains(PackExtensions, strings.ToLower(filepath.Ext(entry.Name()))) {
+			continue
+		}
+
+		file := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("could not read pack: %w", err)
+		}
+
+		pack, packItems, packErrs := loadPack(file, data)
+		errs = append(errs, packErrs...)
+		if pack.Namespace == "" {
+			continue
+		}
+		if first, ok := declaredBy[pack.Namespace]; ok {
+			errs = append(errs, Error{
+				File: file, Line: pack.line, Column: pack.column, Path: "namespace", Code: validation.CodeInvalidValue,
+				Message: fmt.Sprintf("%s is already declared by %s", pack.Namespace, first),
+			})
+			continue
+		}
+		declaredBy[pack.Namespace] = file
+
+		packs = append(packs, pack.Pack)
+		found = append(found, packItems...)
+	}
+	return packs, found, errs, nil
+}
+
+// loadedPack is a pack along with where its namespace was declared.
+type loadedPack struct {
+	Pack
+	line, column int
+}
+
+// loadPack decodes one pack file. Errors point at the key of the invalid value.
+func loadPack(file string, data []byte) (loadedPack, []located, Errors) {
+	pack := loadedPack{Pack: Pack{File: file}, line: 1, column: 1}
+	var errs Errors
+	failAt := func(node *yaml.Node, path, code, message string) {
+		errs = append(errs, Error{File: file, Line: node.Line, Column: node.Column, Path: path, Code: code, Message: message})
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		line, message := yamlErrorLine(err)
+		errs = append(errs, Error{File: file, Line: line, Column: 1, Code: CodeSyntax, Message: message})
+		return loadedPack{}, nil, errs
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		errs = append(errs, Error{File: file, Line: 1, Column: 1, Code: CodeSyntax, Message: "a pack must be a mapping with namespace, version and items"})
+		return loadedPack{}, nil, errs
+	}
+	root := doc.Content[0]
+
+	var namespaceNode, itemsNode *yaml.Node
+	for key, value := range pairs(root) {
+		switch key.Value {
+		case "namespace":
+			namespaceNode = key
+			pack.Namespace, pack.line, pack.column = value.Value, key.Line, key.Column
+		case "version":
+			pack.Version = value.Value
+		case "items":
+			itemsNode = value
+		default:
+			failAt(key, key.Value, validation.CodeInvalidValue, "is not a pack field")
+		}
+	}
+	switch {
+	case namespaceNode == nil:
+		failAt(root, "namespace", validation.CodeRequired, "is required")
+	case !slugPattern.MatchString(pack.Namespace):
+		failAt(namespaceNode, "namespace", validation.CodeInvalidValue, "must be lowercase letters and digits separated by dashes")
+		pack.Namespace = ""
+	}
+	if pack.Version == "" {
+		failAt(root, "version", validation.CodeRequired, "is required")
+	}
+	if itemsNode == nil || itemsNode.Kind != yaml.MappingNode {
+		failAt(root, "items", validation.CodeRequired, "must map item slugs to items")
+		return pack, nil, errs
+	}
+
+	var found []located
+	seen := map[string]int{}
+	for key, value := range pairs(itemsNode) {
+		slug, path := key.Value, "items."+key.Value
+		if !slugPattern.MatchString(slug) {
+			failAt(key, path, validation.CodeInvalidValue, "slugs must be lowercase letters and digits separated by dashes")
+			continue
+		}
+		if line, ok := seen[slug]; ok {
+			failAt(key, path, validation.CodeInvalidValue, fmt.Sprintf("is already defined on line %d", line))
+			continue
+		}
+		seen[slug] = key.Line
+
+		item, itemErrs := decodePackItem(file, path, key, value)
+		errs = append(errs, itemErrs...)
+		item.Pack, item.Slug = pack.Namespace, slug
+		found = append(found, located{item: item, at: Error{File: file, Line: key.Line, Column: key.Column, Path: path}})
+	}
+	pack.Items = len(found)
+	return pack, found, errs
+}
+
+// decodePackItem decodes an item through JSON, so that packs use the same field names as the
+// API whether they are written in JSON or YAML.
+func decodePackItem(file, path string, key, value *yaml.Node) (inventory.Item, Errors) {
+	var item inventory.Item
+	var errs Errors
+	fields := fieldNodes(value)
+	fail := func(field, code, message string) {
+		node := key
+		if fieldNode, ok := fields[strings.SplitN(field, ".", 2)[0]]; ok {
+			node = fieldNode
+		}
+		errs = append(errs, Error{File: file, Line: node.Line, Column: node.Column, Path: joinPath(path, field), Code: code, Message: message})
+	}
+
+	var raw map[string]any
+	if err := value.Decode(&raw); err != nil {
+		fail("", validation.CodeInvalidValue, "must be a mapping of item fields")
+		return item, errs
+	}
+	for _, field := range []string{"id", "pack", "slug"} {
+		if _, ok := raw[field]; ok {
+			fail(field, validation.CodeInvalidValue, "is set from the pack and can't be given")
+			delete(raw, field)
+		}
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		fail("", validation.CodeInvalidValue, err.Error())
+		return item, errs
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&item); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			fail(typeErr.Field, validation.CodeInvalidValue, fmt.Sprintf("must be %s, got %s", typeErr.Type, typeErr.Value))
+		} else if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			fail(strings.Trim(field, `"`), validation.CodeInvalidValue, "is not an item field")
+		} else {
+			fail("", validation.CodeInvalidValue, err.Error())
+		}
+		return item, errs
+	}
+
+	var fieldErrs validation.Errors
+	fieldErrs.Merge("", item.Validate())
+	for _, fieldErr := range fieldErrs {
+		fail(fieldErr.Path, fieldErr.Code, fieldErr.Message)
+	}
+	return item, errs
+}
+
+// pairs yields the keys and values of a mapping node.
+func pairs(node *yaml.Node) func(yield func(key, value *yaml.Node) bool) {
+	return func(yield func(key, value *yaml.Node) bool) {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if !yield(node.Content[i], node.Content[i+1]) {
+				return
+			}
+		}
+	}
+}
+
+func fieldNodes(node *yaml.Node) map[string]*yaml.Node {
+	fields := map[string]*yaml.Node{}
+	if node.Kind != yaml.MappingNode {
+		return fields
+	}
+	for key := range pairs(node) {
+		fields[key.Value] = key
+	}
+	return fields
+}
+
+var yamlLinePattern = regexp.MustCompile(`^yaml: line (\d+): `)
+
+// yamlErrorLine takes the line number out of a YAML syntax error message.
+func yamlErrorLine(err error) (int, string) {
+	message := err.Error()
+	match := yamlLinePattern.FindStringSubmatch(message)
+	if match == nil {
+		return 1, strings.TrimPrefix(message, "yaml: ")
+	}
+	line, _ := strconv.Atoi(match[1])
+	return line, message[len(match[0]):]
+}
@@ -0,0 +1,227 @@
+package seeding
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"dZev1/character-gallery/models"
+)
+
+// Triggers say what started a reload.
+const (
+	TriggerStartup    = "startup"
+	TriggerFileChange = "file_change"
+)
+
+// Status is the outcome of the last reload. A failed reload writes nothing, so the pool applied
+// by the last successful one, at AppliedAt, is still the one being served.
+type Status struct {
+	Trigger string    `json:"trigger"`
+	At      time.Time `json:"at"`
+	OK      bool      `json:"ok"`
+	Error   string    `json:"error,omitempty"`
+	// Problems are the invalid items that made the reload fail, if that was why.
+	Problems  Errors     `json:"problems,omitempty"`
+	Inserted  int        `json:"inserted"`
+	Updated   int        `json:"updated"`
+	Unchanged int        `json:"unchanged"`
+	Packs     []Pack     `json:"packs,omitempty"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// Reloader seeds a gallery from a Source and keeps the status of the last attempt.
+type Reloader struct {
+	gallery models.CharacterGallery
+	source  Source
+	now     func() time.Time
+
+	mu          sync.Mutex
+	status      *Status
+	fingerprint string
+}
+
+func NewReloader(gallery models.CharacterGallery, source Source) *Reloader {
+	return &Reloader{gallery: gallery, source: source, now: time.Now}
+}
+
+// Reload validates the source and seeds it in one transaction. When any item is invalid nothing
+// is written and the error is recorded in the status.
+func (r *Reloader) Reload(ctx context.Context, trigger string) (Plan, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// The fingerprint is taken first, so that a file changed while loading is reloaded again.
+	fingerprint, fingerprintErr := r.source.fingerprint()
+	plan, err := SeedSource(ctx, r.gallery, r.source, false)
+
+	status := &Status{Trigger: trigger, At: r.now(), OK: err == nil}
+	if r.status != nil {
+		status.AppliedAt = r.status.AppliedAt
+	}
+	if err != nil {
+		status.Error = err.Error()
+		errors.As(err, &status.Problems)
+	} else {
+		status.Inserted, status.Updated, status.Unchanged = plan.Count(Insert), plan.Count(Update), plan.Count(Unchanged)
+		status.Packs = plan.Packs
+		status.AppliedAt = &status.At
+	}
+	r.status = status
+	// A pool refused as invalid is only tried again once it changes, but one that could not be
+	// written, say while the database was down, is retried on the next poll.
+	if fingerprintErr == nil && (err == nil || status.Problems != nil) {
+		r.fingerprint = fingerprint
+	}
+	return plan, err
+}
+
+// Status returns the outcome of the last reload, or false before the first one.
+func (r *Reloader) Status() (Status, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.status == nil {
+		return Status{}, false
+	}
+	return *r.status, true
+}
+
+// changed reports whether the source differs from what the last reload read.
+func (r *Reloader) changed() (bool, error) {
+	fingerprint, err := r.source.fingerprint()
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return fingerprint != r.fingerprint, nil
+}
+
+// fingerprint hashes the pool file and every pack file, so that any edit, new file or removed
+// file changes it. Polling the content instead of modification times catches edits made within
+// the resolution of the file system clock.
+func (s Source) fingerprint() (string, error) {
+	hash := sha256.New()
+	addFile := func(name string) error {
+		data, err := os.ReadFile(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			hash.Write([]byte("missing " + name + "\n"))
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		hash.Write([]byte(name + " " + hex.EncodeToString(sum[:]) + "\n"))
+		return nil
+	}
+
+	if s.PoolFile != "" {
+		if err := addFile(s.PoolFile); err != nil {
+			return "", err
+		}
+	}
+	if s.PacksDir != "" {
+		entries, err := os.ReadDir(s.PacksDir)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !slices.Contains(PackExtensions, strings.ToLower(filepath.Ext(entry.Name()))) {
+				continue
+			}
+			if err := addFile(filepath.Join(s.PacksDir, entry.Name())); err != nil {
+				return "", err
+			}
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Watcher polls the source of a Reloader and reloads it whenever its files change. Polling needs
+// nothing from the operating system, so it works the same on every platform and volume.
+type Watcher struct {
+	reloader *Reloader
+	interval time.Duration
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Watch starts polling every interval until Close is called.
+func (r *Reloader) Watch(interval time.Duration) *Watcher {
+	w := &Watcher{
+		reloader: r,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *Watcher) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.Poll()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// Poll reloads the pool if its files changed since the last reload, logging what was applied or
+// why the new pool was refused.
+func (w *Watcher) Poll() {
+	changed, err := w.reloader.changed()
+	if err != nil {
+		slog.Error("could not check the item pool for changes", "error", err)
+		return
+	}
+	if !changed {
+		return
+	}
+
+	plan, err := w.reloader.Reload(context.Background(), TriggerFileChange)
+	if err != nil {
+		slog.Error("item pool changed but could not be reloaded, keeping the current one", "error", err)
+		return
+	}
+	slog.Info("item pool reloaded",
+		"packs", len(plan.Packs),
+		"inserted", plan.Count(Insert),
+		"updated", plan.Count(Update),
+		"unchanged", plan.Count(Unchanged),
+	)
+	for _, change := range plan.Changes {
+		if change.Action != Unchanged {
+			slog.Info("item pool change", "action", change.Action, "item", change.Item.Key(), "name", change.Item.Name)
+		}
+	}
+}
+
+// Close stops polling. A reload in progress is finished first.
+func (w *Watcher) Close() {
+	w.closeOnce.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+}
@@ -0,0 +1,118 @@
+package seeding
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatcher_ReloadsChangedPool(t *testing.T) {
+	pool := filepath.Join(t.TempDir(), "item_pool.json")
+	if err := os.WriteFile(pool, []byte(validPool), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	gallery := &fakeGallery{}
+	reloader := NewReloader(gallery, Source{PoolFile: pool})
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	reloader.now = func() time.Time {
+		clock = clock.Add(time.Minute)
+		return clock
+	}
+	watcher := &Watcher{reloader: reloader}
+
+	if _, ok := reloader.Status(); ok {
+		t.Fatal("expected no status before the first reload")
+	}
+	if _, err := reloader.Reload(context.Background(), TriggerStartup); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	applied, _ := reloader.Status()
+	if !applied.OK || applied.Inserted != 2 || applied.AppliedAt == nil {
+		t.Fatalf("unexpected status %+v", applied)
+	}
+
+	watcher.Poll()
+	if status, _ := reloader.Status(); !status.At.Equal(applied.At) {
+		t.Errorf("expected an unchanged pool not to be reloaded, got %+v", status)
+	}
+
+	invalid := strings.Replace(validPool, `"rarity": 1`, `"rarity": 7`, 1)
+	if err := os.WriteFile(pool, []byte(invalid), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	watcher.Poll()
+	refused, _ := reloader.Status()
+	if refused.OK || refused.Trigger != TriggerFileChange || len(refused.Problems) != 1 || refused.Problems[0].Path != "[1].rarity" {
+		t.Errorf("expected the invalid pool to be refused, got %+v", refused)
+	}
+	if refused.AppliedAt == nil || !refused.AppliedAt.Equal(*applied.AppliedAt) {
+		t.Errorf("expected the pool applied at %v to be kept, got %v", applied.AppliedAt, refused.AppliedAt)
+	}
+	if len(gallery.seeded) != 2 {
+		t.Errorf("expected nothing written for the invalid pool, got %+v", gallery.seeded)
+	}
+
+	watcher.Poll()
+	if status, _ := reloader.Status(); !status.At.Equal(refused.At) {
+		t.Errorf("expected a refused pool not to be retried until it changes, got %+v", status)
+	}
+
+	if err := os.WriteFile(pool, []byte(validPool), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	watcher.Poll()
+	if status, _ := reloader.Status(); !status.OK || !status.AppliedAt.Equal(status.At) {
+		t.Errorf("expected the fixed pool to be applied, got %+v", status)
+	}
+}
+
+func TestWatcher_RetriesPoolThatCouldNotBeWritten(t *testing.T) {
+	pool := filepath.Join(t.TempDir(), "item_pool.json")
+	if err := os.WriteFile(pool, []byte(validPool), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	gallery := &fakeGallery{failures: 1}
+	reloader := NewReloader(gallery, Source{PoolFile: pool})
+	watcher := &Watcher{reloader: reloader}
+
+	if _, err := reloader.Reload(context.Background(), TriggerStartup); err == nil {
+		t.Fatal("expected the first seed to fail")
+	}
+	if status, _ := reloader.Status(); status.OK || status.AppliedAt != nil {
+		t.Fatalf("expected nothing applied, got %+v", status)
+	}
+
+	// The files are unchanged, but the pool was never applied.
+	watcher.Poll()
+	if status, _ := reloader.Status(); !status.OK || status.Trigger != TriggerFileChange || status.Inserted != 2 {
+		t.Errorf("expected the pool to be applied on the next poll, got %+v", status)
+	}
+	if len(gallery.seeded) != 2 {
+		t.Errorf("expected the pool written, got %+v", gallery.seeded)
+	}
+}
+
+func TestSourceFingerprint_SeesNewPacks(t *testing.T) {
+	dir := t.TempDir()
+	src := Source{PoolFile: filepath.Join(dir, "missing.json"), PacksDir: dir}
+
+	before, err := src.fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a pack"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if same, _ := src.fingerprint(); same != before {
+		t.Error("expected files that aren't packs to be ignored")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "core.yaml"), []byte("namespace: core\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := src.fingerprint(); after == before {
+		t.Error("expected a new pack to change the fingerprint")
+	}
+}
@@ -0,0 +1,141 @@
+// Package seeding loads the item pool from a JSON file and content packs into a gallery,
+// reporting every invalid item with its position in the file and what seeding changes before
+// it is written.
+package seeding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"dZev1/character-gallery/models"
+	"dZev1/character-gallery/models/inventory"
+)
+
+// Source is where the item pool is seeded from: a JSON file of numbered items, a directory of
+// content packs, or both. Either may be missing, but not both.
+type Source struct {
+	PoolFile string
+	PacksDir string
+}
+
+// Pool is the content of a Source with the packs merged in.
+type Pool struct {
+	Items []inventory.Item
+	Packs []Pack
+}
+
+// Load reads and validates the pool file and every pack. Besides the problems of each file, it
+// reports items of different files that share a name and rarity, which the pool can't hold.
+func (s Source) Load() (*Pool, error) {
+	var found []located
+	var errs Errors
+	missing := 0
+
+	if s.PoolFile != "" {
+		data, err := os.ReadFile(s.PoolFile)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			missing++
+		case err != nil:
+			return nil, fmt.Errorf("could not read seed file: %w", err)
+		default:
+			poolItems, poolErrs := loadPool(s.PoolFile, data)
+			found, errs = append(found, poolItems...), append(errs, poolErrs...)
+		}
+	} else {
+		missing++
+	}
+
+	var packs []Pack
+	if s.PacksDir != "" {
+		var packItems []located
+		var packErrs Errors
+		var err error
+		packs, packItems, packErrs, err = loadPacks(s.PacksDir)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			missing++
+		case err != nil:
+			return nil, err
+		default:
+			found, errs = append(found, packItems...), append(errs, packErrs...)
+		}
+	} else {
+		missing++
+	}
+
+	if missing == 2 {
+		return nil, fmt.Errorf("neither the seed file %q nor the packs directory %q exist", s.PoolFile, s.PacksDir)
+	}
+
+	errs = append(errs, conflicts(found)...)
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return &Pool{Items: items(found), Packs: packs}, nil
+}
+
+// conflicts reports items with the name and rarity of an earlier one.
+func conflicts(found []located) Errors {
+	type nameRarity struct {
+		name   string
+		rarity uint8
+	}
+
+	var errs Errors
+	first := map[nameRarity]located{}
+	for _, l := range found {
+		key := nameRarity{l.item.Name, l.item.Rarity}
+		if other, ok := first[key]; ok {
+			errs = append(errs, l.fail("name", CodeConflict, fmt.Sprintf(
+				"%q with rarity %d is already defined at %s:%d:%d",
+				l.item.Name, l.item.Rarity, other.at.File, other.at.Line, other.at.Column,
+			)))
+			continue
+		}
+		first[key] = l
+	}
+	return errs
+}
+
+// Seed compares items with the stored pool and writes the ones that are new or changed, unless
+// dryRun is set. The returned plan says what was, or would be, written.
+func Seed(ctx context.Context, gallery models.CharacterGallery, items []inventory.Item, dryRun bool) (Plan, error) {
+	stored, err := gallery.DisplayPoolItems(ctx, inventory.ItemFilter{})
+	if err != nil {
+		return Plan{}, fmt.Errorf("could not read the item pool: %w", err)
+	}
+
+	plan, err := Diff(stored, items)
+	if err != nil {
+		return Plan{}, err
+	}
+	if dryRun {
+		return plan, nil
+	}
+
+	if pending := plan.Pending(); len(pending) > 0 {
+		if err := gallery.SeedItems(ctx, pending); err != nil {
+			return Plan{}, fmt.Errorf("could not seed item pool: %w", err)
+		}
+	}
+	return plan, nil
+}
+
+// SeedSource loads the pool of src and seeds it. Nothing is written when any item is invalid.
+func SeedSource(ctx context.Context, gallery models.CharacterGallery, src Source, dryRun bool) (Plan, error) {
+	pool, err := src.Load()
+	if err != nil {
+		return Plan{}, err
+	}
+
+	plan, err := Seed(ctx, gallery, pool.Items, dryRun)
+	if err != nil {
+		return Plan{}, err
+	}
+	plan.Packs = pool.Packs
+	return plan, nil
+}
@@ -0,0 +1,316 @@
+package seeding
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dZev1/character-gallery/models"
+	"dZev1/character-gallery/models/inventory"
+)
+
+// fakeGallery implements the methods these tests call; anything else panics through the nil
+// embedded interface.
+type fakeGallery struct {
+	models.CharacterGallery
+	stored []inventory.Item
+	seeded []inventory.Item
+	// failures is how many of the next seeds fail.
+	failures int
+}
+
+func (f *fakeGallery) DisplayPoolItems(ctx context.Context, filter inventory.ItemFilter) ([]inventory.Item, error) {
+	return f.stored, nil
+}
+
+func (f *fakeGallery) SeedItems(ctx context.Context, items []inventory.Item) error {
+	if f.failures > 0 {
+		f.failures--
+		return models.Unavailable("database unavailable", nil)
+	}
+	f.seeded = append(f.seeded, items...)
+	return nil
+}
+
+func uint64Ptr(i uint64) *uint64 {
+	return &i
+}
+
+const validPool = `[
+  {"id": 1, "name": "Shield", "type": "shield", "description": "Made from metal", "equippable": true, "rarity": 2, "defense": 6},
+  {"id": 2, "name": "Backpack", "type": "adventuring_gear", "description": "Holds items", "rarity": 1, "capacity": 10}
+]`
+
+func TestLoad_Valid(t *testing.T) {
+	items, err := Load("pool.json", strings.NewReader(validPool))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 || items[1].Capacity == nil || *items[1].Capacity != 10 {
+		t.Errorf("unexpected items %+v", items)
+	}
+}
+
+func TestLoad_ReportsEveryErrorWithItsPosition(t *testing.T) {
+	pool := `[
+  {"id": 1, "name": "Shield", "type": "shield", "description": "Made from metal", "rarity": 2},
+  {"id": 2, "name": "Shortbow", "type": "weapon",
+   "description": "A bow", "damage": 10},
+  {"id": 1, "name": "Club", "type": "weapon", "description": "Bonk", "rarity": "common"},
+  {"id": 3, "name": "Ring", "type": "ring", "description": "Shiny", "rarity": 1, "weight": 2}
+]`
+
+	_, err := Load("pool.json", strings.NewReader(pool))
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected Errors, got %v", err)
+	}
+
+	want := []string{
+		"pool.json:3:3: [1].rarity: must be between 1 and 5",
+		"pool.json:5:70: [2].rarity: must be uint8, got string",
+		"pool.json:6:82: [3].weight: is not an item field",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %d:\n%v", len(want), len(errs), err)
+	}
+	for i, w := range want {
+		if errs[i].Error() != w {
+			t.Errorf("error %d: expected %q, got %q", i, w, errs[i].Error())
+		}
+	}
+}
+
+func TestLoad_DuplicateAndMissingIDs(t *testing.T) {
+	pool := `[
+  {"id": 4, "name": "Shield", "type": "shield", "description": "Made from metal", "rarity": 2},
+  {"id": 4, "name": "Buckler", "type": "shield", "description": "Made from wood", "rarity": 1},
+  {"name": "Dagger", "type": "weapon", "description": "Pointy", "rarity": 1}
+]`
+
+	_, err := Load("pool.json", strings.NewReader(pool))
+	for _, want := range []string{
+		"pool.json:3:4: [1].id: 4 is already used by the item on line 2",
+		"pool.json:4:3: [2].id: is required to seed an item",
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestLoad_SyntaxError(t *testing.T) {
+	_, err := Load("pool.json", strings.NewReader("[\n  {\"id\": 1,}\n]"))
+	if err == nil || !strings.HasPrefix(err.Error(), "pool.json:2:12: ") {
+		t.Errorf("expected a syntax error on line 2, got %v", err)
+	}
+
+	_, err = Load("pool.json", strings.NewReader(`{"id": 1}`))
+	if err == nil || !strings.Contains(err.Error(), "must hold a JSON array") {
+		t.Errorf("expected an array error, got %v", err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	stored := []inventory.Item{
+		{ID: 1, Name: "Shield", Type: inventory.Shield, Description: "Made from metal", Rarity: 2, Defense: uint64Ptr(6)},
+		{ID: 2, Name: "Backpack", Type: inventory.AdventuringGear, Description: "Holds items", Rarity: 1, Capacity: uint64Ptr(5)},
+		{ID: 9, Name: "Retired", Type: inventory.Ring, Description: "Not in the file", Rarity: 1},
+	}
+	seed := []inventory.Item{
+		{ID: 1, Name: "Shield", Type: inventory.Shield, Description: "Made from metal", Rarity: 2, Defense: uint64Ptr(6)},
+		{ID: 2, Name: "Backpack", Type: inventory.AdventuringGear, Description: "Holds items", Rarity: 1, Capacity: uint64Ptr(10)},
+		{ID: 3, Name: "Ring", Type: inventory.Ring, Description: "Shiny", Rarity: 1},
+	}
+
+	plan, err := Diff(stored, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actions := []Action{Unchanged, Update, Insert}
+	for i, want := range actions {
+		if plan.Changes[i].Action != want {
+			t.Errorf("change %d: expected %s, got %s", i, want, plan.Changes[i].Action)
+		}
+	}
+	if fields := plan.Changes[1].Fields; len(fields) != 1 || fields[0].Field != "capacity" || fields[0].Old != uint64(5) || fields[0].New != uint64(10) {
+		t.Errorf("expected only the capacity to change, got %+v", fields)
+	}
+
+	var out strings.Builder
+	if err := plan.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := "= 1 Shield\n~ 2 Backpack\n    capacity: 5 -> 10\n+ 3 Ring\n1 to insert, 1 to update, 1 unchanged\n"
+	if out.String() != want {
+		t.Errorf("expected diff:\n%s\ngot:\n%s", want, out.String())
+	}
+}
+
+func TestSeed_WritesOnlyPendingItems(t *testing.T) {
+	items, err := Load("pool.json", strings.NewReader(validPool))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gallery := &fakeGallery{stored: items[:1]}
+
+	plan, err := Seed(context.Background(), gallery, items, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(gallery.seeded) != 0 {
+		t.Errorf("expected a dry run to write nothing, got %+v", gallery.seeded)
+	}
+	if plan.Count(Insert) != 1 || plan.Count(Unchanged) != 1 {
+		t.Errorf("unexpected plan %+v", plan)
+	}
+
+	if _, err := Seed(context.Background(), gallery, items, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(gallery.seeded) != 1 || gallery.seeded[0].ID != 2 {
+		t.Errorf("expected only the new item to be written, got %+v", gallery.seeded)
+	}
+}
+
+func writePacks(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestSourceLoad_MergesPoolAndPacks(t *testing.T) {
+	dir := writePacks(t, map[string]string{
+		"core.yaml": `namespace: core
+version: 1.0.0
+items:
+  buckler:
+    name: Buckler
+    type: shield
+    description: A small shield
+    equippable: true
+    rarity: 1
+    defense: 2
+`,
+		"magic.json": `{"namespace": "magic", "version": "0.3.0", "items": {
+  "wand-of-sparks": {"name": "Wand of Sparks", "type": "wand", "description": "Crackles", "rarity": 3, "damage": 4}
+}}`,
+		"notes.txt": "not a pack",
+	})
+	pool := filepath.Join(t.TempDir(), "item_pool.json")
+	if err := os.WriteFile(pool, []byte(validPool), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Source{PoolFile: pool, PacksDir: dir}.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(loaded.Items) != 4 || len(loaded.Packs) != 2 {
+		t.Fatalf("expected 4 items from 2 packs and the pool, got %+v", loaded)
+	}
+	wand := loaded.Items[3]
+	if wand.Pack != "magic" || wand.Slug != "wand-of-sparks" || wand.ID != 0 || *wand.Damage != 4 {
+		t.Errorf("unexpected pack item %+v", wand)
+	}
+	if loaded.Packs[0].Namespace != "core" || loaded.Packs[0].Version != "1.0.0" || loaded.Packs[0].Items != 1 {
+		t.Errorf("unexpected pack %+v", loaded.Packs[0])
+	}
+}
+
+func TestSourceLoad_ReportsPackProblems(t *testing.T) {
+	dir := writePacks(t, map[string]string{
+		"a.yaml": `namespace: core
+version: 1.0.0
+items:
+  buckler:
+    name: Buckler
+    type: shield
+    description: A small shield
+    rarity: 1
+  Bad Slug:
+    name: Bad
+  club:
+    id: 5
+    name: Club
+    type: weapon
+    description: Bonk
+    rarity: 9
+`,
+		"b.yaml": `namespace: core
+version: 2.0.0
+items: {}
+`,
+		"c.yml": `namespace: extra
+version: 1.0.0
+items:
+  buckler:
+    name: Buckler
+    type: shield
+    description: Another small shield
+    rarity: 1
+`,
+	})
+
+	_, err := Source{PacksDir: dir}.Load()
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected Errors, got %v", err)
+	}
+
+	want := []string{
+		filepath.Join(dir, "a.yaml") + ":9:3: items.Bad Slug: slugs must be",
+		filepath.Join(dir, "a.yaml") + ":12:5: items.club.id: is set from the pack",
+		filepath.Join(dir, "a.yaml") + ":16:5: items.club.rarity: must be between 1 and 5",
+		filepath.Join(dir, "b.yaml") + ":1:1: namespace: core is already declared by " + filepath.Join(dir, "a.yaml"),
+		filepath.Join(dir, "c.yml") + ":4:3: items.buckler.name: \"Buckler\" with rarity 1 is already defined at " + filepath.Join(dir, "a.yaml") + ":4:3",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %d:\n%v", len(want), len(errs), err)
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(errs[i].Error(), w) {
+			t.Errorf("error %d: expected prefix %q, got %q", i, w, errs[i].Error())
+		}
+	}
+}
+
+func TestSourceLoad_NothingToSeed(t *testing.T) {
+	_, err := Source{PoolFile: "missing.json", PacksDir: "missing"}.Load()
+	if err == nil || !strings.Contains(err.Error(), "neither") {
+		t.Errorf("expected an error for a missing source, got %v", err)
+	}
+}
+
+func TestDiff_MatchesPackItemsBySlug(t *testing.T) {
+	stored := []inventory.Item{
+		{ID: 12, Name: "Buckler", Type: inventory.Shield, Description: "A small shield", Rarity: 1, Pack: "core", Slug: "buckler"},
+	}
+	seed := []inventory.Item{
+		{Name: "Buckler", Type: inventory.Shield, Description: "A sturdy shield", Rarity: 1, Pack: "core", Slug: "buckler"},
+	}
+
+	plan, err := Diff(stored, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	change := plan.Changes[0]
+	if change.Action != Update || change.Item.ID != 12 || len(change.Fields) != 1 || change.Fields[0].Field != "description" {
+		t.Errorf("expected an update of the stored item's description, got %+v", change)
+	}
+
+	_, err = Diff(stored, []inventory.Item{{ID: 12, Name: "Club", Type: inventory.Weapon, Description: "Bonk", Rarity: 1}})
+	if err == nil || !strings.Contains(err.Error(), "would replace core/buckler") {
+		t.Errorf("expected a numbered item to be refused over a pack item, got %v", err)
+	}
+}
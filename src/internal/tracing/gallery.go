@@ -0,0 +1,169 @@
+package tracing
+
+import (
+	"context"
+
+	"dZev1/character-gallery/models"
+	"dZev1/character-gallery/models/auth"
+	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/events"
+	"dZev1/character-gallery/models/inventory"
+	"dZev1/character-gallery/models/webhooks"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedGallery starts a span around every call made to the wrapped gallery. The SQL it runs
+// shows up as child spans when the database driver is instrumented too.
+type tracedGallery struct {
+	gallery models.CharacterGallery
+}
+
+func TraceGallery(gallery models.CharacterGallery) models.CharacterGallery {
+	return &tracedGallery{gallery: gallery}
+}
+
+func (g *tracedGallery) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, "CharacterGallery."+method,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (g *tracedGallery) Create(ctx context.Context, character *characters.Character) error {
+	ctx, span := g.start(ctx, "Create")
+	err := g.gallery.Create(ctx, character)
+	end(span, err)
+	return err
+}
+
+func (g *tracedGallery) Get(ctx context.Context, id characters.CharacterID) (*characters.Character, error) {
+	ctx, span := g.start(ctx, "Get", attribute.Int64("character.id", int64(id)))
+	character, err := g.gallery.Get(ctx, id)
+	end(span, err)
+	return character, err
+}
+
+func (g *tracedGallery) GetAll(ctx context.Context, filter characters.CharacterFilter, offset, limit int) ([]characters.Character, uint64, error) {
+	ctx, span := g.start(ctx, "GetAll", attribute.Int("offset", offset), attribute.Int("limit", limit))
+	chars, total, err := g.gallery.GetAll(ctx, filter, offset, limit)
+	end(span, err)
+	return chars, total, err
+}
+
+func (g *tracedGallery) Edit(ctx context.Context, character *characters.Character) error {
+	ctx, span := g.start(ctx, "Edit", attribute.Int64("character.id", int64(character.ID)))
+	err := g.gallery.Edit(ctx, character)
+	end(span, err)
+	return err
+}
+
+func (g *tracedGallery) Remove(ctx context.Context, id characters.CharacterID) error {
+	ctx, span := g.start(ctx, "Remove", attribute.Int64("character.id", int64(id)))
+	err := g.gallery.Remove(ctx, id)
+	end(span, err)
+	return err
+}
+
+func (g *tracedGallery) CountCharacters(ctx context.Context) (uint64, error) {
+	ctx, span := g.start(ctx, "CountCharacters")
+	total, err := g.gallery.CountCharacters(ctx)
+	end(span, err)
+	return total, err
+}
+
+func (g *tracedGallery) CreateItem(ctx context.Context, item *inventory.Item) error {
+	ctx, span := g.start(ctx, "CreateItem")
+	err := g.gallery.CreateItem(ctx, item)
+	end(span, err)
+	return err
+}
+
+func (g *tracedGallery) SeedItems(ctx context.Context, items []inventory.Item) error {
+	ctx, span := g.start(ctx, "SeedItems", attribute.Int("items", len(items)))
+	err := g.gallery.SeedItems(ctx, items)
+	end(span, err)
+	return err
+}
+
+func (g *tracedGallery) DisplayPoolItems(ctx context.Context, filter inventory.ItemFilter) ([]inventory.Item, error) {
+	ctx, span := g.start(ctx, "DisplayPoolItems", attribute.String("item.pack", filter.Pack))
+	items, err := g.gallery.DisplayPoolItems(ctx, filter)
+	end(span, err)
+	return items, err
+}
+
+func (g *tracedGallery) DisplayItem(ctx context.Context, itemID inventory.ItemID) (*inventory.Item, error) {
+	ctx, span := g.start(ctx, "DisplayItem", attribute.Int64("item.id", int64(itemID)))
+	item, err := g.gallery.DisplayItem(ctx, itemID)
+	end(span, err)
+	return item, err
+}
+
+func (g *tracedGallery) AddItemToCharacter(ctx context.Context, characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) (*inventory.InventoryItem, error) {
+	ctx, span := g.start(ctx, "AddItemToCharacter", attribute.Int64("character.id", int64(characterID)), attribute.Int64("item.id", int64(itemID)), attribute.Int("quantity", int(quantity)))
+	item, err := g.gallery.AddItemToCharacter(ctx, characterID, itemID, quantity)
+	end(span, err)
+	return item, err
+}
+
+func (g *tracedGallery) RemoveItemFromCharacter(ctx context.Context, characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) error {
+	ctx, span := g.start(ctx, "RemoveItemFromCharacter", attribute.Int64("character.id", int64(characterID)), attribute.Int64("item.id", int64(itemID)), attribute.Int("quantity", int(quantity)))
+	err := g.gallery.RemoveItemFromCharacter(ctx, characterID, itemID, quantity)
+	end(span, err)
+	return err
+}
+
+func (g *tracedGallery) GetCharacterInventory(ctx context.Context, characterID characters.CharacterID) ([]inventory.InventoryItem, error) {
+	ctx, span := g.start(ctx, "GetCharacterInventory", attribute.Int64("character.id", int64(characterID)))
+	items, err := g.gallery.GetCharacterInventory(ctx, characterID)
+	end(span, err)
+	return items, err
+}
+
+func (g *tracedGallery) GetCharacterInventories(ctx context.Context, characterIDs []characters.CharacterID) (map[characters.CharacterID][]inventory.InventoryItem, error) {
+	ctx, span := g.start(ctx, "GetCharacterInventories", attribute.Int("character.count", len(characterIDs)))
+	inventories, err := g.gallery.GetCharacterInventories(ctx, characterIDs)
+	end(span, err)
+	return inventories, err
+}
+
+func (g *tracedGallery) CountItems(ctx context.Context) (uint64, error) {
+	ctx, span := g.start(ctx, "CountItems")
+	total, err := g.gallery.CountItems(ctx)
+	end(span, err)
+	return total, err
+}
+
+func (g *tracedGallery) Close() error {
+	return g.gallery.Close()
+}
+
+func (g *tracedGallery) GetAuthStore() auth.AuthStore {
+	return g.gallery.GetAuthStore()
+}
+
+func (g *tracedGallery) GetWebhookStore() webhooks.WebhookStore {
+	return g.gallery.GetWebhookStore()
+}
+
+func (g *tracedGallery) GetOutboxStore() events.OutboxStore {
+	return g.gallery.GetOutboxStore()
+}
+
+func (g *tracedGallery) Batch(ctx context.Context, fn func(ctx context.Context, tx models.BatchTx) error) error {
+	ctx, span := g.start(ctx, "Batch")
+	err := g.gallery.Batch(ctx, fn)
+	end(span, err)
+	return err
+}
@@ -0,0 +1,82 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const ServiceName = "character-gallery"
+
+func Tracer() trace.Tracer {
+	return otel.Tracer("dZev1/character-gallery")
+}
+
+// NewExporter returns one of the built-in exporters: "stdout", or "file" writing to path. An
+// empty kind or "none" returns nil, which disables tracing.
+func NewExporter(kind, path string) (sdktrace.SpanExporter, error) {
+	switch kind {
+	case "", "none":
+		return nil, nil
+	case "stdout":
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "file":
+		if path == "" {
+			return nil, fmt.Errorf("the file trace exporter needs a path")
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("could not open trace file: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &closingExporter{SpanExporter: exporter, closer: file}, nil
+	default:
+		return nil, fmt.Errorf("unsupported trace exporter: %s", kind)
+	}
+}
+
+// Setup installs a global tracer provider sending spans to exporter, and the W3C trace context
+// propagator. Any SpanExporter works, such as an OTLP one pointing at a collector. The returned
+// function flushes pending spans and must be called before exiting.
+func Setup(exporter sdktrace.SpanExporter) func(context.Context) error {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	if exporter == nil {
+		return func(context.Context) error { return nil }
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown
+}
+
+// closingExporter closes the file the wrapped exporter writes to once it shuts down.
+type closingExporter struct {
+	sdktrace.SpanExporter
+	closer io.Closer
+}
+
+func (e *closingExporter) Shutdown(ctx context.Context) error {
+	err := e.SpanExporter.Shutdown(ctx)
+	if closeErr := e.closer.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,120 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dZev1/character-gallery/models"
+	"dZev1/character-gallery/models/characters"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// fakeGallery implements the methods these tests call; anything else panics through the nil
+// embedded interface.
+type fakeGallery struct {
+	models.CharacterGallery
+}
+
+func (f *fakeGallery) Remove(ctx context.Context, id characters.CharacterID) error {
+	if id == 0 {
+		return errors.New("not found")
+	}
+	return nil
+}
+
+func useRecorder(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	return exporter
+}
+
+func TestTraceGallery(t *testing.T) {
+	exporter := useRecorder(t)
+	gallery := TraceGallery(&fakeGallery{})
+
+	gallery.Remove(context.Background(), 5)
+	gallery.Remove(context.Background(), 0)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	ok, failed := spans[0], spans[1]
+	if ok.Name != "CharacterGallery.Remove" {
+		t.Errorf("unexpected span name %q", ok.Name)
+	}
+	if !hasAttribute(ok.Attributes, attribute.Int64("character.id", 5)) {
+		t.Errorf("expected character.id attribute, got %v", ok.Attributes)
+	}
+	if ok.Status.Code == codes.Error {
+		t.Error("expected the successful call not to be marked as an error")
+	}
+	if failed.Status.Code != codes.Error || len(failed.Events) == 0 {
+		t.Errorf("expected the failed call to record its error, got status %v", failed.Status)
+	}
+}
+
+func TestNewExporter(t *testing.T) {
+	if exporter, err := NewExporter("", ""); exporter != nil || err != nil {
+		t.Errorf("expected no exporter when tracing is disabled, got %v, %v", exporter, err)
+	}
+	if _, err := NewExporter("jaeger", ""); err == nil {
+		t.Error("expected an error for an unknown exporter")
+	}
+	if _, err := NewExporter("file", ""); err == nil {
+		t.Error("expected an error for a file exporter without a path")
+	}
+}
+
+func TestNewExporter_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.json")
+	exporter, err := NewExporter("file", path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	shutdown := Setup(exporter)
+
+	_, span := Tracer().Start(context.Background(), "test-span")
+	span.End()
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read trace file: %v", err)
+	}
+	if !strings.Contains(string(contents), "test-span") {
+		t.Errorf("expected the span in the trace file, got %q", contents)
+	}
+}
+
+func hasAttribute(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, attr := range attrs {
+		if attr == want {
+			return true
+		}
+	}
+	return false
+}
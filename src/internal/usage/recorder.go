@@ -0,0 +1,183 @@
+package usage
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"dZev1/character-gallery/models/auth"
+)
+
+// Store is the part of auth.AuthStore the recorder needs.
+type Store interface {
+	RecordUsage(records []auth.UsageRecord) error
+	MonthlyUsage(id auth.APIKeyID, month time.Time) (uint64, error)
+}
+
+type usageKey struct {
+	keyID       auth.APIKeyID
+	day         time.Time
+	route       string
+	statusClass string
+}
+
+type monthlyCount struct {
+	month time.Time
+	count uint64
+}
+
+// Recorder counts requests in memory and writes them to the store in one batch per interval.
+//
+// Monthly counts are only kept for keys with a quota. They are loaded from the store the first
+// time such a key is seen in a month and then advanced locally, so with several replicas a quota
+// is enforced per replica on top of what the others had flushed when it was loaded.
+type Recorder struct {
+	store    Store
+	interval time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	pending map[usageKey]uint64
+	monthly map[auth.APIKeyID]*monthlyCount
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewRecorder starts a recorder that flushes every interval until Close is called.
+func NewRecorder(store Store, interval time.Duration) *Recorder {
+	r := &Recorder{
+		store:    store,
+		interval: interval,
+		now:      time.Now,
+		pending:  make(map[usageKey]uint64),
+		monthly:  make(map[auth.APIKeyID]*monthlyCount),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+func (r *Recorder) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.Flush()
+		case <-r.stop:
+			r.Flush()
+			return
+		}
+	}
+}
+
+// Close stops the background flusher after writing whatever is still pending.
+func (r *Recorder) Close() {
+	r.closeOnce.Do(func() {
+		close(r.stop)
+		<-r.done
+	})
+}
+
+// Record counts one request made by keyID to route that was answered with status.
+func (r *Recorder) Record(keyID auth.APIKeyID, route string, status int) {
+	now := r.now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pending[usageKey{
+		keyID:       keyID,
+		day:         auth.StartOfDay(now),
+		route:       route,
+		statusClass: auth.StatusClass(status),
+	}]++
+
+	if counter, ok := r.monthly[keyID]; ok && counter.month.Equal(auth.StartOfMonth(now)) {
+		counter.count++
+	}
+}
+
+// Allow reports whether key still has requests left in its monthly quota.
+func (r *Recorder) Allow(key *auth.APIKey) (bool, error) {
+	if key.MonthlyQuota == nil {
+		return true, nil
+	}
+
+	month := auth.StartOfMonth(r.now())
+
+	r.mu.Lock()
+	counter, ok := r.monthly[key.ID]
+	if ok && counter.month.Equal(month) {
+		allowed := counter.count < *key.MonthlyQuota
+		r.mu.Unlock()
+		return allowed, nil
+	}
+	r.mu.Unlock()
+
+	// Load outside the lock so a slow store does not hold up every other request.
+	stored, err := r.store.MonthlyUsage(key.ID, month)
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counter, ok = r.monthly[key.ID]
+	if !ok || !counter.month.Equal(month) {
+		counter = &monthlyCount{month: month, count: stored + r.pendingFor(key.ID, month)}
+		r.monthly[key.ID] = counter
+	}
+	return counter.count < *key.MonthlyQuota, nil
+}
+
+// pendingFor sums the unflushed requests of keyID in month. r.mu must be held.
+func (r *Recorder) pendingFor(keyID auth.APIKeyID, month time.Time) uint64 {
+	var total uint64
+	for key, count := range r.pending {
+		if key.keyID == keyID && auth.StartOfMonth(key.day).Equal(month) {
+			total += count
+		}
+	}
+	return total
+}
+
+// Flush writes pending counts to the store. On failure they are kept for the next flush.
+func (r *Recorder) Flush() {
+	r.mu.Lock()
+	if len(r.pending) == 0 {
+		r.mu.Unlock()
+		return
+	}
+	batch := r.pending
+	r.pending = make(map[usageKey]uint64)
+	r.mu.Unlock()
+
+	records := make([]auth.UsageRecord, 0, len(batch))
+	for key, count := range batch {
+		records = append(records, auth.UsageRecord{
+			KeyID:       key.keyID,
+			Day:         key.day,
+			Route:       key.route,
+			StatusClass: key.statusClass,
+			Count:       count,
+		})
+	}
+
+	if err := r.store.RecordUsage(records); err != nil {
+		slog.Error("could not flush usage records, retrying later", "records", len(records), "error", err)
+
+		r.mu.Lock()
+		for key, count := range batch {
+			r.pending[key] += count
+		}
+		r.mu.Unlock()
+	}
+}
@@ -0,0 +1,146 @@
+package usage
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"dZev1/character-gallery/models/auth"
+)
+
+type fakeStore struct {
+	mu        sync.Mutex
+	batches   [][]auth.UsageRecord
+	monthly   uint64
+	recordErr error
+}
+
+func (s *fakeStore) RecordUsage(records []auth.UsageRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.recordErr != nil {
+		return s.recordErr
+	}
+	s.batches = append(s.batches, records)
+	return nil
+}
+
+func (s *fakeStore) MonthlyUsage(id auth.APIKeyID, month time.Time) (uint64, error) {
+	return s.monthly, nil
+}
+
+func newTestRecorder(store Store) *Recorder {
+	r := NewRecorder(store, time.Hour)
+	r.now = func() time.Time { return time.Date(2025, 3, 17, 12, 0, 0, 0, time.UTC) }
+	return r
+}
+
+func quota(n uint64) *uint64 {
+	return &n
+}
+
+func TestRecorder_FlushesOneAggregatedBatch(t *testing.T) {
+	store := &fakeStore{}
+	recorder := newTestRecorder(store)
+	defer recorder.Close()
+
+	for range 3 {
+		recorder.Record(1, "GET /characters", 200)
+	}
+	recorder.Record(1, "GET /characters", 404)
+	recorder.Record(2, "POST /characters", 201)
+
+	recorder.Flush()
+
+	if len(store.batches) != 1 {
+		t.Fatalf("expected one batch, got %d", len(store.batches))
+	}
+
+	counts := make(map[string]uint64)
+	for _, record := range store.batches[0] {
+		counts[record.KeyID.String()+" "+record.Route+" "+record.StatusClass] = record.Count
+	}
+
+	if counts["1 GET /characters 2xx"] != 3 || counts["1 GET /characters 4xx"] != 1 || counts["2 POST /characters 2xx"] != 1 {
+		t.Fatalf("unexpected counts: %v", counts)
+	}
+
+	recorder.Flush()
+	if len(store.batches) != 1 {
+		t.Fatal("expected no write when nothing is pending")
+	}
+}
+
+func TestRecorder_KeepsRecordsWhenFlushFails(t *testing.T) {
+	store := &fakeStore{recordErr: errors.New("database down")}
+	recorder := newTestRecorder(store)
+	defer recorder.Close()
+
+	recorder.Record(1, "GET /items", 200)
+	recorder.Flush()
+
+	store.mu.Lock()
+	store.recordErr = nil
+	store.mu.Unlock()
+
+	recorder.Record(1, "GET /items", 200)
+	recorder.Flush()
+
+	if len(store.batches) != 1 || store.batches[0][0].Count != 2 {
+		t.Fatalf("expected failed records to be retried, got %+v", store.batches)
+	}
+}
+
+func TestRecorder_CloseFlushes(t *testing.T) {
+	store := &fakeStore{}
+	recorder := newTestRecorder(store)
+
+	recorder.Record(1, "GET /items", 200)
+	recorder.Close()
+
+	if len(store.batches) != 1 {
+		t.Fatalf("expected pending records to be flushed on close, got %d batches", len(store.batches))
+	}
+}
+
+func TestRecorder_AllowEnforcesMonthlyQuota(t *testing.T) {
+	store := &fakeStore{monthly: 8}
+	recorder := newTestRecorder(store)
+	defer recorder.Close()
+
+	key := &auth.APIKey{ID: 1, MonthlyQuota: quota(10)}
+
+	for i := range 2 {
+		allowed, err := recorder.Allow(key)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !allowed {
+			t.Fatalf("expected request %d to be allowed", i)
+		}
+		recorder.Record(key.ID, "GET /items", 200)
+	}
+
+	allowed, err := recorder.Allow(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if allowed {
+		t.Fatal("expected quota to be exhausted")
+	}
+}
+
+func TestRecorder_AllowWithoutQuota(t *testing.T) {
+	recorder := newTestRecorder(&fakeStore{monthly: 1 << 40})
+	defer recorder.Close()
+
+	allowed, err := recorder.Allow(&auth.APIKey{ID: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !allowed {
+		t.Fatal("expected keys without quota to always be allowed")
+	}
+}
@@ -1,7 +1,34 @@
 package auth
 
+import "time"
+
+// AuthStore keeps API keys and their usage. The methods taking an actor record the change in
+// the audit log, in the same transaction, so that a change is kept exactly when its entry is.
 type AuthStore interface {
-	ValidateAPIKey(keyHash string) (bool, error)
-	UpdateLastUsed(keyHash string) error
-	CreateAPIKey(name string) (string, error)
-}
\ No newline at end of file
+	// ValidateAPIKey returns the key matching keyHash, or ErrAPIKeyNotFound, ErrAPIKeyRevoked
+	// or ErrAPIKeyExpired when it cannot be used.
+	ValidateAPIKey(keyHash string) (*APIKey, error)
+	// UpdateLastUsed marks every key in keyHashes as used now.
+	UpdateLastUsed(keyHashes ...string) error
+	// CreateAPIKey stores a new key and returns it along with the raw key, which is never persisted.
+	// A zero expiresIn creates a key that never expires.
+	CreateAPIKey(actor Actor, name string, scopes Scopes, expiresIn time.Duration) (*APIKey, string, error)
+	RevokeAPIKey(actor Actor, id APIKeyID) error
+	ReactivateAPIKey(actor Actor, id APIKeyID) error
+	ListAPIKeys() ([]APIKey, error)
+	// RotateAPIKey issues a replacement with the same name, scopes and expiry, and lets the old
+	// key keep working for gracePeriod. Revoked and expired keys can't be rotated, and return
+	// ErrAPIKeyRevoked or ErrAPIKeyExpired.
+	RotateAPIKey(actor Actor, id APIKeyID, gracePeriod time.Duration) (*APIKey, string, error)
+	// PruneAPIKeys deletes keys that have not been used (or, if never used, created) within unusedFor.
+	PruneAPIKeys(actor Actor, unusedFor time.Duration) (int64, error)
+	GetAPIKey(id APIKeyID) (*APIKey, error)
+	// SetMonthlyQuota limits how many requests a key can make per calendar month (UTC).
+	// A zero quota removes the limit.
+	SetMonthlyQuota(actor Actor, id APIKeyID, quota uint64) error
+	// RecordUsage adds the counts of records to the stored usage in a single batch.
+	RecordUsage(records []UsageRecord) error
+	GetUsage(id APIKeyID, from time.Time, to time.Time) ([]UsageRecord, error)
+	// MonthlyUsage returns the stored request count of a key for the month containing month.
+	MonthlyUsage(id APIKeyID, month time.Time) (uint64, error)
+}
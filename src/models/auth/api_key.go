@@ -9,12 +9,15 @@ import (
 )
 
 type APIKey struct {
-	ID          APIKeyID  `json:"id" db:"id"`
-	KeyHash     string    `json:"key_hash" db:"key_hash"`
-	Name        string    `json:"name" db:"name"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	LastUsedAt  time.Time `json:"last_used_at" db:"last_used_at"`
-	IsActive    bool      `json:"is_active" db:"is_active"`
+	ID           APIKeyID   `json:"id" db:"id"`
+	KeyHash      string     `json:"-" db:"key_hash"`
+	Name         string     `json:"name" db:"name"`
+	Scopes       Scopes     `json:"scopes" db:"scopes"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt   *time.Time `json:"last_used_at" db:"last_used_at"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	MonthlyQuota *uint64    `json:"monthly_quota,omitempty" db:"monthly_quota"`
+	IsActive     bool       `json:"is_active" db:"is_active"`
 }
 
 type APIKeyID uint64
@@ -40,4 +43,4 @@ func GenerateAPIKey() (keyHash string, rawKey string, err error) {
 func HashAPIKey(apiKey string) string {
 	hash := sha256.Sum256([]byte(apiKey))
 	return hex.EncodeToString(hash[:])
-}
\ No newline at end of file
+}
@@ -0,0 +1,24 @@
+package auth
+
+type AuditAction string
+
+const (
+	AuditCreate     AuditAction = "create"
+	AuditRevoke     AuditAction = "revoke"
+	AuditReactivate AuditAction = "reactivate"
+	AuditRotate     AuditAction = "rotate"
+	AuditPrune      AuditAction = "prune"
+	AuditSetQuota   AuditAction = "set_quota"
+)
+
+const (
+	AuditSourceAPI = "api"
+	AuditSourceCLI = "cli"
+)
+
+// Actor is who performs a key-management action, as recorded in the audit log. KeyID is the
+// key that performed it, and is nil for actions run from the command line.
+type Actor struct {
+	KeyID  *APIKeyID
+	Source string
+}
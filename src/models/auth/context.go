@@ -0,0 +1,16 @@
+package auth
+
+import "context"
+
+type contextKey struct{}
+
+// WithAPIKey returns a copy of ctx carrying the key that authenticated the request.
+func WithAPIKey(ctx context.Context, key *APIKey) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// APIKeyFromContext returns the key stored by WithAPIKey, if any.
+func APIKeyFromContext(ctx context.Context) (*APIKey, bool) {
+	key, ok := ctx.Value(contextKey{}).(*APIKey)
+	return key, ok && key != nil
+}
@@ -0,0 +1,9 @@
+package auth
+
+import "errors"
+
+var (
+	ErrAPIKeyNotFound = errors.New(`api key not found`)
+	ErrAPIKeyRevoked  = errors.New(`api key has been revoked`)
+	ErrAPIKeyExpired  = errors.New(`api key has expired`)
+)
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type Scope string
+
+const (
+	ScopeRead  Scope = "read"
+	ScopeWrite Scope = "write"
+	ScopeAdmin Scope = "admin"
+)
+
+var AllScopes = []Scope{ScopeRead, ScopeWrite, ScopeAdmin}
+
+// DefaultScopes are granted to keys created without an explicit scope list.
+var DefaultScopes = Scopes{ScopeRead, ScopeWrite}
+
+func (s Scope) String() string {
+	return string(s)
+}
+
+func (s Scope) Validate() bool {
+	return slices.Contains(AllScopes, s)
+}
+
+// Scopes is stored as a comma separated list so every backend can keep it in a plain text column.
+type Scopes []Scope
+
+func ParseScopes(s string) (Scopes, error) {
+	var scopes Scopes
+	for _, part := range strings.Split(s, ",") {
+		scope := Scope(strings.TrimSpace(part))
+		if scope == "" {
+			continue
+		}
+		if !scope.Validate() {
+			return nil, fmt.Errorf("unknown scope: %q", scope)
+		}
+		if !scopes.Has(scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+func (s Scopes) Has(scope Scope) bool {
+	return slices.Contains(s, scope)
+}
+
+func (s Scopes) String() string {
+	parts := make([]string, len(s))
+	for i, scope := range s {
+		parts[i] = scope.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+func (s Scopes) Value() (driver.Value, error) {
+	return s.String(), nil
+}
+
+func (s *Scopes) Scan(src any) error {
+	var raw string
+	switch v := src.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Scopes", src)
+	}
+
+	scopes, err := ParseScopes(raw)
+	if err != nil {
+		return err
+	}
+	*s = scopes
+	return nil
+}
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+)
+
+// UsageRecord counts the requests a key made to a route on a given day, grouped by the
+// class of the response status ("2xx", "4xx", ...).
+type UsageRecord struct {
+	KeyID       APIKeyID  `json:"-" db:"key_id"`
+	Day         time.Time `json:"day" db:"day"`
+	Route       string    `json:"route" db:"route"`
+	StatusClass string    `json:"status_class" db:"status_class"`
+	Count       uint64    `json:"count" db:"count"`
+}
+
+func StatusClass(status int) string {
+	return fmt.Sprintf("%dxx", status/100)
+}
+
+// StartOfDay and StartOfMonth truncate t in UTC, which is how usage is bucketed.
+func StartOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func StartOfMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
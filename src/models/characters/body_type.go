@@ -1,5 +1,11 @@
 package characters
 
+import (
+	"slices"
+
+	"dZev1/character-gallery/models/validation"
+)
+
 type BodyType string
 
 const (
@@ -7,14 +13,15 @@ const (
 	TypeB BodyType = "type_b"
 )
 
+var AllBodyTypes = []BodyType{TypeA, TypeB}
+
 func (bt BodyType) String() string {
 	return string(bt)
 }
 
-func (bt BodyType) Validate() bool {
-	switch bt {
-	case TypeA, TypeB:
-		return true
+func (bt BodyType) Validate() error {
+	if !slices.Contains(AllBodyTypes, bt) {
+		return validation.OneOf(bt, AllBodyTypes)
 	}
-	return false
+	return nil
 } 
\ No newline at end of file
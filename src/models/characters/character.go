@@ -1,6 +1,10 @@
 package characters
 
-import "fmt"
+import (
+	"fmt"
+
+	"dZev1/character-gallery/models/validation"
+)
 
 const formatString = "\nName: %v\nSpecies: %v\nBody Type: %v\nClass: %v\n\n-STATS-\n%v\n\nCustomization: %v\n\n"
 
@@ -14,6 +18,15 @@ type Character struct {
 	Customization *Customization `json:"customization"`
 }
 
+// CharacterFilter narrows down the characters returned from the gallery. Empty fields match every
+// character, and Name matches any character whose name contains it, ignoring case.
+type CharacterFilter struct {
+	Name     string
+	BodyType BodyType
+	Species  Species
+	Class    Class
+}
+
 func (char *Character) String() string {
 	return fmt.Sprintf(formatString,
 		char.Name,
@@ -24,3 +37,29 @@ func (char *Character) String() string {
 		char.Customization,
 	)
 }
+
+// Validate reports every invalid field of the character.
+func (char *Character) Validate() error {
+	var errs validation.Errors
+
+	if len(char.Name) < 2 {
+		errs.Add("name", validation.CodeTooShort, "must be at least 2 characters long")
+	}
+	errs.Merge("body_type", char.BodyType.Validate())
+	errs.Merge("species", char.Species.Validate())
+	errs.Merge("class", char.Class.Validate())
+
+	if char.Stats == nil {
+		errs.Add("stats", validation.CodeRequired, "is required")
+	} else {
+		errs.Merge("stats", char.Stats.Validate())
+	}
+
+	if char.Customization == nil {
+		errs.Add("customization", validation.CodeRequired, "is required")
+	} else {
+		errs.Merge("customization", char.Customization.Validate())
+	}
+
+	return errs.Err()
+}
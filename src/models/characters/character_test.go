@@ -0,0 +1,72 @@
+package characters
+
+import (
+	"errors"
+	"testing"
+
+	"dZev1/character-gallery/models/validation"
+)
+
+func validCharacter() *Character {
+	return &Character{
+		Name:          "Aria",
+		BodyType:      AllBodyTypes[0],
+		Species:       AllSpecies[0],
+		Class:         AllClasses[0],
+		Stats:         &Stats{Strength: 10, Dexterity: 10, Constitution: 10, Intelligence: 10, Wisdom: 10, Charisma: 10},
+		Customization: &Customization{Hair: 1, Face: 1, Shirt: 1, Pants: 1, Shoes: 1},
+	}
+}
+
+func TestCharacterValidate_Valid(t *testing.T) {
+	if err := validCharacter().Validate(); err != nil {
+		t.Fatalf("expected valid character, got %v", err)
+	}
+}
+
+func TestCharacterValidate_ReportsEveryField(t *testing.T) {
+	char := validCharacter()
+	char.Name = "A"
+	char.Class = "Bard-ish"
+	char.Stats.Strength = 0
+	char.Stats.Charisma = 100
+	char.Customization.Hair = 31
+
+	var errs validation.Errors
+	if !errors.As(char.Validate(), &errs) {
+		t.Fatalf("expected validation.Errors")
+	}
+
+	want := map[string]string{
+		"name":               validation.CodeTooShort,
+		"class":              validation.CodeInvalidValue,
+		"stats.strength":     validation.CodeOutOfRange,
+		"stats.charisma":     validation.CodeOutOfRange,
+		"customization.hair": validation.CodeOutOfRange,
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %v", len(want), errs)
+	}
+	for _, fieldErr := range errs {
+		if want[fieldErr.Path] != fieldErr.Code {
+			t.Errorf("unexpected error %+v", fieldErr)
+		}
+	}
+}
+
+func TestCharacterValidate_MissingStats(t *testing.T) {
+	char := validCharacter()
+	char.Stats = nil
+	char.Customization = nil
+
+	var errs validation.Errors
+	if !errors.As(char.Validate(), &errs) {
+		t.Fatalf("expected validation.Errors")
+	}
+	if len(errs) != 2 || errs[0].Path != "stats" || errs[1].Path != "customization" {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	if errs[0].Code != validation.CodeRequired {
+		t.Errorf("expected code %s, got %s", validation.CodeRequired, errs[0].Code)
+	}
+}
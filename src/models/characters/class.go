@@ -1,5 +1,11 @@
 package characters
 
+import (
+	"slices"
+
+	"dZev1/character-gallery/models/validation"
+)
+
 type Class string
 
 const (
@@ -17,14 +23,15 @@ const (
 	Wizard    Class = "wizard"
 )
 
+var AllClasses = []Class{Barbarian, Bard, Cleric, Druid, Fighter, Monk, Paladin, Ranger, Rogue, Sorcerer, Warlock, Wizard}
+
 func (c Class) String() string {
 	return string(c)
 }
 
-func (c Class) Validate() bool {
-	switch c {
-	case Barbarian, Bard, Cleric, Druid, Fighter, Monk, Paladin, Ranger, Rogue, Sorcerer, Warlock, Wizard:
-		return true
+func (c Class) Validate() error {
+	if !slices.Contains(AllClasses, c) {
+		return validation.OneOf(c, AllClasses)
 	}
-	return false
+	return nil
 }
\ No newline at end of file
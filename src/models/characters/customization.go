@@ -1,6 +1,10 @@
 package characters
 
-import "fmt"
+import (
+	"fmt"
+
+	"dZev1/character-gallery/models/validation"
+)
 
 type Customization struct {
 	ID    CharacterID `db:"id" json:"-"`
@@ -21,11 +25,21 @@ func (c *Customization) String() string {
 	)
 }
 
-func (c *Customization) Validate() bool {
-	for _, customization := range []uint8{c.Hair, c.Face, c.Shirt, c.Pants, c.Shoes} {
-		if customization > 30 {
-			return false
+func (c *Customization) Validate() error {
+	var errs validation.Errors
+	for _, part := range []struct {
+		name  string
+		value uint8
+	}{
+		{"hair", c.Hair},
+		{"face", c.Face},
+		{"shirt", c.Shirt},
+		{"pants", c.Pants},
+		{"shoes", c.Shoes},
+	} {
+		if part.value > 30 {
+			errs.Add(part.name, validation.CodeOutOfRange, "must be between 0 and 30")
 		}
 	}
-	return true
+	return errs.Err()
 }
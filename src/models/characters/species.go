@@ -1,5 +1,11 @@
 package characters
 
+import (
+	"slices"
+
+	"dZev1/character-gallery/models/validation"
+)
+
 type Species string
 
 const (
@@ -15,14 +21,15 @@ const (
 	Tiefling   Species = "tiefling"
 )
 
+var AllSpecies = []Species{Aasimar, Dragonborn, Dwarf, Elf, Gnome, Goliath, Halfling, Human, Orc, Tiefling}
+
 func (s Species) String() string {
 	return string(s)
 }
 
-func (s Species) Validate() bool {
-	switch s {
-	case Aasimar, Dragonborn, Dwarf, Elf, Gnome, Goliath, Halfling, Human, Orc, Tiefling:
-		return true
+func (s Species) Validate() error {
+	if !slices.Contains(AllSpecies, s) {
+		return validation.OneOf(s, AllSpecies)
 	}
-	return false
+	return nil
 }
\ No newline at end of file
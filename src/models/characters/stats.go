@@ -1,6 +1,10 @@
 package characters
 
-import "fmt"
+import (
+	"fmt"
+
+	"dZev1/character-gallery/models/validation"
+)
 
 type Stats struct {
 	ID           CharacterID `db:"id" json:"-"`
@@ -23,11 +27,22 @@ func (s *Stats) String() string {
 	)
 }
 
-func (s *Stats) Validate() bool {
-	for _, stat := range []uint8{s.Strength, s.Dexterity, s.Constitution, s.Intelligence, s.Wisdom, s.Charisma} {
-		if stat < 1 || stat > 99 {
-			return false
+func (s *Stats) Validate() error {
+	var errs validation.Errors
+	for _, stat := range []struct {
+		name  string
+		value uint8
+	}{
+		{"strength", s.Strength},
+		{"dexterity", s.Dexterity},
+		{"constitution", s.Constitution},
+		{"intelligence", s.Intelligence},
+		{"wisdom", s.Wisdom},
+		{"charisma", s.Charisma},
+	} {
+		if stat.value < 1 || stat.value > 99 {
+			errs.Add(stat.name, validation.CodeOutOfRange, "must be between 1 and 99")
 		}
 	}
-	return true
+	return errs.Err()
 }
\ No newline at end of file
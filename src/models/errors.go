@@ -0,0 +1,51 @@
+package models
+
+import "errors"
+
+// Kinds of failure every CharacterGallery implementation reports, whatever its storage. Check
+// them with errors.Is.
+var (
+	ErrNotFound    = errors.New(`not found`)
+	ErrConflict    = errors.New(`conflict`)
+	ErrValidation  = errors.New(`validation failed`)
+	ErrUnavailable = errors.New(`storage unavailable`)
+)
+
+// Error is a gallery failure of one of the kinds above. Message can be shown to API clients,
+// while Err keeps the storage error for logs.
+type Error struct {
+	Kind    error
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func NotFound(message string, err error) error {
+	return &Error{Kind: ErrNotFound, Message: message, Err: err}
+}
+
+func Conflict(message string, err error) error {
+	return &Error{Kind: ErrConflict, Message: message, Err: err}
+}
+
+func Invalid(message string, err error) error {
+	return &Error{Kind: ErrValidation, Message: message, Err: err}
+}
+
+func Unavailable(message string, err error) error {
+	return &Error{Kind: ErrUnavailable, Message: message, Err: err}
+}
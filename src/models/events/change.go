@@ -0,0 +1,45 @@
+package events
+
+// Entity is the kind of record a Change is about.
+type Entity string
+
+const (
+	EntityCharacter Entity = "character"
+	EntityInventory Entity = "inventory"
+	EntityItem      Entity = "item"
+	EntityItemPool  Entity = "item_pool"
+	EntityAPIKey    Entity = "api_key"
+)
+
+// Actions say what happened to the entity. Together they name the event type of gallery
+// changes, e.g. character and created make character.created.
+const (
+	ActionCreated     = "created"
+	ActionUpdated     = "updated"
+	ActionDeleted     = "deleted"
+	ActionItemAdded   = "item_added"
+	ActionItemRemoved = "item_removed"
+	ActionSeeded      = "seeded"
+	// ActionMissed is sent by a listener that lost its connection and got it back: changes
+	// committed meanwhile were missed, so anything cached should be dropped.
+	ActionMissed = "missed"
+)
+
+// Change is the notice a backend sends every instance when it commits a write, so that each can
+// drop what it cached. It only names what changed; the events reporting writes, with their data,
+// go through the outbox.
+type Change struct {
+	Entity Entity `json:"entity,omitempty"`
+	Action string `json:"action"`
+	// ID is the character of character and inventory changes, the item of item changes and the
+	// API key of api_key changes. It is 0 when every record may have changed.
+	ID uint64 `json:"id,omitempty"`
+	// Origin identifies the instance that made the write, so that it can skip its own.
+	Origin string `json:"origin,omitempty"`
+}
+
+// EventType returns the type of the event a gallery change is reported as, which is only valid
+// for gallery entities.
+func (c Change) EventType() EventType {
+	return EventType(string(c.Entity) + "." + c.Action)
+}
@@ -0,0 +1,154 @@
+package events
+
+import (
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/inventory"
+)
+
+type EventType string
+
+const (
+	CharacterCreated     EventType = "character.created"
+	CharacterUpdated     EventType = "character.updated"
+	CharacterDeleted     EventType = "character.deleted"
+	InventoryItemAdded   EventType = "inventory.item_added"
+	InventoryItemRemoved EventType = "inventory.item_removed"
+	ItemCreated          EventType = "item.created"
+	ItemPoolSeeded       EventType = "item_pool.seeded"
+)
+
+var AllEventTypes = []EventType{
+	CharacterCreated,
+	CharacterUpdated,
+	CharacterDeleted,
+	InventoryItemAdded,
+	InventoryItemRemoved,
+	ItemCreated,
+	ItemPoolSeeded,
+}
+
+func (t EventType) String() string {
+	return string(t)
+}
+
+func (t EventType) Validate() bool {
+	return slices.Contains(AllEventTypes, t)
+}
+
+// EventTypes is stored as a comma separated list, as auth.Scopes is.
+type EventTypes []EventType
+
+func ParseEventTypes(s string) (EventTypes, error) {
+	var types EventTypes
+	for _, part := range strings.Split(s, ",") {
+		eventType := EventType(strings.TrimSpace(part))
+		if eventType == "" {
+			continue
+		}
+		if !eventType.Validate() {
+			return nil, fmt.Errorf("unknown event type: %q", eventType)
+		}
+		if !types.Has(eventType) {
+			types = append(types, eventType)
+		}
+	}
+	return types, nil
+}
+
+func (t EventTypes) Has(eventType EventType) bool {
+	return slices.Contains(t, eventType)
+}
+
+func (t EventTypes) String() string {
+	parts := make([]string, len(t))
+	for i, eventType := range t {
+		parts[i] = eventType.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+func (t EventTypes) Value() (driver.Value, error) {
+	return t.String(), nil
+}
+
+func (t *EventTypes) Scan(src any) error {
+	var raw string
+	switch v := src.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into EventTypes", src)
+	}
+
+	types, err := ParseEventTypes(raw)
+	if err != nil {
+		return err
+	}
+	*t = types
+	return nil
+}
+
+// Event is a change made to the gallery, as sent to webhook and stream subscribers. Data holds
+// the resource the event is about, in the same JSON the API returns for it.
+type Event struct {
+	ID        string          `json:"id"`
+	Type      EventType       `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+	// CharacterID is the character the event is about, or 0 for item pool events. Streams are
+	// filtered by it.
+	CharacterID characters.CharacterID `json:"-"`
+}
+
+// InventoryChange is the data of inventory events. Quantity is how many were added or removed,
+// and Entry is the inventory entry after an item was added.
+type InventoryChange struct {
+	CharacterID characters.CharacterID   `json:"character_id"`
+	ItemID      inventory.ItemID         `json:"item_id"`
+	Quantity    uint8                    `json:"quantity"`
+	Entry       *inventory.InventoryItem `json:"entry,omitempty"`
+}
+
+// DeletedCharacter is the data of character.deleted events.
+type DeletedCharacter struct {
+	ID characters.CharacterID `json:"id"`
+}
+
+// SeededItems is the data of item_pool.seeded events: the items that were inserted or updated.
+type SeededItems struct {
+	Items []inventory.Item `json:"items"`
+}
+
+// NewEvent stamps data with a random ID and the current time.
+func NewEvent(eventType EventType, data any) (*Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode %s event: %w", eventType, err)
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	return &Event{
+		ID:        "evt_" + hex.EncodeToString(id),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      raw,
+	}, nil
+}
@@ -0,0 +1,17 @@
+package events
+
+import "testing"
+
+func TestParseEventTypes(t *testing.T) {
+	types, err := ParseEventTypes("character.created, item.created,character.created")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if types.String() != "character.created,item.created" {
+		t.Errorf("unexpected types %v", types)
+	}
+
+	if _, err := ParseEventTypes("character.exploded"); err == nil {
+		t.Error("expected an unknown event type to be refused")
+	}
+}
@@ -0,0 +1,48 @@
+package events
+
+import (
+	"context"
+	"time"
+)
+
+type OutboxStatus string
+
+const (
+	// OutboxPending events are handed over once NextAttemptAt has passed.
+	OutboxPending   OutboxStatus = "pending"
+	OutboxDelivered OutboxStatus = "delivered"
+	// OutboxDead events ran out of attempts and are not handed over again.
+	OutboxDead OutboxStatus = "dead"
+)
+
+// OutboxEvent is an event handed over by Dispatch. Sinks names the sinks that accepted it on
+// earlier attempts, which are not handed it again.
+type OutboxEvent struct {
+	*Event
+	Attempts int
+	Sinks    []string
+}
+
+// OutboxOutcome is how an attempt leaves an event: delivered, dead, or pending for another
+// attempt after RetryIn. Sinks names every sink that has accepted the event so far.
+type OutboxOutcome struct {
+	Status  OutboxStatus
+	Sinks   []string
+	Error   string
+	RetryIn time.Duration
+}
+
+// OutboxStore holds the events written in the transactions of the writes they report, until
+// they are delivered. A write and its event are committed together or not at all.
+type OutboxStore interface {
+	// Dispatch claims up to limit pending events that are due, skipping those another dispatcher
+	// holds, and hands them to deliver oldest first. Claimed events are kept from other
+	// dispatchers for lease, and no lock is held while they are delivered. The outcome deliver
+	// returns is recorded with the event. It returns how many events were delivered.
+	Dispatch(ctx context.Context, limit int, lease time.Duration, deliver func(context.Context, *OutboxEvent) OutboxOutcome) (int, error)
+	// Prune deletes the events delivered longer than olderThan ago, and the dead ones written
+	// before then, by the clock of the store, and returns how many it deleted.
+	Prune(ctx context.Context, olderThan time.Duration) (int64, error)
+	// Broadcast hands a delivered event to the listeners of every instance.
+	Broadcast(ctx context.Context, event *Event) error
+}
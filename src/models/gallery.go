@@ -1,25 +1,52 @@
 package models
 
 import (
+	"context"
+
 	"dZev1/character-gallery/models/auth"
 	"dZev1/character-gallery/models/characters"
+	"dZev1/character-gallery/models/events"
 	"dZev1/character-gallery/models/inventory"
+	"dZev1/character-gallery/models/webhooks"
 )
 
 type CharacterGallery interface {
-	Create(character *characters.Character) error
+	Create(ctx context.Context, character *characters.Character) error
 	Close() error
-	Get(id characters.CharacterID) (*characters.Character, error)
-	GetAll(page int) ([]characters.Character, uint64, error)
-	Edit(character *characters.Character) error
-	Remove(id characters.CharacterID) error
+	Get(ctx context.Context, id characters.CharacterID) (*characters.Character, error)
+	GetAll(ctx context.Context, filter characters.CharacterFilter, offset, limit int) ([]characters.Character, uint64, error)
+	Edit(ctx context.Context, character *characters.Character) error
+	Remove(ctx context.Context, id characters.CharacterID) error
+	CountCharacters(ctx context.Context) (uint64, error)
 
-	CreateItem(item *inventory.Item) error
-	SeedItems(items []inventory.Item) error
-	DisplayPoolItems() ([]inventory.Item, error)
-	DisplayItem(itemID inventory.ItemID) (*inventory.Item, error)
-	AddItemToCharacter(characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) (*inventory.InventoryItem, error)
-	RemoveItemFromCharacter(characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) error
-	GetCharacterInventory(characterID characters.CharacterID) ([]inventory.InventoryItem, error)
+	CreateItem(ctx context.Context, item *inventory.Item) error
+	SeedItems(ctx context.Context, items []inventory.Item) error
+	DisplayPoolItems(ctx context.Context, filter inventory.ItemFilter) ([]inventory.Item, error)
+	DisplayItem(ctx context.Context, itemID inventory.ItemID) (*inventory.Item, error)
+	AddItemToCharacter(ctx context.Context, characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) (*inventory.InventoryItem, error)
+	RemoveItemFromCharacter(ctx context.Context, characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) error
+	GetCharacterInventory(ctx context.Context, characterID characters.CharacterID) ([]inventory.InventoryItem, error)
+	// GetCharacterInventories returns the inventories of several characters at once, keyed by
+	// character. Characters with an empty inventory, or that don't exist, are left out.
+	GetCharacterInventories(ctx context.Context, characterIDs []characters.CharacterID) (map[characters.CharacterID][]inventory.InventoryItem, error)
+	CountItems(ctx context.Context) (uint64, error)
 	GetAuthStore() auth.AuthStore
+	GetWebhookStore() webhooks.WebhookStore
+	// GetOutboxStore returns nil for backends without an outbox. Their events are delivered
+	// after each write instead, and lost when that fails.
+	GetOutboxStore() events.OutboxStore
+
+	// Batch runs fn in a single transaction. Its writes are committed together when fn returns
+	// nil, and all rolled back, their events included, when it returns an error. fn is given the
+	// context its writes are to be made with.
+	Batch(ctx context.Context, fn func(ctx context.Context, tx BatchTx) error) error
+}
+
+// BatchTx makes the writes of a batch. Each sees the writes made before it.
+type BatchTx interface {
+	Create(ctx context.Context, character *characters.Character) error
+	Edit(ctx context.Context, character *characters.Character) error
+	AddItemToCharacter(ctx context.Context, characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) (*inventory.InventoryItem, error)
+	RemoveItemFromCharacter(ctx context.Context, characterID characters.CharacterID, itemID inventory.ItemID, quantity uint8) error
+	CreateItem(ctx context.Context, item *inventory.Item) error
 }
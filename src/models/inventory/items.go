@@ -1,5 +1,11 @@
 package inventory
 
+import (
+	"strconv"
+
+	"dZev1/character-gallery/models/validation"
+)
+
 type Item struct {
 	ID          ItemID `db:"id" json:"id,omitempty"`
 	Name        string `db:"name" json:"name"`
@@ -15,22 +21,56 @@ type Item struct {
 	Duration   *uint64 `db:"duration" json:"duration,omitempty"`
 	Cooldown   *uint64 `db:"cooldown" json:"cooldown,omitempty"`
 	Capacity   *uint64 `db:"capacity" json:"capacity,omitempty"`
+
+	// Pack is the namespace of the content pack the item was seeded from, and Slug its stable
+	// name within the pack. Both are empty for items that don't come from a pack.
+	Pack string `db:"pack" json:"pack,omitempty"`
+	Slug string `db:"slug" json:"slug,omitempty"`
+}
+
+// Key identifies the item across seeds: "pack/slug" for content pack items and the ID for the
+// others.
+func (i *Item) Key() string {
+	if i.Slug != "" {
+		return i.Pack + "/" + i.Slug
+	}
+	return strconv.FormatUint(uint64(i.ID), 10)
+}
+
+// ItemFilter narrows down the items returned from the pool. Empty fields match every item.
+type ItemFilter struct {
+	Pack string
 }
 
-func (i *Item) Validate() bool {
-	if len(i.Name) < 3 || len(i.Name) > 50 {
-		return false
+// Validate reports every invalid field of the item.
+func (i *Item) Validate() error {
+	var errs validation.Errors
+
+	switch {
+	case len(i.Name) < 3:
+		errs.Add("name", validation.CodeTooShort, "must be at least 3 characters long")
+	case len(i.Name) > 50:
+		errs.Add("name", validation.CodeTooLong, "must be at most 50 characters long")
 	}
-	if len(i.Description) < 3 || len(i.Description) > 300 {
-		return false
+
+	errs.Merge("type", i.Type.Validate())
+
+	switch {
+	case len(i.Description) < 3:
+		errs.Add("description", validation.CodeTooShort, "must be at least 3 characters long")
+	case len(i.Description) > 300:
+		errs.Add("description", validation.CodeTooLong, "must be at most 300 characters long")
 	}
+
 	if i.Rarity < 1 || i.Rarity > 5 {
-		return false
+		errs.Add("rarity", validation.CodeOutOfRange, "must be between 1 and 5")
 	}
-	if !ValidateStats(i) && i.Equippable {
-		return false
+
+	if i.Equippable && !ValidateStats(i) {
+		errs.Add("equippable", validation.CodeInvalidValue, "equippable items need at least one positive stat")
 	}
-	return true
+
+	return errs.Err()
 }
 
 func ValidateStats(i *Item) bool {
@@ -0,0 +1,34 @@
+package inventory
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"dZev1/character-gallery/models/validation"
+)
+
+func TestItemValidate(t *testing.T) {
+	damage := uint64(5)
+	valid := Item{Name: "Sword", Type: Weapon, Description: "A sharp blade", Equippable: true, Rarity: 2, Damage: &damage}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected valid item, got %v", err)
+	}
+
+	invalid := Item{Name: strings.Repeat("x", 51), Type: "Spoon", Description: "no", Equippable: true, Rarity: 9}
+	var errs validation.Errors
+	if !errors.As(invalid.Validate(), &errs) {
+		t.Fatalf("expected validation.Errors")
+	}
+
+	var paths []string
+	for _, fieldErr := range errs {
+		paths = append(paths, fieldErr.Path)
+	}
+	if got := strings.Join(paths, ","); got != "name,type,description,rarity,equippable" {
+		t.Errorf("unexpected paths %s", got)
+	}
+	if errs[0].Code != validation.CodeTooLong {
+		t.Errorf("expected code %s, got %s", validation.CodeTooLong, errs[0].Code)
+	}
+}
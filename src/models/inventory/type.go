@@ -1,5 +1,11 @@
 package inventory
 
+import (
+	"slices"
+
+	"dZev1/character-gallery/models/validation"
+)
+
 type Type string
 
 const (
@@ -24,10 +30,11 @@ const (
 	WondrousItem Type = "wondrous_item"
 )
 
-func (t Type) Validate() bool {
-	switch t {
-	case Armor, Ring, Weapon, Shield, Tool, AdventuringGear, Rod, Staff, Wand, Scroll, Potion, Ammo, Consumable, WondrousItem:
-		return true
+var AllTypes = []Type{Armor, Ring, Weapon, Shield, Tool, AdventuringGear, Rod, Staff, Wand, Scroll, Potion, Ammo, Consumable, WondrousItem}
+
+func (t Type) Validate() error {
+	if !slices.Contains(AllTypes, t) {
+		return validation.OneOf(t, AllTypes)
 	}
-	return false
+	return nil
 }
\ No newline at end of file
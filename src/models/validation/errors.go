@@ -0,0 +1,98 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	CodeRequired     = "required"
+	CodeTooShort     = "too_short"
+	CodeTooLong      = "too_long"
+	CodeOutOfRange   = "out_of_range"
+	CodeInvalidValue = "invalid_value"
+)
+
+// FieldError describes why one field is invalid. Path is empty until the error is merged into
+// the Errors of the value holding the field.
+type FieldError struct {
+	Path    string `json:"path"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Errors collects every invalid field of a value instead of stopping at the first one.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *Errors) Add(path, code, message string) {
+	*e = append(*e, FieldError{Path: path, Code: code, Message: message})
+}
+
+// Merge adds the errors returned by validating the field at path. Paths of nested errors are
+// joined with a dot, such as stats.strength.
+func (e *Errors) Merge(path string, err error) {
+	if err == nil {
+		return
+	}
+
+	var fieldErrs Errors
+	var fieldErr FieldError
+	switch {
+	case errors.As(err, &fieldErrs):
+	case errors.As(err, &fieldErr):
+		fieldErrs = Errors{fieldErr}
+	default:
+		fieldErrs = Errors{{Code: CodeInvalidValue, Message: err.Error()}}
+	}
+
+	for _, fieldErr := range fieldErrs {
+		fieldErr.Path = join(path, fieldErr.Path)
+		*e = append(*e, fieldErr)
+	}
+}
+
+// Err returns e as an error, or nil when no field is invalid.
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// OneOf returns the error for a value that isn't in values.
+func OneOf[T ~string](value T, values []T) FieldError {
+	names := make([]string, len(values))
+	for i, v := range values {
+		names[i] = string(v)
+	}
+	return FieldError{
+		Code:    CodeInvalidValue,
+		Message: fmt.Sprintf("%q is not one of %s", value, strings.Join(names, ", ")),
+	}
+}
+
+func join(prefix, path string) string {
+	switch {
+	case prefix == "":
+		return path
+	case path == "":
+		return prefix
+	}
+	return prefix + "." + path
+}
@@ -0,0 +1,33 @@
+package webhooks
+
+import (
+	"context"
+	"time"
+
+	"dZev1/character-gallery/models/events"
+)
+
+type WebhookStore interface {
+	// CreateSubscription stores a subscription with a newly generated secret.
+	CreateSubscription(ctx context.Context, url string, types events.EventTypes, description string) (*Subscription, error)
+	ListSubscriptions(ctx context.Context) ([]Subscription, error)
+	GetSubscription(ctx context.Context, id SubscriptionID) (*Subscription, error)
+	// DeleteSubscription removes a subscription along with its deliveries.
+	DeleteSubscription(ctx context.Context, id SubscriptionID) error
+	// Enqueue queues event for every subscription to its type and returns how many deliveries
+	// were queued.
+	Enqueue(ctx context.Context, event *events.Event) (int64, error)
+	// ClaimDeliveries returns up to limit pending deliveries that are due, and keeps them from
+	// being claimed again for lease, so that several dispatchers never post the same one twice.
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]DueDelivery, error)
+	// RecordAttempt adds attempt to the delivery log and moves its delivery to outcome.
+	RecordAttempt(ctx context.Context, attempt *Attempt, outcome Outcome) error
+	// ListDeliveries returns the latest deliveries of a subscription, newest first. An empty
+	// status returns them all.
+	ListDeliveries(ctx context.Context, id SubscriptionID, status DeliveryStatus, limit int) ([]Delivery, error)
+	GetDelivery(ctx context.Context, id SubscriptionID, deliveryID DeliveryID) (*Delivery, error)
+	ListAttempts(ctx context.Context, id DeliveryID) ([]Attempt, error)
+	// RetryDelivery queues a delivery of the subscription again with its attempts reset,
+	// whatever its status.
+	RetryDelivery(ctx context.Context, id SubscriptionID, deliveryID DeliveryID) (*Delivery, error)
+}
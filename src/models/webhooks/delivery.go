@@ -0,0 +1,98 @@
+package webhooks
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"time"
+
+	"dZev1/character-gallery/models/events"
+)
+
+type DeliveryStatus string
+
+const (
+	// DeliveryPending deliveries are posted once NextAttemptAt has passed.
+	DeliveryPending DeliveryStatus = "pending"
+	DeliveryDone    DeliveryStatus = "delivered"
+	// DeliveryDead deliveries ran out of attempts and are only posted again when retried by hand.
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+var AllDeliveryStatuses = []DeliveryStatus{DeliveryPending, DeliveryDone, DeliveryDead}
+
+// Delivery is an event queued for one subscription. Payload is the exact body that is posted,
+// so that every attempt is signed over the same bytes.
+type Delivery struct {
+	ID             DeliveryID       `json:"id" db:"id"`
+	SubscriptionID SubscriptionID   `json:"subscription_id" db:"subscription_id"`
+	EventID        string           `json:"event_id" db:"event_id"`
+	EventType      events.EventType `json:"event_type" db:"event_type"`
+	Payload        Payload          `json:"payload" db:"payload"`
+	Status         DeliveryStatus   `json:"status" db:"status"`
+	Attempts       int              `json:"attempts" db:"attempts"`
+	NextAttemptAt  *time.Time       `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	LastStatusCode *int             `json:"last_status_code,omitempty" db:"last_status_code"`
+	LastError      string           `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt      time.Time        `json:"created_at" db:"created_at"`
+	DeliveredAt    *time.Time       `json:"delivered_at,omitempty" db:"delivered_at"`
+}
+
+type DeliveryID uint64
+
+func (id DeliveryID) String() string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// Payload is a JSON document kept as the exact bytes that are posted. It is stored as text so
+// that no database reformats it.
+type Payload []byte
+
+func (p Payload) MarshalJSON() ([]byte, error) {
+	if len(p) == 0 {
+		return []byte("null"), nil
+	}
+	return p, nil
+}
+
+func (p Payload) Value() (driver.Value, error) {
+	return string(p), nil
+}
+
+func (p *Payload) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*p = nil
+	case string:
+		*p = Payload(v)
+	case []byte:
+		*p = append(Payload(nil), v...)
+	default:
+		return fmt.Errorf("cannot scan %T into Payload", src)
+	}
+	return nil
+}
+
+// DueDelivery is a claimed delivery along with where to post it.
+type DueDelivery struct {
+	Delivery
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+}
+
+// Attempt is one entry of the delivery log. StatusCode is nil when no response was received.
+type Attempt struct {
+	ID          uint64     `json:"id" db:"id"`
+	DeliveryID  DeliveryID `json:"delivery_id" db:"delivery_id"`
+	StatusCode  *int       `json:"status_code,omitempty" db:"status_code"`
+	Error       string     `json:"error,omitempty" db:"error"`
+	DurationMS  int64      `json:"duration_ms" db:"duration_ms"`
+	AttemptedAt time.Time  `json:"attempted_at" db:"attempted_at"`
+}
+
+// Outcome is how an attempt leaves its delivery: delivered, dead, or pending for another
+// attempt after RetryIn.
+type Outcome struct {
+	Status  DeliveryStatus
+	RetryIn time.Duration
+}
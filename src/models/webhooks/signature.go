@@ -0,0 +1,49 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+var ErrInvalidSignature = errors.New(`invalid webhook signature`)
+
+// Sign returns the X-Webhook-Signature of a payload sent at timestamp: "sha256=" followed by the
+// hex HMAC-SHA256 of "<unix timestamp>.<payload>". Signing the timestamp lets receivers refuse
+// replayed deliveries.
+func Sign(secret string, timestamp time.Time, payload []byte) string {
+	return "sha256=" + hex.EncodeToString(mac(secret, strconv.FormatInt(timestamp.Unix(), 10), payload))
+}
+
+// Verify checks the X-Webhook-Timestamp and X-Webhook-Signature headers of a delivery.
+func Verify(secret, timestamp, signature string, payload []byte) error {
+	sum, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return ErrInvalidSignature
+	}
+	got, err := hex.DecodeString(sum)
+	if err != nil || !hmac.Equal(got, mac(secret, timestamp, payload)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func mac(secret, timestamp string, payload []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(payload)
+	return h.Sum(nil)
+}
@@ -0,0 +1,29 @@
+package webhooks
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	sentAt := time.Unix(1700000000, 0)
+	payload := []byte(`{"id":"evt_1"}`)
+	signature := Sign("whsec_test", sentAt, payload)
+	timestamp := strconv.FormatInt(sentAt.Unix(), 10)
+
+	if err := Verify("whsec_test", timestamp, signature, payload); err != nil {
+		t.Errorf("expected the signature to verify, got %v", err)
+	}
+
+	for name, args := range map[string][3]string{
+		"other secret":    {"whsec_other", timestamp, signature},
+		"other timestamp": {"whsec_test", "1700000001", signature},
+		"no prefix":       {"whsec_test", timestamp, signature[len("sha256="):]},
+	} {
+		if err := Verify(args[0], args[1], args[2], payload); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: expected ErrInvalidSignature, got %v", name, err)
+		}
+	}
+}
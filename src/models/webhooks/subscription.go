@@ -0,0 +1,36 @@
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"dZev1/character-gallery/models/events"
+)
+
+// Subscription asks for the events of Events to be posted to URL, signed with Secret.
+type Subscription struct {
+	ID          SubscriptionID    `json:"id" db:"id"`
+	URL         string            `json:"url" db:"url"`
+	Events      events.EventTypes `json:"events" db:"events"`
+	Description string            `json:"description" db:"description"`
+	Secret      string            `json:"-" db:"secret"`
+	CreatedAt   time.Time         `json:"created_at" db:"created_at"`
+}
+
+type SubscriptionID uint64
+
+func (id SubscriptionID) String() string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// GenerateSecret returns a new signing secret. Unlike API keys it is stored as is, since it is
+// needed to sign every delivery.
+func GenerateSecret() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(bytes), nil
+}